@@ -0,0 +1,208 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Formats results can be written in.
+const (
+	FormatJSONL = "jsonl" // the API's results file, one result per line
+	FormatText  = "text"  // the custom ID and reply, or error, of each request
+)
+
+// Formats lists every format results can be written in.
+var Formats = []string{FormatJSONL, FormatText}
+
+// inputLine is a line of a submitted file, in the format of the API's batch
+// requests. Params are decoded over the defaults.
+type inputLine struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// ReadRequests reads batch requests from JSONL, one {"custom_id": ...,
+// "params": {...}} object per line, params being a Messages API request.
+// Fields missing from params are taken from defaults, and a missing custom_id
+// becomes the line's number.
+func ReadRequests(r io.Reader, defaults claude.RequestBody) ([]claude.BatchRequest, error) {
+	var requests []claude.BatchRequest
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var line inputLine
+		err := json.Unmarshal([]byte(text), &line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		params := defaults
+		params.Messages = nil
+		if len(line.Params) > 0 {
+			err = json.Unmarshal(line.Params, &params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: params: %w", number, err)
+			}
+		}
+		params.Stream = false
+		if len(params.Messages) == 0 {
+			return nil, fmt.Errorf("line %d: params has no messages", number)
+		}
+		if line.CustomID == "" {
+			line.CustomID = fmt.Sprintf("line-%d", number)
+		}
+		if previous, ok := seen[line.CustomID]; ok {
+			return nil, fmt.Errorf("line %d: custom_id %q is already used on line %d", number, line.CustomID, previous)
+		}
+		seen[line.CustomID] = number
+		requests = append(requests, claude.BatchRequest{CustomID: line.CustomID, Params: params})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("no requests found")
+	}
+	return requests, nil
+}
+
+// Submit sends the requests of a JSONL file as a batch and records its job.
+func Submit(ctx context.Context, client *claude.Client, path string, defaults claude.RequestBody) (*claude.MessageBatch, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	requests, err := ReadRequests(file, defaults)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	batch, err := client.CreateBatch(ctx, requests)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = db.AddBatchJob(batch.Id, path, batch.ProcessingStatus, int64(len(requests)))
+	if err != nil {
+		return batch, len(requests), err
+	}
+	return batch, len(requests), nil
+}
+
+// Refresh gets the state of a batch from the API and stores it in its job,
+// adding a job for batches submitted elsewhere.
+func Refresh(ctx context.Context, client *claude.Client, batchID string) (*db.BatchJob, error) {
+	batch, err := client.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return store(batch)
+}
+
+// Cancel asks the API to stop a batch and stores its new state.
+func Cancel(ctx context.Context, client *claude.Client, batchID string) (*db.BatchJob, error) {
+	batch, err := client.CancelBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return store(batch)
+}
+
+// Report counts the results written by WriteResults.
+type Report struct {
+	Succeeded     int
+	Failed        int
+	UsageRecorded bool // false when it was recorded by an earlier call
+	Usage         []db.Usage
+}
+
+// WriteResults writes the results of an ended batch to out in one of
+// Formats, and records their usage, once per batch, for stats and budgets.
+func WriteResults(ctx context.Context, client *claude.Client, batchID string, out io.Writer, format string) (*Report, error) {
+	job, err := Refresh(ctx, client, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != claude.BatchStatusEnded {
+		return nil, fmt.Errorf("batch %s is %s, its results are available once it has ended", batchID, job.Status)
+	}
+
+	report := &Report{}
+	err = client.BatchResults(ctx, batchID, func(r claude.BatchResult) error {
+		resultErr := r.Err()
+		if resultErr != nil {
+			report.Failed++
+		} else {
+			report.Succeeded++
+			report.Usage = append(report.Usage, db.Usage{
+				Model:               r.Result.Message.Model,
+				InputTokens:         r.Result.Message.Usage.InputTokens,
+				OutputTokens:        r.Result.Message.Usage.OutputTokens,
+				CacheCreationTokens: r.Result.Message.Usage.CacheCreationInputTokens,
+				CacheReadTokens:     r.Result.Message.Usage.CacheReadInputTokens,
+				StopReason:          r.Result.Message.StopReason,
+			})
+		}
+
+		switch format {
+		case FormatText:
+			if resultErr != nil {
+				_, err := fmt.Fprintf(out, "=== %s (failed)\n%v\n\n", r.CustomID, resultErr)
+				return err
+			}
+			_, err := fmt.Fprintf(out, "=== %s\n%s\n\n", r.CustomID, r.Result.Message.Text())
+			return err
+		default:
+			_, err := fmt.Fprintf(out, "%s\n", r.Raw)
+			return err
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.UsageRecorded, err = db.RecordBatchUsage(batchID, report.Usage)
+	return report, err
+}
+
+// store records the state of a batch in its job.
+func store(batch *claude.MessageBatch) (*db.BatchJob, error) {
+	job, err := db.GetBatchJob(batch.Id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		counts := batch.RequestCounts
+		total := counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired
+		_, err = db.AddBatchJob(batch.Id, "", batch.ProcessingStatus, total)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = db.UpdateBatchJob(db.BatchJob{
+		BatchID:   batch.Id,
+		Status:    batch.ProcessingStatus,
+		Succeeded: batch.RequestCounts.Succeeded,
+		Errored:   batch.RequestCounts.Errored,
+		Canceled:  batch.RequestCounts.Canceled,
+		Expired:   batch.RequestCounts.Expired,
+		EndedAt:   batch.EndedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db.GetBatchJob(batch.Id)
+}
@@ -0,0 +1,140 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+func TestReadRequests(t *testing.T) {
+	input := `{"custom_id": "q1", "params": {"messages": [{"role": "user", "content": "Hello"}]}}
+
+{"params": {"model": "claude-3-opus-20240229", "max_tokens": 50, "stream": true, "messages": [{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]}}
+`
+	defaults := claude.RequestBody{Model: "claude-3-haiku-20240307", MaxTokens: 100, System: "Be brief."}
+	requests, err := ReadRequests(strings.NewReader(input), defaults)
+	if err != nil {
+		t.Fatalf("ReadRequests returned an error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	first, second := requests[0], requests[1]
+	if first.CustomID != "q1" || first.Params.Model != "claude-3-haiku-20240307" || first.Params.MaxTokens != 100 || first.Params.System != "Be brief." || first.Params.Messages[0].ContentRaw != "Hello" {
+		t.Errorf("Expected the defaults to fill the first request, got %+v", first)
+	}
+	if second.CustomID != "line-3" || second.Params.Model != "claude-3-opus-20240229" || second.Params.MaxTokens != 50 || second.Params.Stream {
+		t.Errorf("Expected the second request's own params, got %+v", second)
+	}
+
+	for _, bad := range []string{
+		``,
+		`{"custom_id": "q1", "params": {}}`,
+		`{"custom_id": "q1", "params": {"messages": [{"role": "user", "content": "a"}]}}` + "\n" + `{"custom_id": "q1", "params": {"messages": [{"role": "user", "content": "b"}]}}`,
+		`not json`,
+	} {
+		_, err := ReadRequests(strings.NewReader(bad), defaults)
+		if err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestReadRequestsContent(t *testing.T) {
+	long := strings.Repeat("word ", 4000)
+	input := `{"custom_id": "long", "params": {"messages": [{"role": "user", "content": "` + long + `"}]}}
+{"custom_id": "blocks", "params": {"system": [{"type": "text", "text": "Cached rules.", "cache_control": {"type": "ephemeral"}}], "messages": [{"role": "user", "content": [{"type": "text", "text": "` + long + `"}]}]}}
+{"custom_id": "plain", "params": {"system": "Own prompt.", "messages": [{"role": "user", "content": "Hi"}]}}
+`
+	defaults := claude.RequestBody{Model: "claude-3-haiku-20240307", MaxTokens: 100, SystemBlocks: []claude.RequestContentBlock{claude.NewTextBlock("Default rules.")}}
+	requests, err := ReadRequests(strings.NewReader(input), defaults)
+	if err != nil {
+		t.Fatalf("ReadRequests returned an error: %v", err)
+	}
+	want := claude.EstimateTokens(long)
+	for _, r := range requests[:2] {
+		if tokens := claude.EstimateRequestTokens(r.Params); tokens < want {
+			t.Errorf("%s: expected the estimate to cover the message's %d tokens, got %d", r.CustomID, want, tokens)
+		}
+	}
+
+	blocks := requests[1].Params
+	if len(blocks.SystemBlocks) != 1 || blocks.SystemBlocks[0].Text != "Cached rules." || blocks.SystemBlocks[0].CacheControl == nil || blocks.System != "" {
+		t.Errorf("Expected the system blocks of the params, got %q and %+v", blocks.System, blocks.SystemBlocks)
+	}
+	if len(blocks.Messages[0].ContentBlocks) != 1 || blocks.Messages[0].ContentBlocks[0].Text != long {
+		t.Errorf("Expected the message's content blocks, got %+v", blocks.Messages[0].ContentBlocks)
+	}
+	plain := requests[2].Params
+	if plain.System != "Own prompt." || len(plain.SystemBlocks) != 0 {
+		t.Errorf("Expected the system string to replace the default blocks, got %q and %+v", plain.System, plain.SystemBlocks)
+	}
+	if requests[0].Params.SystemBlocks[0].Text != "Default rules." {
+		t.Errorf("Expected the default system blocks without a system in the params, got %+v", requests[0].Params.SystemBlocks)
+	}
+
+	_, err = ReadRequests(strings.NewReader(`{"params": {"system": 3, "messages": [{"role": "user", "content": "Hi"}]}}`), defaults)
+	if err == nil || !strings.Contains(err.Error(), "system") {
+		t.Errorf("Expected a system that is neither a string nor blocks to be refused, got %v", err)
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	status := claude.BatchStatusInProgress
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/results") {
+			w.Write([]byte(`{"custom_id":"q1","result":{"type":"succeeded","message":{"model":"claude-3-haiku-20240307","content":[{"type":"text","text":"Hi!"}],"usage":{"input_tokens":8,"output_tokens":3}}}}
+{"custom_id":"q2","result":{"type":"expired"}}
+`))
+			return
+		}
+		w.Write([]byte(`{"id":"msgbatch_1","processing_status":"` + status + `","request_counts":{"succeeded":1,"expired":1}}`))
+	}))
+	defer server.Close()
+	client := claude.NewClientWithConfig(claude.ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+
+	var out bytes.Buffer
+	_, err = WriteResults(context.Background(), client, "msgbatch_1", &out, FormatText)
+	if err == nil || !strings.Contains(err.Error(), "in_progress") {
+		t.Errorf("Expected results of a batch in progress to be refused, got %v", err)
+	}
+
+	status = claude.BatchStatusEnded
+	report, err := WriteResults(context.Background(), client, "msgbatch_1", &out, FormatText)
+	if err != nil {
+		t.Fatalf("WriteResults returned an error: %v", err)
+	}
+	if report.Succeeded != 1 || report.Failed != 1 || !report.UsageRecorded {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if out.String() != "=== q1\nHi!\n\n=== q2 (failed)\nrequest expired\n\n" {
+		t.Errorf("Unexpected text %q", out.String())
+	}
+
+	job, err := db.GetBatchJob("msgbatch_1")
+	if err != nil || job == nil || job.Status != claude.BatchStatusEnded || job.Requests != 2 || !job.UsageRecorded {
+		t.Errorf("Expected the job of a batch submitted elsewhere, got %+v, %v", job, err)
+	}
+
+	out.Reset()
+	report, err = WriteResults(context.Background(), client, "msgbatch_1", &out, FormatJSONL)
+	if err != nil || report.UsageRecorded || strings.Count(out.String(), "\n") != 2 || !strings.HasPrefix(out.String(), `{"custom_id":"q1"`) {
+		t.Errorf("Expected the raw lines without recording usage again, got %q, %+v, %v", out.String(), report, err)
+	}
+	totals, err := db.GetUsageTotals(db.UsageByModel, db.UsageFilter{})
+	if err != nil || len(totals) != 1 || totals[0].InputTokens != 8 || !totals[0].Batch {
+		t.Errorf("Expected the usage of the successful request once, got %+v, %v", totals, err)
+	}
+}
@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// MaxTextAttachmentSize caps the size of a text file inlined into a message.
+const MaxTextAttachmentSize = 1024 * 1024
+
+// fileReferencePattern matches "@path" at the start of the message or after
+// whitespace, so addresses such as user@example.com are left alone.
+var fileReferencePattern = regexp.MustCompile(`(^|\s)@(\S+)`)
+
+// ExpandMessage resolves the files attached with --attach and the inline @path
+// references of message into attachments. References to files that do not
+// exist are left untouched, the others are replaced by the bare path so the
+// text still reads naturally.
+func ExpandMessage(message string, attachPaths []string) (string, []db.Attachment, error) {
+	var attachments []db.Attachment
+	seen := make(map[string]bool)
+
+	add := func(path string) error {
+		if seen[path] {
+			return nil
+		}
+		a, err := LoadAttachment(path)
+		if err != nil {
+			return err
+		}
+		seen[path] = true
+		attachments = append(attachments, a)
+		return nil
+	}
+
+	for _, path := range attachPaths {
+		if err := add(expandHome(path)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var expandErr error
+	text := fileReferencePattern.ReplaceAllStringFunc(message, func(match string) string {
+		groups := fileReferencePattern.FindStringSubmatch(match)
+		prefix, ref := groups[1], strings.TrimRight(groups[2], ".,;:!?)")
+		trailing := strings.TrimPrefix(groups[2], ref)
+		path := expandHome(ref)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return match
+		}
+		if err := add(path); err != nil && expandErr == nil {
+			expandErr = err
+		}
+		return prefix + ref + trailing
+	})
+	if expandErr != nil {
+		return "", nil, expandErr
+	}
+	return text, attachments, nil
+}
+
+// LoadAttachment reads a file into an attachment. Images and PDFs are stored
+// base64 encoded, any other file must be valid UTF-8 text.
+func LoadAttachment(path string) (db.Attachment, error) {
+	mediaType, err := claude.DetectMediaType(path)
+	if err != nil {
+		return db.Attachment{}, err
+	}
+	if claude.IsImageMediaType(mediaType) || mediaType == claude.MediaTypePDF {
+		block, err := claude.NewMediaBlockFromFile(path)
+		if err != nil {
+			return db.Attachment{}, err
+		}
+		kind := db.AttachmentKindImage
+		if block.Type == claude.RequestContentTypeDocumentType {
+			kind = db.AttachmentKindDocument
+		}
+		return db.Attachment{
+			Kind:      kind,
+			Path:      path,
+			MediaType: block.Source.MediaType,
+			Data:      block.Source.Data,
+		}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return db.Attachment{}, err
+	}
+	if !utf8.Valid(data) {
+		return db.Attachment{}, fmt.Errorf("%s: %w %s", path, claude.ErrUnsupportedMediaType, mediaType)
+	}
+	if len(data) > MaxTextAttachmentSize {
+		return db.Attachment{}, fmt.Errorf("%s: file is %d bytes, the limit for text files is %d", path, len(data), MaxTextAttachmentSize)
+	}
+	return db.Attachment{
+		Kind:      db.AttachmentKindText,
+		Path:      path,
+		MediaType: "text/plain",
+		Data:      string(data),
+	}, nil
+}
+
+// AttachmentToBlock converts a stored attachment into a request content block.
+func AttachmentToBlock(a db.Attachment) (claude.RequestContentBlock, error) {
+	switch a.Kind {
+	case db.AttachmentKindText:
+		return claude.NewTextBlock(fmt.Sprintf("Contents of %s:\n```\n%s\n```", a.Path, strings.TrimRight(a.Data, "\n"))), nil
+	case db.AttachmentKindImage:
+		return claude.NewImageBlockBase64(a.MediaType, a.Data), nil
+	case db.AttachmentKindDocument:
+		return claude.NewDocumentBlockBase64(filepath.Base(a.Path), a.Data), nil
+	}
+	return claude.RequestContentBlock{}, errors.New("unknown attachment kind: " + a.Kind)
+}
+
+// MessageWithAttachmentsToRequest builds a message whose content blocks hold
+// the attachments followed by the text. Content keeps the bare text so
+// history presentation is unaffected.
+func MessageWithAttachmentsToRequest(role, message string, attachments []db.Attachment) (claude.RequestMessages, error) {
+	request := claude.RequestMessages{
+		Role:    role,
+		Content: message,
+	}
+	if len(attachments) == 0 {
+		return request, nil
+	}
+	for _, a := range attachments {
+		block, err := AttachmentToBlock(a)
+		if err != nil {
+			return claude.RequestMessages{}, err
+		}
+		request.ContentBlocks = append(request.ContentBlocks, block)
+	}
+	if message != "" {
+		request.ContentBlocks = append(request.ContentBlocks, claude.NewTextBlock(message))
+	}
+	return request, nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// writeFile writes data to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestExpandMessage(t *testing.T) {
+	dir := t.TempDir()
+	notes := writeFile(t, dir, "notes.txt", []byte("remember the milk\n"))
+	screen := writeFile(t, dir, "screen.png", pngHeader)
+
+	tests := []struct {
+		name    string
+		message string
+		attach  []string
+		want    string
+		paths   []string // of the attachments, in order
+	}{
+		{"start of the message", "@" + notes + " sums it up", nil, notes + " sums it up", []string{notes}},
+		{"mid-message", "Compare @" + notes + " with @" + screen + ", please", nil, "Compare " + notes + " with " + screen + ", please", []string{notes, screen}},
+		{"email address", "Mail a@b.com about it", nil, "Mail a@b.com about it", nil},
+		{"missing file", "Look at @" + filepath.Join(dir, "missing.txt"), nil, "Look at @" + filepath.Join(dir, "missing.txt"), nil},
+		{"attached and referenced", "What is in @" + notes + "?", []string{notes}, "What is in " + notes + "?", []string{notes}},
+	}
+	for _, tt := range tests {
+		text, attachments, err := ExpandMessage(tt.message, tt.attach)
+		if err != nil {
+			t.Errorf("%s: ExpandMessage returned an error: %v", tt.name, err)
+			continue
+		}
+		if text != tt.want {
+			t.Errorf("%s: expected the text %q, got %q", tt.name, tt.want, text)
+		}
+		var paths []string
+		for _, a := range attachments {
+			paths = append(paths, a.Path)
+		}
+		if strings.Join(paths, "|") != strings.Join(tt.paths, "|") {
+			t.Errorf("%s: expected attachments %q, got %q", tt.name, tt.paths, paths)
+		}
+	}
+
+	_, _, err := ExpandMessage("Hello", []string{filepath.Join(dir, "missing.txt")})
+	if err == nil {
+		t.Errorf("Expected a missing --attach file to be refused")
+	}
+}
+
+func TestLoadAttachment(t *testing.T) {
+	dir := t.TempDir()
+	large := writeFile(t, dir, "large.png", pngHeader)
+	if err := os.Truncate(large, claude.MaxImageSize+1); err != nil {
+		t.Fatalf("Failed to grow large.png: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		kind string // of the attachment, empty when it is refused
+		err  string
+	}{
+		{"text", writeFile(t, dir, "notes.txt", []byte("remember the milk\n")), db.AttachmentKindText, ""},
+		{"image", writeFile(t, dir, "screen.png", pngHeader), db.AttachmentKindImage, ""},
+		{"document", writeFile(t, dir, "spec.pdf", []byte("%PDF-1.7\n")), db.AttachmentKindDocument, ""},
+		{"missing file", filepath.Join(dir, "missing.txt"), "", "no such file"},
+		{"oversized image", large, "", "the limit is"},
+		{"unsupported type", writeFile(t, dir, "blob.bin", []byte{0x00, 0xff, 0xfe, 0x01}), "", claude.ErrUnsupportedMediaType.Error()},
+		{"text over the limit", writeFile(t, dir, "big.txt", []byte(strings.Repeat("a", MaxTextAttachmentSize+1))), "", "the limit for text files"},
+	}
+	for _, tt := range tests {
+		a, err := LoadAttachment(tt.path)
+		if tt.kind == "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: LoadAttachment returned an error: %v", tt.name, err)
+			continue
+		}
+		if a.Kind != tt.kind || a.Path != tt.path {
+			t.Errorf("%s: unexpected attachment %s of %s", tt.name, a.Kind, a.Path)
+		}
+		block, err := AttachmentToBlock(a)
+		if err != nil {
+			t.Errorf("%s: AttachmentToBlock returned an error: %v", tt.name, err)
+		}
+		if tt.kind != db.AttachmentKindText && (block.Source == nil || block.Source.Data != a.Data) {
+			t.Errorf("%s: expected the block to carry the stored data, got %+v", tt.name, block)
+		}
+	}
+
+	_, err := LoadAttachment(writeFile(t, dir, "blob.bin", []byte{0x00, 0xff}))
+	if !errors.Is(err, claude.ErrUnsupportedMediaType) {
+		t.Errorf("Expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
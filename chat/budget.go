@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Budgets guard an API key shared by several people. Spend is read from the
+// usage recorded for every request, so it covers every conversation,
+// including deleted ones, sent from this database.
+
+// ErrBudgetExceeded is wrapped by the errors of requests a budget refuses.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget holds the spending limits. Zero values are no limit.
+type Budget struct {
+	DailyTokens    int64
+	MonthlyTokens  int64
+	DailyUSD       float64
+	MonthlyUSD     float64
+	WarnPercent    int // share of a limit spent before requests warn about it
+	MaxInputTokens int // estimated input tokens a single request may send
+}
+
+// BudgetLimit is how much of one limit has been spent.
+type BudgetLimit struct {
+	Name  string // e.g. "daily dollar budget"
+	Used  float64
+	Limit float64
+	USD   bool
+}
+
+// Percent returns the share of the limit spent.
+func (l BudgetLimit) Percent() float64 {
+	return 100 * l.Used / l.Limit
+}
+
+func (l BudgetLimit) String() string {
+	if l.USD {
+		return fmt.Sprintf("%s: $%.2f of $%.2f spent (%.0f%%)", l.Name, l.Used, l.Limit, l.Percent())
+	}
+	return fmt.Sprintf("%s: %.0f of %.0f tokens used (%.0f%%)", l.Name, l.Used, l.Limit, l.Percent())
+}
+
+// GlobalBudget returns the budget from the global configuration.
+func GlobalBudget() Budget {
+	return Budget{
+		DailyTokens:    int64(config.GetInt(config.BudgetDailyTokensKey)),
+		MonthlyTokens:  int64(config.GetInt(config.BudgetMonthlyTokensKey)),
+		DailyUSD:       config.GetFloat64(config.BudgetDailyUSDKey),
+		MonthlyUSD:     config.GetFloat64(config.BudgetMonthlyUSDKey),
+		WarnPercent:    config.GetInt(config.BudgetWarnPercentKey),
+		MaxInputTokens: config.GetInt(config.MaxInputTokensKey),
+	}
+}
+
+// Limits returns how much of every configured limit has been spent as of now.
+func (b Budget) Limits(now time.Time) ([]BudgetLimit, error) {
+	var limits []BudgetLimit
+	periods := []struct {
+		name   string
+		since  time.Time
+		tokens int64
+		usd    float64
+	}{
+		{"daily", time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), b.DailyTokens, b.DailyUSD},
+		{"monthly", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), b.MonthlyTokens, b.MonthlyUSD},
+	}
+	for _, p := range periods {
+		if p.tokens <= 0 && p.usd <= 0 {
+			continue
+		}
+		tokens, cost, err := spentSince(p.since)
+		if err != nil {
+			return nil, err
+		}
+		if p.tokens > 0 {
+			limits = append(limits, BudgetLimit{Name: p.name + " token budget", Used: float64(tokens), Limit: float64(p.tokens)})
+		}
+		if p.usd > 0 {
+			limits = append(limits, BudgetLimit{Name: p.name + " dollar budget", Used: cost, Limit: p.usd, USD: true})
+		}
+	}
+	return limits, nil
+}
+
+// Check tells whether a request may be sent. It returns the limits past the
+// warning threshold, and an error wrapping ErrBudgetExceeded when a limit is
+// spent or the request is larger than MaxInputTokens.
+func (b Budget) Check(body claude.RequestBody, now time.Time) ([]BudgetLimit, error) {
+	if b.MaxInputTokens > 0 {
+		tokens := claude.EstimateRequestTokens(body)
+		if tokens > b.MaxInputTokens {
+			return nil, fmt.Errorf("%w: the request has about %d input tokens, more than the %d allowed per request", ErrBudgetExceeded, tokens, b.MaxInputTokens)
+		}
+	}
+
+	limits, err := b.Limits(now)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []BudgetLimit
+	for _, l := range limits {
+		if l.Used >= l.Limit {
+			return warnings, fmt.Errorf("%w: %s", ErrBudgetExceeded, l)
+		}
+		if b.WarnPercent > 0 && l.Percent() >= float64(b.WarnPercent) {
+			warnings = append(warnings, l)
+		}
+	}
+	return warnings, nil
+}
+
+// BudgetGuard returns a request guard enforcing the budget, printing its
+// warnings to out. With force, requests past the budget are sent anyway
+// after a warning.
+func BudgetGuard(budget Budget, force bool, out io.Writer) claude.RequestGuard {
+	return func(ctx context.Context, body claude.RequestBody) error {
+		warnings, err := budget.Check(body, time.Now())
+		for _, w := range warnings {
+			fmt.Fprintf(out, "Warning: %s\n", w)
+		}
+		if errors.Is(err, ErrBudgetExceeded) {
+			if force {
+				fmt.Fprintf(out, "Warning: %v, sending anyway because of --force\n", err)
+				return nil
+			}
+			return fmt.Errorf("%w, pass --force to send anyway", err)
+		}
+		return err
+	}
+}
+
+// spentSince returns the tokens and dollars spent since a point in time.
+// Tokens of models without a price count toward token budgets only.
+func spentSince(since time.Time) (int64, float64, error) {
+	totals, err := db.GetUsageTotals(db.UsageByModel, db.UsageFilter{Since: since})
+	if err != nil {
+		return 0, 0, err
+	}
+	var tokens int64
+	var usd float64
+	for _, t := range totals {
+		tokens += t.InputTokens + t.OutputTokens + t.CacheCreationTokens + t.CacheReadTokens
+		cost, _ := config.UsageCost(t.Model, t.InputTokens, t.OutputTokens, t.CacheCreationTokens, t.CacheReadTokens, t.Batch)
+		usd += cost
+	}
+	return tokens, usd, nil
+}
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+func TestBudgetCheck(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// 1M input tokens of Haiku cost $0.25.
+	err = db.AddUsage(db.Usage{Model: "claude-3-haiku-20240307", InputTokens: 1000000})
+	if err != nil {
+		t.Fatalf("AddUsage returned an error: %v", err)
+	}
+	body := claude.RequestBody{Messages: []claude.RequestMessages{{Role: claude.MessageRoleUser, Content: strings.Repeat("x", 400)}}}
+	now := time.Now()
+
+	warnings, err := Budget{DailyUSD: 1, WarnPercent: 20}.Check(body, now)
+	if err != nil || len(warnings) != 1 || warnings[0].Used != 0.25 {
+		t.Errorf("Expected a warning at 25%% of the daily budget, got %+v, %v", warnings, err)
+	}
+
+	_, err = Budget{MonthlyUSD: 0.2}.Check(body, now)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected the monthly budget to be exceeded, got %v", err)
+	}
+
+	_, err = Budget{DailyTokens: 2000000, MaxInputTokens: 50}.Check(body, now)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected the request to be larger than allowed, got %v", err)
+	}
+
+	// Usage from before the period doesn't count.
+	warnings, err = Budget{DailyTokens: 10}.Check(body, now.AddDate(0, 0, 1))
+	if err != nil {
+		t.Errorf("Expected tomorrow's budget to be untouched, got %+v, %v", warnings, err)
+	}
+}
+
+func TestBudgetGuard(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	err = db.AddUsage(db.Usage{Model: "claude-3-haiku-20240307", InputTokens: 100, OutputTokens: 100})
+	if err != nil {
+		t.Fatalf("AddUsage returned an error: %v", err)
+	}
+	budget := Budget{DailyTokens: 100}
+
+	var out bytes.Buffer
+	err = BudgetGuard(budget, false, &out)(context.Background(), claude.RequestBody{})
+	if !errors.Is(err, ErrBudgetExceeded) || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected the guard to refuse the request, got %v", err)
+	}
+
+	err = BudgetGuard(budget, true, &out)(context.Background(), claude.RequestBody{})
+	if err != nil || !strings.Contains(out.String(), "sending anyway") {
+		t.Errorf("Expected --force to send the request with a warning, got %v and %q", err, out.String())
+	}
+}
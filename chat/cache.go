@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"slices"
+
+	"github.com/christianhturner/go-claude/claude"
+)
+
+// AddCacheBreakpoints marks the parts of a request the next turn of the
+// conversation sends again for prompt caching: the tools, the system prompt,
+// the history up to the previous user message, which the last turn cached,
+// and the whole request up to the new message, which the next turn reads.
+// Requests to models without prompt caching, or too short to be cached, are
+// returned as they are.
+func AddCacheBreakpoints(body claude.RequestBody) claude.RequestBody {
+	minTokens := claude.CacheMinTokens(body.Model)
+	if minTokens == 0 || claude.EstimateRequestTokens(body) < minTokens {
+		return body
+	}
+
+	if len(body.Tools) > 0 {
+		body.Tools = slices.Clone(body.Tools)
+		body.Tools[len(body.Tools)-1].CacheControl = claude.EphemeralCache()
+	}
+
+	if len(body.SystemBlocks) > 0 {
+		body.SystemBlocks = slices.Clone(body.SystemBlocks)
+	} else if body.System != "" {
+		body.SystemBlocks = []claude.RequestContentBlock{claude.NewTextBlock(body.System)}
+	}
+	if len(body.SystemBlocks) > 0 {
+		body.SystemBlocks[len(body.SystemBlocks)-1].CacheControl = claude.EphemeralCache()
+	}
+
+	messages := slices.Clone(body.Messages)
+	if last := len(messages) - 1; last >= 0 {
+		messages[last] = claude.CacheMessage(messages[last])
+		for i := last - 1; i >= 0; i-- {
+			if messages[i].Role == claude.MessageRoleUser {
+				messages[i] = claude.CacheMessage(messages[i])
+				break
+			}
+		}
+	}
+	body.Messages = messages
+	return body
+}
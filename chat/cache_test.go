@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+)
+
+func TestAddCacheBreakpoints(t *testing.T) {
+	long := strings.Repeat("x", 4*3000)
+	history := []claude.RequestMessages{
+		{Role: claude.MessageRoleUser, Content: long},
+		{Role: claude.MessageRoleAssistant, Content: "a1"},
+		{Role: claude.MessageRoleUser, Content: "q2"},
+		{Role: claude.MessageRoleAssistant, Content: "a2"},
+		{Role: claude.MessageRoleUser, Content: "q3"},
+	}
+	body := claude.RequestBody{Model: "claude-3-5-sonnet-20241022", System: "Be brief.", Messages: history}
+
+	cached := AddCacheBreakpoints(body)
+	if len(cached.SystemBlocks) != 1 || cached.SystemBlocks[0].Text != "Be brief." || cached.SystemBlocks[0].CacheControl == nil {
+		t.Errorf("Expected the system prompt to be cached, got %+v", cached.SystemBlocks)
+	}
+	var marked []int
+	for i, m := range cached.Messages {
+		if len(m.ContentBlocks) > 0 && m.ContentBlocks[len(m.ContentBlocks)-1].CacheControl != nil {
+			marked = append(marked, i)
+		}
+	}
+	if len(marked) != 2 || marked[0] != 2 || marked[1] != 4 {
+		t.Errorf("Expected the previous and the new user message to be cached, got %v", marked)
+	}
+	if history[4].Content != "q3" || len(history[4].ContentBlocks) != 0 {
+		t.Errorf("Expected the history to be left alone, got %+v", history[4])
+	}
+
+	for _, short := range []claude.RequestBody{
+		{Model: "claude-3-5-sonnet-20241022", System: "Be brief.", Messages: history[2:]},
+		{Model: "claude-2.1", System: "Be brief.", Messages: history},
+		{Model: "claude-3-haiku-20240307", Messages: []claude.RequestMessages{{Role: claude.MessageRoleUser, Content: long[:4*1500]}}},
+	} {
+		cached := AddCacheBreakpoints(short)
+		if len(cached.SystemBlocks) != 0 || len(cached.Messages[len(cached.Messages)-1].ContentBlocks) != 0 {
+			t.Errorf("Expected no breakpoints for %s with %d messages, got %+v", short.Model, len(short.Messages), cached)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/christianhturner/go-claude/claude"
 	"github.com/christianhturner/go-claude/db"
@@ -33,23 +34,89 @@ func GetMessagePairs(history []claude.RequestMessages) []MessagePair {
 	return pairs
 }
 
+// GetConversationHistory replays the conversation's active branch.
 func GetConversationHistory(convId int64) []claude.RequestMessages {
-	messages, err := db.GetMessages(convId)
+	messages, err := db.GetActiveBranch(convId)
 	if err != nil {
 		logger.PanicError(err, "Error getting messages from conversation table")
 	}
+	return replayMessages(convId, messages)
+}
+
+// GetCompactedHistory replays the conversation's active branch the way it is
+// sent to Claude: the text of the branch's latest summary, empty when it has
+// none, and the messages the summary does not cover.
+func GetCompactedHistory(convId int64) (string, []claude.RequestMessages) {
+	activeID, err := db.GetActiveMessageID(convId)
+	if err != nil {
+		logger.PanicError(err, "Error getting the active message from conversation table")
+	}
+	if activeID == 0 {
+		return "", nil
+	}
+	return GetCompactedBranch(convId, activeID)
+}
+
+// GetCompactedBranch is GetCompactedHistory for the branch ending on messageID.
+func GetCompactedBranch(convId, messageID int64) (string, []claude.RequestMessages) {
+	summary, messages, err := compactedBranch(messageID)
+	if err != nil {
+		logger.PanicError(err, "Error getting messages from conversation table")
+	}
+	if summary == nil {
+		return "", replayMessages(convId, messages)
+	}
+	return summary.Content, replayMessages(convId, messages)
+}
+
+func replayMessages(convId int64, messages []db.Message) []claude.RequestMessages {
+	attachments, err := db.GetConversationAttachments(convId)
+	if err != nil {
+		logger.PanicError(err, "Error getting attachments from conversation table")
+	}
 
 	var historicMessages []claude.RequestMessages
 	for _, historicMessage := range messages {
-		claudeMessage := claude.RequestMessages{
-			Role:    historicMessage.Role,
-			Content: historicMessage.Content,
+		claudeMessage, err := MessageWithAttachmentsToRequest(historicMessage.Role, historicMessage.Content, attachments[historicMessage.ID])
+		if err != nil {
+			logger.PanicError(err, "Error replaying message attachments")
+		}
+		if historicMessage.Thinking != "" {
+			claudeMessage, err = withThinking(claudeMessage, historicMessage.Thinking)
+			if err != nil {
+				logger.PanicError(err, "Error replaying message thinking")
+			}
 		}
 		historicMessages = append(historicMessages, claudeMessage)
 	}
 	return historicMessages
 }
 
+// Fork makes the conversation continue after messageID, the next message
+// starting a new branch. Forking on a user message replaces it rather than
+// answering it twice, so the branch resumes from the message before it. It
+// returns the message the branch now ends on, nil at the beginning of the
+// conversation.
+func Fork(conversationID, messageID int64) (*db.Message, error) {
+	if messageID != 0 {
+		message, err := db.GetMessage(messageID)
+		if err != nil {
+			return nil, err
+		}
+		if message == nil || message.ConversationID != conversationID {
+			return nil, fmt.Errorf("message %d is not part of conversation %d", messageID, conversationID)
+		}
+		if message.Role == claude.MessageRoleUser {
+			messageID = message.ParentID
+		}
+	}
+	err := db.SetActiveMessage(conversationID, messageID)
+	if err != nil || messageID == 0 {
+		return nil, err
+	}
+	return db.GetMessage(messageID)
+}
+
 func AppendHistoryToMessageRequest(messageRequest claude.RequestMessages, history []claude.RequestMessages) []claude.RequestMessages {
 	return append(history, messageRequest)
 }
@@ -65,6 +132,20 @@ func AddMessageToConversationTable(convId int64, message claude.RequestMessages)
 	db.AddMessage(convId, message.Role, message.Content)
 }
 
+func AddReplyToConversationTable(convId int64, reply Reply) {
+	_, err := db.AddReplyWithThinking(convId, reply.Text, reply.Thinking, reply.Usage)
+	if err != nil {
+		logger.PanicError(err, "Error adding reply to conversation table")
+	}
+}
+
+func AddMessageWithAttachmentsToConversationTable(convId int64, message claude.RequestMessages, attachments []db.Attachment) {
+	_, err := db.AddMessageWithAttachments(convId, message.Role, message.Content, attachments)
+	if err != nil {
+		logger.PanicError(err, "Error adding message with attachments to conversation table")
+	}
+}
+
 func SendMessageToClaude(ctx context.Context, body claude.RequestBody, client claude.Client) *claude.ResponseBody {
 	res, err := client.CreateMessages(ctx, body)
 	if err != nil {
@@ -0,0 +1,522 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/export"
+)
+
+// ErrExitSession is returned by a command to end the session.
+var ErrExitSession = errors.New("exit session")
+
+// Command is a slash command run from inside a chat session, e.g. "/model".
+// Run receives everything typed after the command name, trimmed.
+type Command struct {
+	Name        string // without the leading slash
+	Aliases     []string
+	Usage       string
+	Description string
+	Run         func(ctx context.Context, s *Session, args string) error
+}
+
+var commands = map[string]Command{}
+
+// RegisterCommand makes a command available in every session. Registering a
+// name twice replaces the earlier command.
+func RegisterCommand(command Command) {
+	commands[command.Name] = command
+	for _, alias := range command.Aliases {
+		commands[alias] = command
+	}
+}
+
+// LookupCommand finds a command by name or alias, with or without the slash.
+func LookupCommand(name string) (Command, bool) {
+	command, ok := commands[strings.TrimPrefix(name, "/")]
+	return command, ok
+}
+
+// Commands returns every registered command once, sorted by name.
+func Commands() []Command {
+	var list []Command
+	for name, command := range commands {
+		if name == command.Name {
+			list = append(list, command)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// IsCommand reports whether the input should be handled as a slash command.
+func IsCommand(input string) bool {
+	return strings.HasPrefix(input, "/")
+}
+
+// RunCommand parses and runs a slash command typed in the session.
+func (s *Session) RunCommand(ctx context.Context, input string) error {
+	name, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+	command, ok := LookupCommand(name)
+	if !ok {
+		return fmt.Errorf("unknown command %s, type /help to list the commands", name)
+	}
+	return command.Run(ctx, s, strings.TrimSpace(args))
+}
+
+func init() {
+	RegisterCommand(Command{
+		Name:        "help",
+		Usage:       "/help",
+		Description: "List the available commands.",
+		Run:         runHelpCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "exit",
+		Aliases:     []string{"quit"},
+		Usage:       "/exit",
+		Description: "Leave the session.",
+		Run: func(ctx context.Context, s *Session, args string) error {
+			return ErrExitSession
+		},
+	})
+	RegisterCommand(Command{
+		Name:        "model",
+		Usage:       "/model [name]",
+		Description: "Show or change the conversation's model.",
+		Run:         runModelCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "system",
+		Usage:       "/system [prompt|clear]",
+		Description: "Show, set or clear the conversation's system prompt.",
+		Run:         runSystemCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "persona",
+		Usage:       "/persona [name]",
+		Description: "Show or switch the conversation's persona.",
+		Run:         runPersonaCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "retry",
+		Usage:       "/retry",
+		Description: "Ask again for the last reply, keeping the old one as a branch.",
+		Run:         runRetryCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "undo",
+		Usage:       "/undo",
+		Description: "Step back before the last message pair, keeping it as a branch.",
+		Run:         runUndoCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "history",
+		Usage:       "/history [pairs]",
+		Description: "Show the last message pairs of the branch, 5 by default.",
+		Run:         runHistoryCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "thinking",
+		Usage:       "/thinking [message id]",
+		Description: "Show the thinking of the last reply, or of another on the branch.",
+		Run:         runThinkingCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "fork",
+		Usage:       "/fork <message id>",
+		Description: "Continue the conversation from an earlier message.",
+		Run:         runForkCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "branches",
+		Usage:       "/branches",
+		Description: "List the branches of the conversation.",
+		Run:         runBranchesCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "switch",
+		Usage:       "/switch <message id>",
+		Description: "Switch to the latest branch through a message.",
+		Run:         runSwitchCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "compact",
+		Usage:       "/compact [pairs]",
+		Description: "Summarize the conversation except for its latest message pairs.",
+		Run:         runCompactCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "export",
+		Usage:       "/export [path]",
+		Description: "Export the conversation, in the format of the path's extension (md, json, jsonl, html).",
+		Run:         runExportCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "tokens",
+		Usage:       "/tokens",
+		Description: "Show the tokens used by the last reply and the session.",
+		Run:         runTokensCommand,
+	})
+	RegisterCommand(Command{
+		Name:        "title",
+		Usage:       "/title [title]",
+		Description: "Show or rename the conversation title.",
+		Run:         runTitleCommand,
+	})
+}
+
+func runHelpCommand(ctx context.Context, s *Session, args string) error {
+	for _, command := range Commands() {
+		fmt.Fprintf(s.out, "  %-24s %s\n", command.Usage, command.Description)
+	}
+	fmt.Fprintf(s.out, "  %-24s %s\n", "@path", "Attach a file to the message: text is inlined, images and PDFs are sent as blocks.")
+	return nil
+}
+
+func runModelCommand(ctx context.Context, s *Session, args string) error {
+	if args != "" {
+		err := ConfigureConversation(s.ConversationID, map[string]string{db.OptionModel: args})
+		if err != nil {
+			return err
+		}
+		// The stored model must not be hidden by a --model given for the session.
+		delete(s.overrides, db.OptionModel)
+		err = s.ReloadSettings()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(s.out, "Model: %s\n", s.Settings.Model)
+	return nil
+}
+
+func runSystemCommand(ctx context.Context, s *Session, args string) error {
+	if args != "" {
+		system := args
+		if args == "clear" {
+			system = ""
+		}
+		err := SetConversationSystem(s.ConversationID, system)
+		if err != nil {
+			return err
+		}
+		err = s.ReloadSettings()
+		if err != nil {
+			return err
+		}
+	}
+	if s.Settings.System == "" {
+		fmt.Fprintln(s.out, "No system prompt set.")
+		return nil
+	}
+	fmt.Fprintf(s.out, "System prompt: %s\n", s.Settings.System)
+	return nil
+}
+
+func runPersonaCommand(ctx context.Context, s *Session, args string) error {
+	if args != "" {
+		err := SetConversationPersona(s.ConversationID, args)
+		if err != nil {
+			return err
+		}
+		err = s.ReloadSettings()
+		if err != nil {
+			return err
+		}
+	}
+	options, err := ConversationOptions(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	if options[db.OptionPersona] == "" {
+		fmt.Fprintln(s.out, "No persona set.")
+		return nil
+	}
+	fmt.Fprintf(s.out, "Persona: %s (model %s)\n", options[db.OptionPersona], s.Settings.Model)
+	return nil
+}
+
+func runRetryCommand(ctx context.Context, s *Session, args string) error {
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	last := len(branch) - 1
+	if last < 1 || branch[last].Role != claude.MessageRoleAssistant {
+		return errors.New("there is no reply to retry")
+	}
+
+	summary, history := GetCompactedBranch(s.ConversationID, branch[last].ParentID)
+	reply, err := s.complete(ctx, summary, history)
+	if err != nil {
+		return err
+	}
+
+	// The new reply becomes a sibling of the old one, which stays available
+	// as another branch.
+	err = db.SetActiveMessage(s.ConversationID, branch[last].ParentID)
+	if err != nil {
+		return err
+	}
+	_, err = db.AddReplyWithThinking(s.ConversationID, reply.Text, reply.Thinking, reply.Usage)
+	return err
+}
+
+func runUndoCommand(ctx context.Context, s *Session, args string) error {
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	last := len(branch) - 1
+	if last < 1 || branch[last].Role != claude.MessageRoleAssistant || branch[last-1].Role != claude.MessageRoleUser {
+		return errors.New("there is no message pair to undo")
+	}
+	err = db.SetActiveMessage(s.ConversationID, branch[last-1].ParentID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Removed: %s\n", truncate(branch[last-1].Content, 60))
+	fmt.Fprintf(s.out, "The pair is kept as a branch, /switch %d goes back to it.\n", branch[last].ID)
+	return nil
+}
+
+func runForkCommand(ctx context.Context, s *Session, args string) error {
+	messageID, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid message id: %q, /history lists the ids", args)
+	}
+	message, err := Fork(s.ConversationID, messageID)
+	if err != nil {
+		return err
+	}
+	if message == nil {
+		fmt.Fprintln(s.out, "The next message starts a new branch from the beginning of the conversation.")
+		return nil
+	}
+	fmt.Fprintf(s.out, "The next message branches off after: %s\n", truncate(message.Content, 60))
+	return nil
+}
+
+func runBranchesCommand(ctx context.Context, s *Session, args string) error {
+	leaves, err := db.GetBranchLeaves(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	if len(leaves) == 0 {
+		fmt.Fprintln(s.out, "No messages yet.")
+		return nil
+	}
+	activeID, err := db.GetActiveMessageID(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	for _, leaf := range leaves {
+		marker := " "
+		if leaf.ID == activeID {
+			marker = "*"
+		}
+		branch, err := db.GetBranch(leaf.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "%s %-6d %3d messages  %s\n", marker, leaf.ID, len(branch), truncate(leaf.Content, 50))
+	}
+	if !slices.ContainsFunc(leaves, func(m db.Message) bool { return m.ID == activeID }) {
+		fmt.Fprintf(s.out, "Forked after message %d, the next message starts a new branch.\n", activeID)
+	}
+	return nil
+}
+
+func runSwitchCommand(ctx context.Context, s *Session, args string) error {
+	messageID, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid message id: %q, /branches lists the ids", args)
+	}
+	leafID, err := db.GetLatestLeaf(messageID)
+	if err != nil {
+		return err
+	}
+	err = db.SetActiveMessage(s.ConversationID, leafID)
+	if err != nil {
+		return err
+	}
+	return runHistoryCommand(ctx, s, "1")
+}
+
+func runHistoryCommand(ctx context.Context, s *Session, args string) error {
+	count := 5
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of pairs: %s", args)
+		}
+		count = n
+	}
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	if len(branch) == 0 {
+		fmt.Fprintln(s.out, "No messages yet.")
+		return nil
+	}
+	if 2*count < len(branch) {
+		branch = branch[len(branch)-2*count:]
+	}
+	for _, m := range branch {
+		name := "User"
+		if m.Role == claude.MessageRoleAssistant {
+			name = "Claude"
+		}
+		thinking, err := DecodeThinking(m.Thinking)
+		if err != nil {
+			return err
+		}
+		if collapsed := CollapsedThinking(thinking); collapsed != "" {
+			fmt.Fprintf(s.out, "\n[%d] %s: %s\n%s\n", m.ID, name, collapsed, m.Content)
+			continue
+		}
+		fmt.Fprintf(s.out, "\n[%d] %s: %s\n", m.ID, name, m.Content)
+	}
+	return nil
+}
+
+func runThinkingCommand(ctx context.Context, s *Session, args string) error {
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	var reply *db.Message
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Role != claude.MessageRoleAssistant {
+			continue
+		}
+		if args == "" || strconv.FormatInt(branch[i].ID, 10) == args {
+			reply = &branch[i]
+			break
+		}
+	}
+	if reply == nil {
+		if args != "" {
+			return fmt.Errorf("no reply %s on the branch, /history lists the ids", args)
+		}
+		return errors.New("there is no reply yet")
+	}
+
+	blocks, err := DecodeThinking(reply.Thinking)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		fmt.Fprintf(s.out, "Reply %d has no thinking.\n", reply.ID)
+		return nil
+	}
+	for _, block := range blocks {
+		if block.Type == claude.RequestContentTypeRedactedThinkingType {
+			fmt.Fprintln(s.out, "[redacted thinking]")
+			continue
+		}
+		fmt.Fprintln(s.out, block.Thinking)
+	}
+	return nil
+}
+
+func runCompactCommand(ctx context.Context, s *Session, args string) error {
+	keep := s.Settings.CompactKeepRecent
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of pairs: %s", args)
+		}
+		keep = n
+	}
+	report, err := Compact(ctx, s.client, s.ConversationID, s.Settings, keep)
+	if err != nil {
+		return err
+	}
+	s.recordUsage(report.Usage)
+	fmt.Fprintf(s.out, "Summarized %d messages, about %d tokens, in about %d tokens. The latest %d messages are sent as they are.\n",
+		report.SummarizedMessages, report.TokensBefore, report.TokensAfter, report.KeptMessages)
+	return nil
+}
+
+func runExportCommand(ctx context.Context, s *Session, args string) error {
+	path := args
+	format := export.FormatMarkdown
+	if path == "" {
+		conversation, err := db.GetConversation(s.ConversationID)
+		if err != nil {
+			return err
+		}
+		if conversation == nil {
+			return fmt.Errorf("conversation %d not found", s.ConversationID)
+		}
+		path = export.FileName(conversation.ID, conversation.Title, format)
+	} else if ext := filepath.Ext(path); ext != "" {
+		parsed, err := export.ParseFormat(ext)
+		if err != nil {
+			return err
+		}
+		format = parsed
+	}
+
+	err := export.ExportConversation(s.ConversationID, format, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Exported conversation to %s\n", path)
+	return nil
+}
+
+func runTokensCommand(ctx context.Context, s *Session, args string) error {
+	fmt.Fprintf(s.out, "Last reply: %d input, %d output tokens", s.lastUsage.InputTokens, s.lastUsage.OutputTokens)
+	if s.lastUsage.CacheCreationTokens > 0 || s.lastUsage.CacheReadTokens > 0 {
+		fmt.Fprintf(s.out, ", %d written to and %d read from the cache", s.lastUsage.CacheCreationTokens, s.lastUsage.CacheReadTokens)
+	}
+	if cost, ok := usageCost(s.lastUsage); ok {
+		fmt.Fprintf(s.out, ", $%.4f", cost)
+	}
+	fmt.Fprintf(s.out, "\nSession:    %d input, %d output tokens", s.totalUsage.InputTokens, s.totalUsage.OutputTokens)
+	if s.totalUsage.CacheCreationInputTokens > 0 || s.totalUsage.CacheReadInputTokens > 0 {
+		fmt.Fprintf(s.out, ", %d written to and %d read from the cache", s.totalUsage.CacheCreationInputTokens, s.totalUsage.CacheReadInputTokens)
+	}
+	fmt.Fprintf(s.out, ", $%.4f\n", s.totalCost)
+	return nil
+}
+
+func runTitleCommand(ctx context.Context, s *Session, args string) error {
+	if args != "" {
+		err := db.UpdateConversationTitle(s.ConversationID, args)
+		if err != nil {
+			return err
+		}
+	}
+	conversation, err := db.GetConversation(s.ConversationID)
+	if err != nil {
+		return err
+	}
+	if conversation == nil {
+		return fmt.Errorf("conversation %d not found", s.ConversationID)
+	}
+	fmt.Fprintf(s.out, "Title: %s\n", conversation.Title)
+	return nil
+}
+
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len([]rune(s)) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-3]) + "..."
+}
@@ -0,0 +1,280 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// newTestSession opens a session on a new conversation holding messages,
+// alternating user and assistant, and returns it with what it prints.
+func newTestSession(t *testing.T, client *claude.Client, messages ...string) (*Session, *bytes.Buffer) {
+	t.Helper()
+	convID, err := db.CreateConversation("Commands")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for i, content := range messages {
+		role := claude.MessageRoleUser
+		if i%2 == 1 {
+			role = claude.MessageRoleAssistant
+		}
+		err := db.AddMessage(convID, role, content)
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+	}
+	s, err := NewSession(convID, client, Overrides{
+		db.OptionModel:     "claude-3-haiku-20240307",
+		db.OptionMaxTokens: "100",
+		db.OptionStream:    "false",
+	})
+	if err != nil {
+		t.Fatalf("NewSession returned an error: %v", err)
+	}
+	var out bytes.Buffer
+	s.SetWriter(&out)
+	return s, &out
+}
+
+func TestCommandRegistry(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	s, _ := newTestSession(t, nil)
+	err = s.RunCommand(context.Background(), "/nope")
+	if err == nil || !strings.Contains(err.Error(), "unknown command /nope") {
+		t.Errorf("Expected an unknown command error, got %v", err)
+	}
+
+	if command, ok := LookupCommand("/quit"); !ok || command.Name != "exit" {
+		t.Errorf("Expected /quit to be an alias of /exit, got %+v", command)
+	}
+	if err := s.RunCommand(context.Background(), "/quit"); err != ErrExitSession {
+		t.Errorf("Expected /quit to end the session, got %v", err)
+	}
+
+	var got string
+	RegisterCommand(Command{
+		Name: "echo-test",
+		Run: func(ctx context.Context, s *Session, args string) error {
+			got = args
+			return nil
+		},
+	})
+	defer delete(commands, "echo-test")
+	if err := s.RunCommand(context.Background(), "/echo-test   some words  "); err != nil {
+		t.Fatalf("RunCommand returned an error: %v", err)
+	}
+	if got != "some words" {
+		t.Errorf("Expected the trimmed arguments, got %q", got)
+	}
+}
+
+func TestRetryCommand(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var sent []int
+	client := chatServer(t, nil, &sent)
+
+	for _, messages := range [][]string{nil, {"Hello"}} {
+		s, _ := newTestSession(t, client, messages...)
+		err := s.RunCommand(context.Background(), "/retry")
+		if err == nil || !strings.Contains(err.Error(), "no reply to retry") {
+			t.Errorf("%q: expected no reply to retry, got %v", messages, err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Errorf("Expected nothing to be sent without a reply to retry, got %d requests", len(sent))
+	}
+
+	s, _ := newTestSession(t, client, "Hello", "Old reply")
+	if err := s.RunCommand(context.Background(), "/retry"); err != nil {
+		t.Fatalf("/retry returned an error: %v", err)
+	}
+	if got := branchContents(t, s.ConversationID); strings.Join(got, "|") != "user: Hello|assistant: Reply 1" {
+		t.Errorf("Expected the new reply on the active branch, got %q", got)
+	}
+	if len(sent) != 1 || sent[0] != 1 {
+		t.Errorf("Expected the retry to send the user message alone, got %v", sent)
+	}
+	leaves, err := db.GetBranchLeaves(s.ConversationID)
+	if err != nil {
+		t.Fatalf("GetBranchLeaves returned an error: %v", err)
+	}
+	if len(leaves) != 2 {
+		t.Errorf("Expected the old reply kept as a branch, got %d branches", len(leaves))
+	}
+}
+
+func TestUndoCommand(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	for _, messages := range [][]string{nil, {"Hello"}} {
+		s, _ := newTestSession(t, nil, messages...)
+		err := s.RunCommand(context.Background(), "/undo")
+		if err == nil || !strings.Contains(err.Error(), "no message pair to undo") {
+			t.Errorf("%q: expected no message pair to undo, got %v", messages, err)
+		}
+	}
+
+	s, out := newTestSession(t, nil, "q1", "a1", "q2", "a2")
+	if err := s.RunCommand(context.Background(), "/undo"); err != nil {
+		t.Fatalf("/undo returned an error: %v", err)
+	}
+	if got := branchContents(t, s.ConversationID); strings.Join(got, "|") != "user: q1|assistant: a1" {
+		t.Errorf("Expected the last pair undone, got %q", got)
+	}
+	if !strings.Contains(out.String(), "Removed: q2") {
+		t.Errorf("Expected the removed message to be named, got %q", out.String())
+	}
+
+	// Undoing the first pair leaves the conversation at its root.
+	if err := s.RunCommand(context.Background(), "/undo"); err != nil {
+		t.Fatalf("/undo at the root returned an error: %v", err)
+	}
+	if got := branchContents(t, s.ConversationID); len(got) != 0 {
+		t.Errorf("Expected an empty branch, got %q", got)
+	}
+	if err := s.RunCommand(context.Background(), "/undo"); err == nil {
+		t.Errorf("Expected nothing left to undo")
+	}
+	leaves, err := db.GetBranchLeaves(s.ConversationID)
+	if err != nil {
+		t.Fatalf("GetBranchLeaves returned an error: %v", err)
+	}
+	if len(leaves) != 1 {
+		t.Errorf("Expected the undone pairs kept as a branch, got %d branches", len(leaves))
+	}
+}
+
+func TestModelCommand(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	s, out := newTestSession(t, nil)
+	if err := s.RunCommand(context.Background(), "/model"); err != nil {
+		t.Fatalf("/model returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Model: claude-3-haiku-20240307") {
+		t.Errorf("Expected the current model, got %q", out.String())
+	}
+
+	if err := s.RunCommand(context.Background(), "/model claude-3-opus-20240229"); err != nil {
+		t.Fatalf("/model returned an error: %v", err)
+	}
+	if s.Settings.Model != "claude-3-opus-20240229" {
+		t.Errorf("Expected the session to use the new model, got %s", s.Settings.Model)
+	}
+	options, err := ConversationOptions(s.ConversationID)
+	if err != nil {
+		t.Fatalf("ConversationOptions returned an error: %v", err)
+	}
+	if options[db.OptionModel] != "claude-3-opus-20240229" {
+		t.Errorf("Expected the model stored with the conversation, got %q", options[db.OptionModel])
+	}
+
+	if err := s.RunCommand(context.Background(), "/model claude-0-unknown"); err == nil {
+		t.Errorf("Expected an unknown model to be refused")
+	}
+	options, _ = ConversationOptions(s.ConversationID)
+	if options[db.OptionModel] != "claude-3-opus-20240229" || s.Settings.Model != "claude-3-opus-20240229" {
+		t.Errorf("Expected a refused model to change nothing, got %q and %s", options[db.OptionModel], s.Settings.Model)
+	}
+}
+
+func TestForkCommand(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var sent []int
+	s, out := newTestSession(t, chatServer(t, nil, &sent), "q1", "a1", "q2", "a2")
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	ids := map[string]int64{}
+	for _, m := range branch {
+		ids[m.Content] = m.ID
+	}
+	other, _ := newTestSession(t, nil, "elsewhere")
+
+	tests := []struct {
+		name string
+		args string
+		want string // the branch after forking
+		out  string
+	}{
+		{"reply", fmt.Sprint(ids["a1"]), "user: q1|assistant: a1", "branches off after: a1"},
+		{"user message", fmt.Sprint(ids["q2"]), "user: q1|assistant: a1", "branches off after: a1"},
+		{"first user message", fmt.Sprint(ids["q1"]), "", "from the beginning"},
+		{"root", "0", "", "from the beginning"},
+	}
+	for _, tt := range tests {
+		db.SetActiveMessage(s.ConversationID, ids["a2"])
+		out.Reset()
+		if err := s.RunCommand(context.Background(), "/fork "+tt.args); err != nil {
+			t.Errorf("%s: /fork returned an error: %v", tt.name, err)
+			continue
+		}
+		if got := strings.Join(branchContents(t, s.ConversationID), "|"); got != tt.want {
+			t.Errorf("%s: expected the branch %q, got %q", tt.name, tt.want, got)
+		}
+		if !strings.Contains(out.String(), tt.out) {
+			t.Errorf("%s: expected the output to contain %q, got %q", tt.name, tt.out, out.String())
+		}
+	}
+
+	for _, args := range []string{"", "abc", fmt.Sprint(other.ConversationID * 1000), otherMessageID(t, other)} {
+		if err := s.RunCommand(context.Background(), "/fork "+args); err == nil {
+			t.Errorf("/fork %s: expected an error", args)
+		}
+	}
+
+	// The message after forking on a user message takes its place.
+	if _, err := Fork(s.ConversationID, ids["q2"]); err != nil {
+		t.Fatalf("Fork returned an error: %v", err)
+	}
+	if err := s.Send(context.Background(), "q2 again"); err != nil {
+		t.Fatalf("Send returned an error: %v", err)
+	}
+	if got := strings.Join(branchContents(t, s.ConversationID), "|"); got != "user: q1|assistant: a1|user: q2 again|assistant: Reply 1" {
+		t.Errorf("Expected the new message to replace q2, got %q", got)
+	}
+	if len(sent) != 1 || sent[0] != 3 {
+		t.Errorf("Expected the request to alternate roles with 3 messages, got %v", sent)
+	}
+}
+
+// otherMessageID returns the ID of the first message of another session's
+// conversation.
+func otherMessageID(t *testing.T, s *Session) string {
+	t.Helper()
+	branch, err := db.GetActiveBranch(s.ConversationID)
+	if err != nil || len(branch) == 0 {
+		t.Fatalf("GetActiveBranch returned %v, %v", branch, err)
+	}
+	return fmt.Sprint(branch[0].ID)
+}
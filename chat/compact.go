@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Compacting a conversation asks Claude to summarize the older part of its
+// active branch. The summary is stored below the last message it covers, and
+// from then on requests send the summary, as part of the system prompt, and
+// the messages after it instead of the whole branch. The summarized messages
+// are kept, so history, search and export still see all of them.
+
+// ErrNothingToCompact is returned when the branch is too short to summarize
+// anything beyond the pairs that are kept as they are.
+var ErrNothingToCompact = errors.New("nothing to compact, every message is among the most recent ones kept as they are")
+
+const compactSystemPrompt = `You condense conversations between a user and an AI assistant so they can be continued without the full transcript.
+
+Write a summary of the conversation you are given, in the language of the conversation. Keep every fact, decision, requirement, name, number, piece of code and open question that later turns may depend on, and note what the user prefers or asked to avoid. Leave out greetings, repetition and anything that was superseded. If the transcript starts with an earlier summary, fold it into yours.
+
+Answer with the summary only.`
+
+const summaryHeading = "Summary of the earlier part of this conversation:"
+
+// CompactReport tells what Compact summarized.
+type CompactReport struct {
+	SummaryID          int64
+	SummarizedMessages int      // messages newly covered by the summary
+	KeptMessages       int      // recent messages still sent as they are
+	TokensBefore       int      // estimated tokens of what the summary replaces
+	TokensAfter        int      // estimated tokens of the summary
+	Usage              db.Usage // of the summarizing request
+}
+
+// SystemWithSummary adds a conversation summary to a system prompt.
+func SystemWithSummary(system, summary string) string {
+	if summary == "" {
+		return system
+	}
+	section := summaryHeading + "\n\n" + summary
+	if system == "" {
+		return section
+	}
+	return system + "\n\n" + section
+}
+
+// Compact summarizes the conversation's active branch, up to its keepRecent
+// latest message pairs, using the model in settings. An earlier summary of the
+// branch is folded into the new one.
+func Compact(ctx context.Context, client *claude.Client, conversationID int64, settings Settings, keepRecent int) (*CompactReport, error) {
+	activeID, err := db.GetActiveMessageID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if activeID == 0 {
+		return nil, ErrNothingToCompact
+	}
+	summary, messages, err := compactedBranch(activeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The summary ends on a reply and the kept messages start with a
+	// question, at least one pair is always kept.
+	if keepRecent < 1 {
+		keepRecent = 1
+	}
+	cut := len(messages) - 2*keepRecent
+	for cut > 0 && messages[cut].Role != claude.MessageRoleUser {
+		cut--
+	}
+	if cut <= 0 {
+		return nil, ErrNothingToCompact
+	}
+
+	attachments, err := db.GetConversationAttachments(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	transcript, tokens := compactTranscript(summary, messages[:cut], attachments)
+
+	start := time.Now()
+	response, err := client.CreateMessages(ctx, claude.RequestBody{
+		Model:     settings.Model,
+		MaxTokens: settings.MaxTokens,
+		System:    compactSystemPrompt,
+		Messages: []claude.RequestMessages{{
+			Role:    claude.MessageRoleUser,
+			Content: transcript,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("summarizing conversation %d: %w", conversationID, err)
+	}
+	text := strings.TrimSpace(response.Text())
+	if text == "" {
+		return nil, fmt.Errorf("summarizing conversation %d: the reply was empty (stop reason %s)", conversationID, response.StopReason)
+	}
+
+	usage := responseUsage(response, settings.Model, time.Since(start))
+	summaryID, err := db.AddSummary(conversationID, messages[cut-1].ID, text)
+	if err != nil {
+		return nil, err
+	}
+	usage.MessageID = summaryID
+	usage.ConversationID = conversationID
+	err = db.AddUsage(usage)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactReport{
+		SummaryID:          summaryID,
+		SummarizedMessages: cut,
+		KeptMessages:       len(messages) - cut,
+		TokensBefore:       tokens,
+		TokensAfter:        claude.EstimateTokens(text),
+		Usage:              usage,
+	}, nil
+}
+
+// ShouldCompact tells whether a request has grown past the share of the
+// context budget set by settings.CompactThreshold.
+func ShouldCompact(body claude.RequestBody, settings Settings) bool {
+	if settings.CompactThreshold <= 0 {
+		return false
+	}
+	return claude.EstimateRequestTokens(body) > ContextBudget(settings)*settings.CompactThreshold/100
+}
+
+// compactedBranch returns the latest summary of the branch ending on
+// messageID, nil when there is none, and the messages after it.
+func compactedBranch(messageID int64) (*db.Message, []db.Message, error) {
+	branch, err := db.GetBranch(messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, err := db.GetBranchSummary(messageID)
+	if err != nil || summary == nil {
+		return nil, branch, err
+	}
+	for i, m := range branch {
+		if m.ID == summary.ParentID {
+			return summary, branch[i+1:], nil
+		}
+	}
+	return nil, branch, nil
+}
+
+// compactTranscript writes the messages to summarize as a single text, and
+// returns it with the estimated tokens of what it replaces.
+func compactTranscript(summary *db.Message, messages []db.Message, attachments map[int64][]db.Attachment) (string, int) {
+	var b strings.Builder
+	tokens := 0
+	if summary != nil {
+		fmt.Fprintf(&b, "%s\n\n%s\n\n", summaryHeading, summary.Content)
+		tokens += claude.EstimateTokens(summary.Content)
+	}
+	b.WriteString("Transcript:\n")
+	for _, m := range messages {
+		name := "User"
+		if m.Role == claude.MessageRoleAssistant {
+			name = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n%s: %s\n", name, m.Content)
+		tokens += claude.EstimateTokens(m.Content)
+		for _, a := range attachments[m.ID] {
+			if a.Kind == db.AttachmentKindText {
+				fmt.Fprintf(&b, "[Attached file %s]\n%s\n", a.Path, a.Data)
+				tokens += claude.EstimateTokens(a.Data)
+				continue
+			}
+			fmt.Fprintf(&b, "[Attached %s %s, not included]\n", a.Kind, a.Path)
+			tokens += claude.EstimateBlockTokens(claude.RequestContentBlock{Type: a.Kind, Source: &claude.RequestContentSource{Data: a.Data}})
+		}
+	}
+	return b.String(), tokens
+}
@@ -0,0 +1,135 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// summaryRequest is the part of a request the tests look at.
+type summaryRequest struct {
+	System   string `json:"system"`
+	Messages []struct {
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+// summaryServer answers every request with summary and records the requests.
+func summaryServer(t *testing.T, summary string, requests *[]summaryRequest) *claude.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body summaryRequest
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		*requests = append(*requests, body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"type":        "message",
+			"role":        "assistant",
+			"content":     []map[string]string{{"type": "text", "text": summary}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 100, "output_tokens": 10},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return claude.NewClientWithConfig(claude.ClientConfig{
+		BaseURL:    server.URL + "/",
+		Endpoint:   "v1/messages",
+		HTTPCLient: server.Client(),
+	})
+}
+
+func TestCompact(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	convID, err := db.CreateConversation("Compact")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, content := range []string{"q1", "a1", "q2", "a2", "q3", "a3"} {
+		role := claude.MessageRoleUser
+		if strings.HasPrefix(content, "a") {
+			role = claude.MessageRoleAssistant
+		}
+		err := db.AddMessage(convID, role, content)
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+	}
+
+	var requests []summaryRequest
+	client := summaryServer(t, "The user asked q1 and q2.", &requests)
+	settings := Settings{Model: "claude-3-haiku-20240307", MaxTokens: 500}
+
+	report, err := Compact(context.Background(), client, convID, settings, 1)
+	if err != nil {
+		t.Fatalf("Compact returned an error: %v", err)
+	}
+	if report.SummarizedMessages != 4 || report.KeptMessages != 2 {
+		t.Errorf("Expected 4 messages summarized and 2 kept, got %+v", report)
+	}
+	transcript := requests[0].Messages[0].Content
+	if !strings.Contains(transcript, "User: q2") || strings.Contains(transcript, "q3") {
+		t.Errorf("Expected the transcript to hold the first two pairs only, got %q", transcript)
+	}
+
+	summary, history := GetCompactedHistory(convID)
+	if summary != "The user asked q1 and q2." || len(history) != 2 || history[0].Content != "q3" {
+		t.Errorf("Expected the summary and the last pair, got %q and %+v", summary, history)
+	}
+	if len(GetConversationHistory(convID)) != 6 {
+		t.Errorf("Expected the full history to be kept")
+	}
+
+	// A second compaction folds the first summary in.
+	_, err = Compact(context.Background(), client, convID, settings, 1)
+	if !errors.Is(err, ErrNothingToCompact) {
+		t.Errorf("Expected nothing to compact with a single pair left, got %v", err)
+	}
+	for _, content := range []string{"q4", "a4"} {
+		role := claude.MessageRoleUser
+		if content == "a4" {
+			role = claude.MessageRoleAssistant
+		}
+		err := db.AddMessage(convID, role, content)
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+	}
+	report, err = Compact(context.Background(), client, convID, settings, 1)
+	if err != nil {
+		t.Fatalf("Compact returned an error: %v", err)
+	}
+	transcript = requests[1].Messages[0].Content
+	if report.SummarizedMessages != 2 || !strings.HasPrefix(transcript, summaryHeading) || strings.Contains(transcript, "User: q1") {
+		t.Errorf("Expected the earlier summary and the third pair to be summarized, got %+v and %q", report, transcript)
+	}
+}
+
+func TestShouldCompact(t *testing.T) {
+	settings := Settings{Model: "claude-3-haiku-20240307", ContextBudget: 1000, CompactThreshold: 50}
+	body := claude.RequestBody{Messages: []claude.RequestMessages{{Role: claude.MessageRoleUser, Content: strings.Repeat("x", 1600)}}}
+	if ShouldCompact(body, settings) {
+		t.Errorf("Expected about 400 tokens to stay under half of 1000")
+	}
+	body.System = SystemWithSummary("Be brief.", strings.Repeat("y", 800))
+	if !ShouldCompact(body, settings) {
+		t.Errorf("Expected the summary to push the request over the threshold")
+	}
+	settings.CompactThreshold = 0
+	if ShouldCompact(body, settings) {
+		t.Errorf("Expected a threshold of 0 to never compact")
+	}
+}
@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/christianhturner/go-claude/claude"
+)
+
+// Context strategies decide which history is dropped when a conversation no
+// longer fits the model's context window. The message being sent is always
+// kept, and history is dropped a user/assistant pair at a time so the roles
+// keep alternating.
+const (
+	// ContextStrategyDropOldest drops the oldest pairs first.
+	ContextStrategyDropOldest = "drop-oldest"
+	// ContextStrategyKeepFirstLast keeps the first pairs, which usually set
+	// up the conversation, and drops the oldest of the pairs after them.
+	ContextStrategyKeepFirstLast = "keep-first-last"
+	// ContextStrategyNone sends the whole history, letting the API reject
+	// requests that are too long.
+	ContextStrategyNone = "none"
+)
+
+// ContextStrategies lists every context strategy.
+var ContextStrategies = []string{ContextStrategyDropOldest, ContextStrategyKeepFirstLast, ContextStrategyNone}
+
+// ContextReport tells what FitContext left out of a request.
+type ContextReport struct {
+	Budget          int // input tokens available
+	Tokens          int // estimated input tokens of the request sent
+	DroppedMessages int
+	DroppedTokens   int
+	Strategy        string // the context strategy that dropped them
+}
+
+// LeftOut describes the messages FitContext dropped, e.g. "the 4 oldest
+// messages". Under ContextStrategyKeepFirstLast they come from the middle of
+// the history rather than its start.
+func (r ContextReport) LeftOut() string {
+	if r.Strategy == ContextStrategyKeepFirstLast {
+		return fmt.Sprintf("%d earlier messages", r.DroppedMessages)
+	}
+	return fmt.Sprintf("the %d oldest messages", r.DroppedMessages)
+}
+
+// ContextBudget returns the number of input tokens a request may use: the
+// model's context window less the tokens reserved for the reply, capped by
+// the configured budget when there is one.
+func ContextBudget(settings Settings) int {
+	budget := claude.ContextWindow(settings.Model) - settings.MaxTokens
+	if settings.ContextBudget > 0 && settings.ContextBudget < budget {
+		budget = settings.ContextBudget
+	}
+	return budget
+}
+
+// FitContext drops history from body, following the strategy in settings,
+// until its estimated size fits the context budget. It fails when the
+// request cannot fit even with every droppable message left out.
+func FitContext(body claude.RequestBody, settings Settings) (claude.RequestBody, ContextReport, error) {
+	report := ContextReport{
+		Budget:   ContextBudget(settings),
+		Tokens:   claude.EstimateRequestTokens(body),
+		Strategy: settings.ContextStrategy,
+	}
+	if settings.ContextStrategy == ContextStrategyNone || report.Tokens <= report.Budget {
+		return body, report, nil
+	}
+
+	keep := 0
+	switch settings.ContextStrategy {
+	case ContextStrategyDropOldest, "":
+	case ContextStrategyKeepFirstLast:
+		keep = 2 * settings.ContextKeepFirst
+	default:
+		return body, report, fmt.Errorf("unknown context strategy %q, expected one of %v", settings.ContextStrategy, ContextStrategies)
+	}
+
+	messages := append([]claude.RequestMessages(nil), body.Messages...)
+	last := len(messages) - 1
+	if keep > last {
+		keep = last
+	}
+
+	drop := func(i int) {
+		tokens := claude.EstimateMessageTokens(messages[i])
+		report.Tokens -= tokens
+		report.DroppedTokens += tokens
+		report.DroppedMessages++
+		messages = append(messages[:i], messages[i+1:]...)
+		last--
+	}
+	for report.Tokens > report.Budget && last-keep >= 2 {
+		drop(keep)
+		drop(keep)
+	}
+	// The history after the kept pairs must start with a user message.
+	for keep < last && messages[keep].Role != claude.MessageRoleUser {
+		drop(keep)
+	}
+
+	body.Messages = messages
+	if report.Tokens > report.Budget {
+		return body, report, fmt.Errorf("the request needs about %d tokens even after dropping %d messages, the budget for %s is %d", report.Tokens, report.DroppedMessages, settings.Model, report.Budget)
+	}
+	return body, report, nil
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+)
+
+func testHistory(pairs int, size int) []claude.RequestMessages {
+	var messages []claude.RequestMessages
+	for i := 0; i < pairs; i++ {
+		messages = append(messages,
+			claude.RequestMessages{Role: claude.MessageRoleUser, Content: strings.Repeat("q", size)},
+			claude.RequestMessages{Role: claude.MessageRoleAssistant, Content: strings.Repeat("a", size)},
+		)
+	}
+	return append(messages, claude.RequestMessages{Role: claude.MessageRoleUser, Content: "next"})
+}
+
+func TestFitContextDropOldest(t *testing.T) {
+	settings := Settings{Model: "claude-3-haiku-20240307", MaxTokens: 1000, ContextStrategy: ContextStrategyDropOldest, ContextBudget: 2500}
+	body := claude.RequestBody{Messages: testHistory(5, 2000)} // about 500 tokens a message
+
+	fitted, report, err := FitContext(body, settings)
+	if err != nil {
+		t.Fatalf("FitContext returned an error: %v", err)
+	}
+	if report.DroppedMessages != 6 || len(fitted.Messages) != 5 {
+		t.Errorf("Expected the 3 oldest pairs to be dropped, got %+v with %d messages", report, len(fitted.Messages))
+	}
+	if report.LeftOut() != "the 6 oldest messages" {
+		t.Errorf("Unexpected notice %q", report.LeftOut())
+	}
+	if report.Tokens > report.Budget {
+		t.Errorf("Expected the request to fit, got %+v", report)
+	}
+	if fitted.Messages[0].Role != claude.MessageRoleUser || fitted.Messages[len(fitted.Messages)-1].Content != "next" {
+		t.Errorf("Expected a user message first and the new message last")
+	}
+	if len(body.Messages) != 11 {
+		t.Errorf("Expected the original request to be left alone")
+	}
+}
+
+func TestFitContextKeepFirstLast(t *testing.T) {
+	settings := Settings{Model: "claude-3-haiku-20240307", ContextStrategy: ContextStrategyKeepFirstLast, ContextKeepFirst: 1, ContextBudget: 2500}
+	body := claude.RequestBody{Messages: testHistory(5, 2000)}
+	body.Messages[0].Content = "setup " + body.Messages[0].Content
+
+	fitted, report, err := FitContext(body, settings)
+	if err != nil {
+		t.Fatalf("FitContext returned an error: %v", err)
+	}
+	if report.DroppedMessages != 6 || !strings.HasPrefix(fitted.Messages[0].Content, "setup") {
+		t.Errorf("Expected the first pair to be kept and the next 3 dropped, got %+v", report)
+	}
+	if report.LeftOut() != "6 earlier messages" {
+		t.Errorf("Expected the notice not to call messages after the first pair the oldest, got %q", report.LeftOut())
+	}
+	for i, m := range fitted.Messages {
+		expected := claude.MessageRoleUser
+		if i%2 == 1 {
+			expected = claude.MessageRoleAssistant
+		}
+		if m.Role != expected {
+			t.Errorf("Expected message %d to be from %s, got %s", i, expected, m.Role)
+		}
+	}
+}
+
+func TestFitContextTooLarge(t *testing.T) {
+	settings := Settings{Model: "claude-3-haiku-20240307", ContextBudget: 100}
+	body := claude.RequestBody{Messages: []claude.RequestMessages{{Role: claude.MessageRoleUser, Content: strings.Repeat("x", 1000)}}}
+
+	_, _, err := FitContext(body, settings)
+	if err == nil {
+		t.Errorf("Expected an error for a message larger than the budget")
+	}
+
+	settings.ContextStrategy = ContextStrategyNone
+	_, report, err := FitContext(body, settings)
+	if err != nil || report.DroppedMessages != 0 {
+		t.Errorf("Expected the none strategy to send the request as is, got %+v, %v", report, err)
+	}
+}
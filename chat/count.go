@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+)
+
+// TokenCount is the size of a request before it is sent.
+type TokenCount struct {
+	InputTokens int
+	Exact       bool    // counted by the API rather than estimated offline
+	Cost        float64 // of the input tokens, in US dollars
+	Priced      bool    // whether the model's price is known
+	Err         error   // why the API could not count them, when it was asked to
+}
+
+func (t TokenCount) String() string {
+	s := fmt.Sprintf("%d input tokens", t.InputTokens)
+	if !t.Exact {
+		s = "about " + s
+	}
+	if t.Priced {
+		s += fmt.Sprintf(", $%.4f", t.Cost)
+	}
+	return s
+}
+
+// ExactCountThreshold is the share of the context budget, in percent, the
+// estimated size of a request must reach before a session asks the API for
+// the exact count. Smaller requests fit either way and are only estimated.
+const ExactCountThreshold = 80
+
+// NeedsExactCount reports whether body is estimated close enough to the
+// context budget for the exact size to matter.
+func NeedsExactCount(body claude.RequestBody, settings Settings) bool {
+	return claude.EstimateRequestTokens(body) >= ContextBudget(settings)*ExactCountThreshold/100
+}
+
+// CountInputTokens counts the input tokens of body with the API when useAPI
+// is set, and estimates them offline otherwise or when counting fails.
+func CountInputTokens(ctx context.Context, client *claude.Client, body claude.RequestBody, useAPI bool) TokenCount {
+	var count TokenCount
+	if useAPI && client != nil {
+		tokens, err := client.CountTokens(ctx, body)
+		if err == nil {
+			count.InputTokens = tokens
+			count.Exact = true
+		}
+		count.Err = err
+	}
+	if !count.Exact {
+		count.InputTokens = claude.EstimateRequestTokens(body)
+	}
+	count.Cost, count.Priced = config.Cost(body.Model, int64(count.InputTokens), 0)
+	return count
+}
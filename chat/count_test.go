@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/spf13/viper"
+)
+
+func TestCountInputTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "count_tokens") && r.Header.Get("X-Api-Key") == "key" {
+			w.Write([]byte(`{"input_tokens":1000000}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	config := claude.ClientConfig{ApiKey: "key", BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()}
+	body := claude.RequestBody{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []claude.RequestMessages{{Role: claude.MessageRoleUser, Content: strings.Repeat("x", 400)}},
+	}
+
+	count := CountInputTokens(context.Background(), claude.NewClientWithConfig(config), body, true)
+	if !count.Exact || count.InputTokens != 1000000 || !count.Priced || count.Cost != 0.25 {
+		t.Errorf("Expected a million tokens counted by the API, got %+v", count)
+	}
+	if count.String() != "1000000 input tokens, $0.2500" {
+		t.Errorf("Unexpected text %q", count.String())
+	}
+
+	config.ApiKey = "wrong"
+	count = CountInputTokens(context.Background(), claude.NewClientWithConfig(config), body, true)
+	if count.Exact || count.Err == nil || count.InputTokens != claude.EstimateRequestTokens(body) {
+		t.Errorf("Expected an estimate when counting fails, got %+v", count)
+	}
+
+	count = CountInputTokens(context.Background(), nil, body, false)
+	if count.Exact || count.Err != nil || !strings.HasPrefix(count.String(), "about ") {
+		t.Errorf("Expected an offline estimate, got %+v", count)
+	}
+}
+
+func TestSessionCountsNearBudget(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(viper.Reset)
+	viper.Set(config.CountTokensKey, true)
+
+	counted := 0
+	countFails := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "count_tokens") {
+			counted++
+			if countFails {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"cannot count"}}`))
+				return
+			}
+			w.Write([]byte(`{"input_tokens":1234}`))
+			return
+		}
+		w.Write([]byte(`{"type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+	client := claude.NewClientWithConfig(claude.ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+
+	tests := []struct {
+		name       string
+		budget     string // context budget of the conversation
+		countFails bool
+		counted    int
+		out        string
+	}{
+		{"far below the budget", "100000", false, 0, "(Sending about "},
+		{"close to the budget", "300", false, 1, "(Sending 1234 input tokens"},
+		{"count fails", "300", true, 1, "Could not count the tokens with the API, estimating instead: 400 invalid_request_error: cannot count)"},
+	}
+	for _, tt := range tests {
+		convID, err := db.CreateConversation(tt.name)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		s, err := NewSession(convID, client, Overrides{
+			db.OptionModel:         "claude-3-haiku-20240307",
+			db.OptionMaxTokens:     "100",
+			db.OptionStream:        "false",
+			db.OptionContextBudget: tt.budget,
+		})
+		if err != nil {
+			t.Fatalf("NewSession returned an error: %v", err)
+		}
+		var out bytes.Buffer
+		s.SetWriter(&out)
+		s.ShowTokens(true)
+
+		counted, countFails = 0, tt.countFails
+		err = s.Send(context.Background(), strings.Repeat("word ", 200))
+		if err != nil {
+			t.Fatalf("%s: Send returned an error: %v", tt.name, err)
+		}
+		if counted != tt.counted {
+			t.Errorf("%s: expected %d count requests, got %d", tt.name, tt.counted, counted)
+		}
+		if !strings.Contains(out.String(), tt.out) {
+			t.Errorf("%s: expected the output to contain %q, got %q", tt.name, tt.out, out.String())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// RefreshModels lists the models available to the API key and replaces the
+// cached ones with them.
+func RefreshModels(ctx context.Context, client *claude.Client) ([]db.Model, error) {
+	listed, err := client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]db.Model, len(listed))
+	for i, m := range listed {
+		models[i] = db.Model{ID: m.Id, DisplayName: m.DisplayName, CreatedAt: m.CreatedAt}
+	}
+	err = db.ReplaceModels(models)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetModels()
+}
+
+// ValidateModel checks that a model is known, either to the model registry or
+// among the models the API listed when they were last refreshed.
+func ValidateModel(model string) error {
+	if _, ok := claude.LookupModel(model); ok {
+		return nil
+	}
+	listed, err := db.HasModel(model)
+	if err != nil {
+		return err
+	}
+	if !listed {
+		return fmt.Errorf("unknown model %q, see go-claude models, or run go-claude models --refresh if it is new", model)
+	}
+	return nil
+}
+
+// ValidateSettings checks settings against what their model supports. Models
+// missing from the registry are left for the API to judge.
+func ValidateSettings(settings Settings) error {
+	info, ok := claude.LookupModel(settings.Model)
+	if ok && settings.MaxTokens > info.MaxOutputTokens {
+		return fmt.Errorf("max tokens %d is more than %s can write, at most %d", settings.MaxTokens, settings.Model, info.MaxOutputTokens)
+	}
+	if settings.ThinkingBudget != 0 {
+		return validateThinking(settings, info, ok)
+	}
+	return nil
+}
+
+// validateThinking checks that extended thinking can be enabled with the
+// settings. The API refuses a budget out of range and the sampling parameters
+// it does not support while thinking.
+func validateThinking(settings Settings, info claude.ModelInfo, known bool) error {
+	switch {
+	case settings.ThinkingBudget < claude.MinThinkingBudget:
+		return fmt.Errorf("thinking budget %d is less than the minimum of %d tokens", settings.ThinkingBudget, claude.MinThinkingBudget)
+	case settings.ThinkingBudget >= settings.MaxTokens:
+		return fmt.Errorf("thinking budget %d must be less than max tokens %d", settings.ThinkingBudget, settings.MaxTokens)
+	case known && !info.Thinking:
+		return fmt.Errorf("%s does not support extended thinking, set the thinking budget to 0", settings.Model)
+	case settings.Temperature != nil && *settings.Temperature != 1:
+		return errors.New("temperature cannot be changed with extended thinking")
+	case settings.TopK != nil:
+		return errors.New("top-k cannot be set with extended thinking")
+	case settings.TopP != nil && *settings.TopP < 0.95:
+		return errors.New("top-p must be at least 0.95 with extended thinking")
+	}
+	return nil
+}
+
+// checkAttachments refuses attachments the model cannot read.
+func checkAttachments(model string, attachments []db.Attachment) error {
+	info, ok := claude.LookupModel(model)
+	if !ok || info.Vision {
+		return nil
+	}
+	for _, a := range attachments {
+		if a.Kind == db.AttachmentKindImage {
+			return fmt.Errorf("%s does not accept images, attach %s to a conversation using a model with vision", model, a.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+func TestValidateModels(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if err := ValidateModel("claude-3-opus-20240229"); err != nil {
+		t.Errorf("Expected a model of the registry to be valid, got %v", err)
+	}
+	if err := ValidateOption(db.OptionModel, "claude-4-future"); err == nil {
+		t.Errorf("Expected an unknown model to be refused")
+	}
+	err = db.ReplaceModels([]db.Model{{ID: "claude-4-future"}})
+	if err != nil {
+		t.Fatalf("ReplaceModels returned an error: %v", err)
+	}
+	if err := ValidateOption(db.OptionModel, "claude-4-future"); err != nil {
+		t.Errorf("Expected a model listed by the API to be valid, got %v", err)
+	}
+
+	convID, err := db.CreateConversation("Models")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	err = ConfigureConversation(convID, map[string]string{db.OptionModel: "claude-3-5-sonnet-20241022", db.OptionMaxTokens: "8192"})
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error: %v", err)
+	}
+	err = ConfigureConversation(convID, map[string]string{db.OptionModel: "claude-3-haiku-20240307"})
+	if err == nil {
+		t.Errorf("Expected a model that writes at most 4096 tokens to be refused with max tokens 8192")
+	}
+	err = ConfigureConversation(convID, map[string]string{db.OptionModel: "claude-3-haiku-20240307", db.OptionMaxTokens: ""})
+	if err != nil {
+		t.Errorf("Expected the model to be accepted once max tokens is unset, got %v", err)
+	}
+
+	attachments := []db.Attachment{{Path: "./screen.png", Kind: db.AttachmentKindImage}}
+	if err := checkAttachments("claude-3-5-haiku-20241022", attachments); err == nil {
+		t.Errorf("Expected an image to be refused by a model without vision")
+	}
+	if err := checkAttachments("claude-3-5-sonnet-20241022", attachments); err != nil {
+		t.Errorf("Expected an image to be accepted by a model with vision, got %v", err)
+	}
+}
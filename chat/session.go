@@ -0,0 +1,405 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Settings holds the request parameters used for every turn of a session.
+type Settings struct {
+	Model       string
+	MaxTokens   int
+	Stream      bool
+	System      string
+	Temperature *float64 // nil leaves the API default
+	TopP        *float64 // nil leaves the API default
+	TopK        *float64 // nil leaves the API default
+
+	ThinkingBudget int // tokens Claude may think for before replying, 0 for no extended thinking
+
+	ContextStrategy  string // see ContextStrategies
+	ContextKeepFirst int    // pairs kept by ContextStrategyKeepFirstLast
+	ContextBudget    int    // most input tokens per request, 0 for the model's context window
+
+	CompactThreshold  int // percent of the context budget the history may fill before it is summarized, 0 never
+	CompactKeepRecent int // latest pairs left out of summaries
+}
+
+// Reply is a reply received in a session, with its thinking blocks encoded
+// for storage, see EncodeThinking.
+type Reply struct {
+	Text     string
+	Thinking string
+	Usage    db.Usage
+}
+
+// Session keeps a conversation open across several turns. Every exchange is
+// sent with the conversation's full history and persisted once the reply has
+// been received.
+type Session struct {
+	ConversationID int64
+	Settings       Settings
+
+	overrides Overrides // settings given for this session only, e.g. flags
+
+	client  *claude.Client
+	in      *bufio.Reader
+	out     io.Writer
+	pending []string // files attached to the next message
+
+	lastUsage  db.Usage     // the last reply's
+	totalUsage claude.Usage // every request of the session's
+	totalCost  float64      // in US dollars, of the requests with a known price
+
+	showTokens bool // print the input tokens of every request before sending it
+}
+
+// NewSession opens a session on a conversation, resolving its settings from
+// overrides, the conversation's options and the global configuration.
+func NewSession(conversationID int64, client *claude.Client, overrides Overrides) (*Session, error) {
+	if overrides == nil {
+		overrides = Overrides{}
+	}
+	s := &Session{
+		ConversationID: conversationID,
+		overrides:      overrides,
+		client:         client,
+		in:             bufio.NewReader(os.Stdin),
+		out:            os.Stdout,
+	}
+	return s, s.ReloadSettings()
+}
+
+func (s *Session) SetReader(reader io.Reader) {
+	s.in = bufio.NewReader(reader)
+}
+
+func (s *Session) SetWriter(writer io.Writer) {
+	s.out = writer
+}
+
+// ReloadSettings resolves the settings again, picking up options changed
+// since the session started. Settings the model does not support are only
+// warned about, leaving the API to refuse them.
+func (s *Session) ReloadSettings() error {
+	settings, err := ResolveSettings(s.ConversationID, s.overrides)
+	if err != nil {
+		return err
+	}
+	err = ValidateSettings(settings)
+	if err != nil {
+		fmt.Fprintf(s.out, "Warning: %v\n", err)
+	}
+	s.Settings = settings
+	return nil
+}
+
+// AttachToNextMessage queues files to be sent with the next message.
+func (s *Session) AttachToNextMessage(paths []string) {
+	s.pending = append(s.pending, paths...)
+}
+
+// Run reads messages until the user types /exit or closes the input (Ctrl-D).
+// Input starting with a slash runs the matching registered Command. Errors
+// from a single turn are printed and the session carries on.
+func (s *Session) Run(ctx context.Context) error {
+	fmt.Fprintln(s.out, "Type /help to list the commands, /exit or Ctrl-D to leave the conversation.")
+	for {
+		fmt.Fprint(s.out, "\nUser: ")
+		line, err := s.in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		input := strings.TrimSpace(line)
+		if errors.Is(err, io.EOF) && input == "" {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+
+		if input == "" {
+			continue
+		}
+
+		var turnErr error
+		if IsCommand(input) {
+			turnErr = s.RunCommand(ctx, input)
+		} else {
+			turnErr = s.Send(ctx, input)
+		}
+		if errors.Is(turnErr, ErrExitSession) {
+			return nil
+		}
+		if turnErr != nil {
+			fmt.Fprintf(s.out, "\nError: %v\n", turnErr)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// Send sends a single user message, with any queued or @path referenced files,
+// prints the reply and stores both messages in the conversation.
+func (s *Session) Send(ctx context.Context, message string) error {
+	messageRequest, attachments, err := s.userMessage(message)
+	if err != nil {
+		return err
+	}
+
+	summary, history := GetCompactedHistory(s.ConversationID)
+	messages := AppendHistoryToMessageRequest(messageRequest, history)
+	if ShouldCompact(s.requestBody(summary, messages), s.Settings) {
+		summary, messages = s.autoCompact(ctx, summary, messages, messageRequest)
+	}
+
+	reply, err := s.complete(ctx, summary, messages)
+	if err != nil {
+		return err
+	}
+
+	AddMessageWithAttachmentsToConversationTable(s.ConversationID, messageRequest, attachments)
+	AddReplyToConversationTable(s.ConversationID, reply)
+	s.pending = nil
+	return nil
+}
+
+// NextRequest returns the request message would be sent with, the history
+// fitted to the context budget as Send does but without summarizing it. An
+// empty message returns the request of the history alone.
+func (s *Session) NextRequest(message string) (claude.RequestBody, ContextReport, error) {
+	summary, messages := GetCompactedHistory(s.ConversationID)
+	if message != "" || len(s.pending) > 0 {
+		messageRequest, _, err := s.userMessage(message)
+		if err != nil {
+			return claude.RequestBody{}, ContextReport{}, err
+		}
+		messages = AppendHistoryToMessageRequest(messageRequest, messages)
+	}
+	return FitContext(s.requestBody(summary, messages), s.Settings)
+}
+
+// ShowTokens makes the session print the input tokens of every request
+// before sending it.
+func (s *Session) ShowTokens(show bool) {
+	s.showTokens = show
+}
+
+// userMessage builds the request message for a user message with its
+// attachments.
+func (s *Session) userMessage(message string) (claude.RequestMessages, []db.Attachment, error) {
+	text, attachments, err := ExpandMessage(message, s.pending)
+	if err != nil {
+		return claude.RequestMessages{}, nil, err
+	}
+	err = checkAttachments(s.Settings.Model, attachments)
+	if err != nil {
+		return claude.RequestMessages{}, nil, err
+	}
+	messageRequest, err := MessageWithAttachmentsToRequest(claude.MessageRoleUser, text, attachments)
+	if err != nil {
+		return claude.RequestMessages{}, nil, err
+	}
+	return messageRequest, attachments, nil
+}
+
+// RequestBody builds the request for messages from the session settings.
+// Stored thinking blocks are only sent back when extended thinking is on.
+func (s *Session) RequestBody(messages []claude.RequestMessages) claude.RequestBody {
+	body := claude.RequestBody{
+		Model:       s.Settings.Model,
+		MaxTokens:   s.Settings.MaxTokens,
+		Messages:    messages,
+		System:      s.Settings.System,
+		Stream:      s.Settings.Stream,
+		Temparature: s.Settings.Temperature,
+		TopP:        s.Settings.TopP,
+		TopK:        s.Settings.TopK,
+	}
+	if s.Settings.ThinkingBudget > 0 {
+		body.Thinking = claude.NewThinkingConfig(s.Settings.ThinkingBudget)
+	} else {
+		body.Messages = withoutThinking(messages)
+	}
+	return body
+}
+
+// requestBody builds the request for messages following a summary of the
+// history before them.
+func (s *Session) requestBody(summary string, messages []claude.RequestMessages) claude.RequestBody {
+	body := s.RequestBody(messages)
+	body.System = SystemWithSummary(body.System, summary)
+	return body
+}
+
+// autoCompact summarizes the older history once it fills more of the context
+// budget than the compact threshold allows, and returns the summary and
+// messages to send instead. When summarizing fails the history is sent as it
+// is and FitContext drops what does not fit.
+func (s *Session) autoCompact(ctx context.Context, summary string, messages []claude.RequestMessages, message claude.RequestMessages) (string, []claude.RequestMessages) {
+	report, err := Compact(ctx, s.client, s.ConversationID, s.Settings, s.Settings.CompactKeepRecent)
+	if errors.Is(err, ErrNothingToCompact) {
+		return summary, messages
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "\n(Could not summarize the older messages: %v)\n", err)
+		return summary, messages
+	}
+	s.recordUsage(report.Usage)
+	fmt.Fprintf(s.out, "\n(Summarized %d older messages, about %d tokens, in about %d tokens to stay within the context budget.)\n",
+		report.SummarizedMessages, report.TokensBefore, report.TokensAfter)
+
+	summary, history := GetCompactedHistory(s.ConversationID)
+	return summary, AppendHistoryToMessageRequest(message, history)
+}
+
+// complete sends messages, following a summary of the history before them, to
+// Claude, prints the reply as it arrives, its thinking collapsed to a line, and
+// returns it.
+func (s *Session) complete(ctx context.Context, summary string, messages []claude.RequestMessages) (Reply, error) {
+	body, report, err := FitContext(s.requestBody(summary, messages), s.Settings)
+	if err != nil {
+		return Reply{}, err
+	}
+	if report.DroppedMessages > 0 {
+		fmt.Fprintf(s.out, "\n(Left out %s, about %d tokens, to fit the %d token context budget.)\n",
+			report.LeftOut(), report.DroppedTokens, report.Budget)
+	}
+	if config.GetBool(config.PromptCachingKey) {
+		body = AddCacheBreakpoints(body)
+	}
+	if s.showTokens {
+		useAPI := config.GetBool(config.CountTokensKey) && NeedsExactCount(body, s.Settings)
+		count := CountInputTokens(ctx, s.client, body, useAPI)
+		if count.Err != nil {
+			fmt.Fprintf(s.out, "\n(Could not count the tokens with the API, estimating instead: %v)", count.Err)
+		}
+		fmt.Fprintf(s.out, "\n(Sending %s.)\n", count)
+	}
+
+	start := time.Now()
+	if !s.Settings.Stream {
+		response, err := s.client.CreateMessages(ctx, body)
+		if err != nil {
+			return Reply{}, err
+		}
+		reply, err := s.reply(response, body.Model, time.Since(start))
+		if err != nil {
+			return Reply{}, err
+		}
+		fmt.Fprint(s.out, "\nClaude: ")
+		if collapsed := CollapsedThinking(response.ThinkingBlocks()); collapsed != "" {
+			fmt.Fprintln(s.out, collapsed)
+		}
+		fmt.Fprintln(s.out, reply.Text)
+		return reply, nil
+	}
+
+	stream, err := s.client.CreateMessagesStream(ctx, body)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer stream.Close()
+
+	fmt.Fprint(s.out, "\nClaude: ")
+	thinking := false // the thinking line is open
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(s.out)
+			return Reply{}, err
+		}
+		if len(res.Content) == 0 {
+			continue
+		}
+		switch res.Content[0].Type {
+		case claude.ResponseContentTypeThinking, claude.ResponseContentTypeRedactedThinking:
+			if !thinking && res.Event == claude.MessagesStreamResponseTypeContentBlockStart {
+				fmt.Fprint(s.out, "[thinking...")
+				thinking = true
+			}
+		case claude.ResponseContentTypeText:
+			if res.Event != claude.MessagesStreamResponseTypeContentBlockDelta {
+				continue
+			}
+			if thinking {
+				s.closeThinking(stream.Message())
+				thinking = false
+			}
+			fmt.Fprint(s.out, res.Content[0].Text)
+		}
+	}
+	if thinking {
+		s.closeThinking(stream.Message())
+	}
+	fmt.Fprintln(s.out)
+	return s.reply(stream.Message(), body.Model, time.Since(start))
+}
+
+// closeThinking ends the line printed while Claude thinks with the size of
+// the thinking so far.
+func (s *Session) closeThinking(response *claude.ResponseBody) {
+	words, redacted := thinkingSize(response.ThinkingBlocks())
+	if redacted > 0 {
+		fmt.Fprintf(s.out, " %d words, %d redacted, /thinking to show]\n", words, redacted)
+		return
+	}
+	fmt.Fprintf(s.out, " %d words, /thinking to show]\n", words)
+}
+
+// reply records the usage of a response and returns it as a Reply.
+func (s *Session) reply(response *claude.ResponseBody, model string, latency time.Duration) (Reply, error) {
+	usage := responseUsage(response, model, latency)
+	s.recordUsage(usage)
+	thinking, err := EncodeThinking(response)
+	if err != nil {
+		return Reply{}, err
+	}
+	return Reply{Text: response.Text(), Thinking: thinking, Usage: usage}, nil
+}
+
+// responseUsage is the usage to store for a response. model is the one
+// requested, in case the response leaves it out.
+func responseUsage(response *claude.ResponseBody, model string, latency time.Duration) db.Usage {
+	if response.Model != "" {
+		model = response.Model
+	}
+	return db.Usage{
+		Model:               model,
+		InputTokens:         response.Usage.InputTokens,
+		OutputTokens:        response.Usage.OutputTokens,
+		CacheCreationTokens: response.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     response.Usage.CacheReadInputTokens,
+		StopReason:          response.StopReason,
+		Latency:             latency,
+	}
+}
+
+func (s *Session) recordUsage(usage db.Usage) {
+	s.lastUsage = usage
+	s.totalUsage.InputTokens += usage.InputTokens
+	s.totalUsage.OutputTokens += usage.OutputTokens
+	s.totalUsage.CacheCreationInputTokens += usage.CacheCreationTokens
+	s.totalUsage.CacheReadInputTokens += usage.CacheReadTokens
+	if cost, ok := usageCost(usage); ok {
+		s.totalCost += cost
+	}
+}
+
+// usageCost returns what the request of usage cost, and false when its model
+// has no price.
+func usageCost(u db.Usage) (float64, bool) {
+	return config.UsageCost(u.Model, u.InputTokens, u.OutputTokens, u.CacheCreationTokens, u.CacheReadTokens, u.Batch)
+}
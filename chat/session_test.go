@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// chatServer answers the nth request with "Reply n", except those listed in
+// fail, which get an invalid_request_error. It records how many messages each
+// request sent.
+func chatServer(t *testing.T, fail map[int]bool, sent *[]int) *claude.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		*sent = append(*sent, len(body.Messages))
+		n := len(*sent)
+
+		w.Header().Set("Content-Type", "application/json")
+		if fail[n] {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad request"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"type":        "message",
+			"role":        "assistant",
+			"model":       "claude-3-haiku-20240307",
+			"content":     []map[string]string{{"type": "text", "text": fmt.Sprintf("Reply %d", n)}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 2},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return claude.NewClientWithConfig(claude.ClientConfig{
+		BaseURL:    server.URL + "/",
+		Endpoint:   "v1/messages",
+		HTTPCLient: server.Client(),
+	})
+}
+
+// runSession runs a session on a new conversation reading input, and returns
+// the conversation with what the session printed.
+func runSession(t *testing.T, client *claude.Client, input string) (int64, string) {
+	t.Helper()
+	convID, err := db.CreateConversation("Session")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	s, err := NewSession(convID, client, Overrides{
+		db.OptionModel:     "claude-3-haiku-20240307",
+		db.OptionMaxTokens: "100",
+		db.OptionStream:    "false",
+	})
+	if err != nil {
+		t.Fatalf("NewSession returned an error: %v", err)
+	}
+	var out bytes.Buffer
+	s.SetReader(strings.NewReader(input))
+	s.SetWriter(&out)
+	err = s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	return convID, out.String()
+}
+
+// branchContents returns the contents of the conversation's active branch.
+func branchContents(t *testing.T, convID int64) []string {
+	t.Helper()
+	branch, err := db.GetActiveBranch(convID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	var contents []string
+	for _, m := range branch {
+		contents = append(contents, m.Role+": "+m.Content)
+	}
+	return contents
+}
+
+func TestSessionRun(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name  string
+		input string
+		fail  map[int]bool
+		want  []string // the branch after the session
+		sent  []int    // messages sent with each request
+		out   []string // printed
+	}{
+		{
+			name:  "multi-turn until exit",
+			input: "Hello\n\nHow are you?\n/exit\nNot sent\n",
+			want:  []string{"user: Hello", "assistant: Reply 1", "user: How are you?", "assistant: Reply 2"},
+			sent:  []int{1, 3},
+			out:   []string{"Claude: Reply 1", "Claude: Reply 2"},
+		},
+		{
+			name:  "end of input without a newline",
+			input: "Hello\nLast words",
+			want:  []string{"user: Hello", "assistant: Reply 1", "user: Last words", "assistant: Reply 2"},
+			sent:  []int{1, 3},
+		},
+		{
+			name:  "empty input",
+			input: "",
+		},
+		{
+			name:  "api error",
+			input: "Hello\nAgain\n",
+			fail:  map[int]bool{1: true},
+			want:  []string{"user: Again", "assistant: Reply 2"},
+			sent:  []int{1, 1},
+			out:   []string{"Error: ", "bad request", "Claude: Reply 2"},
+		},
+		{
+			name:  "unknown command",
+			input: "/nope\nHello\n",
+			want:  []string{"user: Hello", "assistant: Reply 1"},
+			sent:  []int{1},
+			out:   []string{"Error: unknown command /nope"},
+		},
+	}
+	for _, tt := range tests {
+		var sent []int
+		convID, out := runSession(t, chatServer(t, tt.fail, &sent), tt.input)
+
+		got := branchContents(t, convID)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: expected the branch %q, got %q", tt.name, tt.want, got)
+		}
+		if fmt.Sprint(sent) != fmt.Sprint(tt.sent) {
+			t.Errorf("%s: expected requests of %v messages, got %v", tt.name, tt.sent, sent)
+		}
+		for _, o := range tt.out {
+			if !strings.Contains(out, o) {
+				t.Errorf("%s: expected the output to contain %q, got:\n%s", tt.name, o, out)
+			}
+		}
+	}
+}
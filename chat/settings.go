@@ -0,0 +1,288 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Overrides are settings given for a single invocation, e.g. through command
+// line flags, keyed by conversation option name. They win over everything
+// stored for the conversation and are not persisted.
+type Overrides map[string]string
+
+// SettingOptions lists the conversation options that map onto Settings.
+var SettingOptions = []string{
+	db.OptionModel,
+	db.OptionMaxTokens,
+	db.OptionTemperature,
+	db.OptionTopP,
+	db.OptionTopK,
+	db.OptionThinkingBudget,
+	db.OptionStream,
+	db.OptionSystem,
+	db.OptionPersona,
+	db.OptionContextStrategy,
+	db.OptionContextKeepFirst,
+	db.OptionContextBudget,
+	db.OptionCompactThreshold,
+	db.OptionCompactKeepRecent,
+}
+
+// GlobalSettings returns the settings from the global configuration, which
+// already falls back to the built in defaults for anything left unset.
+func GlobalSettings() Settings {
+	return Settings{
+		Model:       config.GetString(config.ModelKey),
+		MaxTokens:   config.GetInt(config.MaxTokensKey),
+		Stream:      config.GetBool(config.StreamKey),
+		Temperature: optionalFloat(config.GetFloat64(config.TemperatureKey)),
+		TopP:        optionalFloat(config.GetFloat64(config.TopPKey)),
+		TopK:        optionalFloat(config.GetFloat64(config.TopKKey)),
+
+		ThinkingBudget: config.GetInt(config.ThinkingBudgetKey),
+
+		ContextStrategy:  config.GetString(config.ContextStrategyKey),
+		ContextKeepFirst: config.GetInt(config.ContextKeepFirstKey),
+		ContextBudget:    config.GetInt(config.ContextBudgetKey),
+
+		CompactThreshold:  config.GetInt(config.CompactThresholdKey),
+		CompactKeepRecent: config.GetInt(config.CompactKeepRecentKey),
+	}
+}
+
+// ResolveSettings builds the settings for a conversation's next request. Each
+// layer replaces the values it sets in the one before it:
+//
+//	default < global config < persona < conversation option < override
+//
+// The persona is itself an option, so an override can swap it for a single
+// invocation.
+func ResolveSettings(conversationID int64, overrides Overrides) (Settings, error) {
+	options, err := ConversationOptions(conversationID)
+	if err != nil {
+		return GlobalSettings(), err
+	}
+	return resolveSettings(conversationID, options, overrides)
+}
+
+// resolveSettings layers a conversation's options and the overrides over the
+// global configuration. The result is not checked against what the model
+// supports: that is done by ValidateSettings when options are written, so
+// values stored before a model's limits were known still resolve.
+func resolveSettings(conversationID int64, options map[string]string, overrides Overrides) (Settings, error) {
+	settings := GlobalSettings()
+
+	personaName := options[db.OptionPersona]
+	if name, ok := overrides[db.OptionPersona]; ok {
+		personaName = name
+	}
+	if personaName != "" {
+		persona, err := db.GetPersona(personaName)
+		if err != nil {
+			return settings, err
+		}
+		if persona == nil {
+			return settings, fmt.Errorf("persona %q used by conversation %d does not exist", personaName, conversationID)
+		}
+		settings = ApplyPersona(settings, *persona)
+	}
+
+	for _, layer := range []map[string]string{options, overrides} {
+		for name, value := range layer {
+			// Options that are not settings, e.g. where an imported
+			// conversation came from, are left alone.
+			if name == db.OptionPersona || value == "" || !slices.Contains(SettingOptions, name) {
+				continue
+			}
+			err := applyOption(&settings, name, value)
+			if err != nil {
+				return settings, fmt.Errorf("conversation %d option %s: %w", conversationID, name, err)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// ValidateOption checks that value can be stored for the option name.
+func ValidateOption(name, value string) error {
+	var settings Settings
+	err := applyOption(&settings, name, value)
+	if err == nil && name == db.OptionModel {
+		err = ValidateModel(value)
+	}
+	return err
+}
+
+func applyOption(settings *Settings, name, value string) error {
+	var err error
+	switch name {
+	case db.OptionModel:
+		settings.Model = value
+	case db.OptionSystem:
+		settings.System = value
+	case db.OptionMaxTokens:
+		settings.MaxTokens, err = strconv.Atoi(value)
+	case db.OptionTemperature:
+		settings.Temperature, err = parseFloatOption(value)
+	case db.OptionTopP:
+		settings.TopP, err = parseFloatOption(value)
+	case db.OptionTopK:
+		settings.TopK, err = parseFloatOption(value)
+	case db.OptionThinkingBudget:
+		settings.ThinkingBudget, err = strconv.Atoi(value)
+	case db.OptionStream:
+		settings.Stream, err = strconv.ParseBool(value)
+	case db.OptionContextStrategy:
+		if !slices.Contains(ContextStrategies, value) {
+			return fmt.Errorf("unknown context strategy %q, expected one of %v", value, ContextStrategies)
+		}
+		settings.ContextStrategy = value
+	case db.OptionContextKeepFirst:
+		settings.ContextKeepFirst, err = strconv.Atoi(value)
+	case db.OptionContextBudget:
+		settings.ContextBudget, err = strconv.Atoi(value)
+	case db.OptionCompactThreshold:
+		settings.CompactThreshold, err = strconv.Atoi(value)
+		if err == nil && (settings.CompactThreshold < 0 || settings.CompactThreshold > 100) {
+			return fmt.Errorf("compact threshold %d is not a percentage between 0 and 100", settings.CompactThreshold)
+		}
+	case db.OptionCompactKeepRecent:
+		settings.CompactKeepRecent, err = strconv.Atoi(value)
+	case db.OptionPersona:
+	default:
+		return fmt.Errorf("unknown option %s", name)
+	}
+	return err
+}
+
+// ApplyPersona returns settings with every value the persona sets replacing
+// the one in settings.
+func ApplyPersona(settings Settings, persona db.Persona) Settings {
+	if persona.SystemPrompt != "" {
+		settings.System = persona.SystemPrompt
+	}
+	if persona.Model != "" {
+		settings.Model = persona.Model
+	}
+	if persona.MaxTokens != 0 {
+		settings.MaxTokens = persona.MaxTokens
+	}
+	if persona.Temperature != 0 {
+		settings.Temperature = optionalFloat(persona.Temperature)
+	}
+	if persona.TopP != 0 {
+		settings.TopP = optionalFloat(persona.TopP)
+	}
+	if persona.TopK != 0 {
+		settings.TopK = optionalFloat(persona.TopK)
+	}
+	return settings
+}
+
+// optionalFloat returns a sampling parameter of the global configuration or
+// a persona, where 0 means it is not set, as a setting.
+func optionalFloat(value float64) *float64 {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// parseFloatOption parses a sampling parameter stored as a conversation
+// option or given as an override. Unlike in the global configuration, 0 is a
+// value of its own there, e.g. a temperature of 0 for deterministic replies.
+func parseFloatOption(value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// ConfigureConversation validates and stores options for a conversation. An
+// empty value removes the option so the conversation falls back to the
+// persona and global configuration again.
+func ConfigureConversation(conversationID int64, options map[string]string) error {
+	for name, value := range options {
+		if value == "" || name == db.OptionPersona {
+			continue
+		}
+		err := ValidateOption(name, value)
+		if err != nil {
+			return fmt.Errorf("option %s: %w", name, err)
+		}
+	}
+	// The options must also make sense together with those already stored.
+	combined, err := ConversationOptions(conversationID)
+	if err != nil {
+		return err
+	}
+	for name, value := range options {
+		combined[name] = value
+	}
+	settings, err := resolveSettings(conversationID, combined, nil)
+	if err != nil {
+		return err
+	}
+	err = ValidateSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range options {
+		if value == "" {
+			err := db.DeleteConversationOption(conversationID, name)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if name == db.OptionPersona {
+			err := SetConversationPersona(conversationID, value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := db.ConfigureConversation(conversationID, name, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetConversationPersona stores the persona a conversation uses on every turn.
+func SetConversationPersona(conversationID int64, name string) error {
+	persona, err := db.GetPersona(name)
+	if err != nil {
+		return err
+	}
+	if persona == nil {
+		return fmt.Errorf("persona %q does not exist", name)
+	}
+	return db.ConfigureConversation(conversationID, db.OptionPersona, name)
+}
+
+// SetConversationSystem stores the system prompt a conversation uses on every
+// turn. An empty prompt falls back to the persona's, if any.
+func SetConversationSystem(conversationID int64, system string) error {
+	return ConfigureConversation(conversationID, map[string]string{db.OptionSystem: system})
+}
+
+// ConversationOptions returns the options stored for a conversation by name.
+func ConversationOptions(conversationID int64) (map[string]string, error) {
+	options, err := db.GetConversationOptions(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(options))
+	for _, o := range options {
+		values[o.OptionName] = o.OptionValue
+	}
+	return values, nil
+}
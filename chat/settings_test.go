@@ -0,0 +1,333 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/spf13/viper"
+)
+
+func TestResolveSettingsPrecedence(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(viper.Reset)
+
+	err = db.SavePersona(db.Persona{Name: "reviewer", SystemPrompt: "You review code.", Model: "claude-3-opus-20240229", Temperature: 0.3})
+	if err != nil {
+		t.Fatalf("SavePersona returned an error: %v", err)
+	}
+	err = db.SavePersona(db.Persona{Name: "translator", SystemPrompt: "You translate.", MaxTokens: 1500})
+	if err != nil {
+		t.Fatalf("SavePersona returned an error: %v", err)
+	}
+
+	type want struct {
+		model       string
+		maxTokens   int
+		temperature float64
+		system      string
+	}
+	tests := []struct {
+		name      string
+		defaults  map[string]any // built in defaults
+		global    map[string]any // the config file
+		options   map[string]string
+		overrides Overrides
+		want      want
+	}{
+		{
+			name:     "default",
+			defaults: map[string]any{config.ModelKey: "claude-3-haiku-20240307", config.MaxTokensKey: 2000},
+			want:     want{"claude-3-haiku-20240307", 2000, 0, ""},
+		},
+		{
+			name:     "global over default",
+			defaults: map[string]any{config.ModelKey: "claude-3-haiku-20240307", config.MaxTokensKey: 2000},
+			global:   map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.TemperatureKey: 0.7},
+			want:     want{"claude-3-5-sonnet-20240620", 2000, 0.7, ""},
+		},
+		{
+			name:    "persona over global",
+			global:  map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.MaxTokensKey: 2000, config.TemperatureKey: 0.7},
+			options: map[string]string{db.OptionPersona: "reviewer"},
+			want:    want{"claude-3-opus-20240229", 2000, 0.3, "You review code."},
+		},
+		{
+			name:    "option over persona",
+			global:  map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.MaxTokensKey: 2000},
+			options: map[string]string{db.OptionPersona: "reviewer", db.OptionModel: "claude-3-haiku-20240307", db.OptionSystem: "Be brief."},
+			want:    want{"claude-3-haiku-20240307", 2000, 0.3, "Be brief."},
+		},
+		{
+			name:      "override over option",
+			global:    map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.MaxTokensKey: 2000},
+			options:   map[string]string{db.OptionModel: "claude-3-haiku-20240307", db.OptionMaxTokens: "1000"},
+			overrides: Overrides{db.OptionMaxTokens: "500", db.OptionTemperature: "1"},
+			want:      want{"claude-3-haiku-20240307", 500, 1, ""},
+		},
+		{
+			name:      "override swaps the persona",
+			global:    map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.MaxTokensKey: 2000},
+			options:   map[string]string{db.OptionPersona: "reviewer"},
+			overrides: Overrides{db.OptionPersona: "translator"},
+			want:      want{"claude-3-5-sonnet-20240620", 1500, 0, "You translate."},
+		},
+		{
+			name:    "options that are not settings",
+			global:  map[string]any{config.ModelKey: "claude-3-5-sonnet-20240620", config.MaxTokensKey: 2000},
+			options: map[string]string{"imported_from": "chatgpt"},
+			want:    want{"claude-3-5-sonnet-20240620", 2000, 0, ""},
+		},
+	}
+	for _, tt := range tests {
+		viper.Reset()
+		for key, value := range tt.defaults {
+			viper.SetDefault(key, value)
+		}
+		for key, value := range tt.global {
+			viper.Set(key, value)
+		}
+		convID, err := db.CreateConversation(tt.name)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		for name, value := range tt.options {
+			err := db.ConfigureConversation(convID, name, value)
+			if err != nil {
+				t.Fatalf("ConfigureConversation returned an error: %v", err)
+			}
+		}
+
+		settings, err := ResolveSettings(convID, tt.overrides)
+		if err != nil {
+			t.Errorf("%s: ResolveSettings returned an error: %v", tt.name, err)
+			continue
+		}
+		got := want{settings.Model, settings.MaxTokens, valueOf(settings.Temperature), settings.System}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// valueOf returns the value of an optional setting, 0 when it is unset.
+func valueOf(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestResolveSettingsExplicitZero(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+	viper.Set(config.ModelKey, "claude-3-5-sonnet-20240620")
+	viper.Set(config.MaxTokensKey, 2000)
+	viper.Set(config.TemperatureKey, 0.7)
+
+	tests := []struct {
+		name      string
+		options   map[string]string
+		overrides Overrides
+		want      string // the sampling parameters of the request JSON
+	}{
+		{"global", nil, nil, `"temperature":0.7`},
+		{"option", map[string]string{db.OptionTemperature: "0", db.OptionTopK: "0"}, nil, `"temperature":0,"top_k":0`},
+		{"override", nil, Overrides{db.OptionTemperature: "0", db.OptionTopP: "0"}, `"temperature":0,"top_p":0`},
+	}
+	for _, tt := range tests {
+		convID, err := db.CreateConversation(tt.name)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		err = ConfigureConversation(convID, tt.options)
+		if err != nil {
+			t.Fatalf("%s: ConfigureConversation returned an error: %v", tt.name, err)
+		}
+		s, err := NewSession(convID, nil, tt.overrides)
+		if err != nil {
+			t.Fatalf("%s: NewSession returned an error: %v", tt.name, err)
+		}
+		data, err := json.Marshal(s.RequestBody(nil))
+		if err != nil {
+			t.Fatalf("%s: Marshal returned an error: %v", tt.name, err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s: expected the request to contain %s, got %s", tt.name, tt.want, data)
+		}
+	}
+
+	// Left unset, the API default applies.
+	viper.Set(config.TemperatureKey, 0)
+	s, err := NewSession(0, nil, nil)
+	if err != nil {
+		t.Fatalf("NewSession returned an error: %v", err)
+	}
+	data, _ := json.Marshal(s.RequestBody(nil))
+	if strings.Contains(string(data), "temperature") {
+		t.Errorf("Expected no temperature in the request, got %s", data)
+	}
+}
+
+func TestResolveSettingsInvalidOptions(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+	viper.Set(config.ModelKey, "claude-3-5-sonnet-20240620")
+
+	tests := []struct {
+		name      string
+		options   map[string]string
+		overrides Overrides
+	}{
+		{"max tokens", map[string]string{db.OptionMaxTokens: "many"}, nil},
+		{"temperature", map[string]string{db.OptionTemperature: "warm"}, nil},
+		{"stream", map[string]string{db.OptionStream: "sometimes"}, nil},
+		{"context strategy", map[string]string{db.OptionContextStrategy: "drop-newest"}, nil},
+		{"compact threshold", map[string]string{db.OptionCompactThreshold: "150"}, nil},
+		{"override", nil, Overrides{db.OptionTopK: "high"}},
+	}
+	for _, tt := range tests {
+		convID, err := db.CreateConversation(tt.name)
+		if err != nil {
+			t.Fatalf("Failed to create conversation: %v", err)
+		}
+		// Stored directly, as older versions did without validating.
+		for name, value := range tt.options {
+			err := db.ConfigureConversation(convID, name, value)
+			if err != nil {
+				t.Fatalf("ConfigureConversation returned an error: %v", err)
+			}
+		}
+		_, err = ResolveSettings(convID, tt.overrides)
+		if err == nil {
+			t.Errorf("%s: expected an invalid value to be refused", tt.name)
+			continue
+		}
+		for name := range tt.options {
+			if !strings.Contains(err.Error(), name) {
+				t.Errorf("%s: expected the error to name option %s, got %v", tt.name, name, err)
+			}
+		}
+	}
+
+	if err := ValidateOption(db.OptionMaxTokens, "many"); err == nil {
+		t.Errorf("Expected ValidateOption to refuse the value before it is stored")
+	}
+}
+
+func TestResolveSettingsAfterPersonaDeleted(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	err = db.SavePersona(db.Persona{Name: "reviewer", SystemPrompt: "You review code.", Model: "claude-3-opus-20240229"})
+	if err != nil {
+		t.Fatalf("SavePersona returned an error: %v", err)
+	}
+	convID, err := db.CreateConversation("Persona")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	err = ConfigureConversation(convID, map[string]string{db.OptionPersona: "reviewer", db.OptionMaxTokens: "1000"})
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error: %v", err)
+	}
+	settings, err := ResolveSettings(convID, nil)
+	if err != nil || settings.Model != "claude-3-opus-20240229" {
+		t.Fatalf("Expected the persona's model, got %q, %v", settings.Model, err)
+	}
+
+	err = db.DeletePersona("reviewer")
+	if err != nil {
+		t.Fatalf("DeletePersona returned an error: %v", err)
+	}
+	settings, err = ResolveSettings(convID, nil)
+	if err != nil {
+		t.Fatalf("Expected the conversation to resolve without its deleted persona, got %v", err)
+	}
+	if settings.Model == "claude-3-opus-20240229" || settings.System != "" {
+		t.Errorf("Expected the deleted persona's settings to be gone, got %+v", settings)
+	}
+	if settings.MaxTokens != 1000 {
+		t.Errorf("Expected the conversation's own options to be kept, got max tokens %d", settings.MaxTokens)
+	}
+
+	// Setting the system prompt goes through the same resolution.
+	err = SetConversationSystem(convID, "Answer in French.")
+	if err != nil {
+		t.Errorf("SetConversationSystem returned an error: %v", err)
+	}
+}
+
+func TestResolveSettingsBeyondModelLimits(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	convID, err := db.CreateConversation("Limits")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	// Stored directly, as before the model registry knew haiku's limit.
+	for name, value := range map[string]string{db.OptionModel: "claude-3-haiku-20240307", db.OptionMaxTokens: "8192"} {
+		err := db.ConfigureConversation(convID, name, value)
+		if err != nil {
+			t.Fatalf("ConfigureConversation returned an error: %v", err)
+		}
+	}
+
+	settings, err := ResolveSettings(convID, nil)
+	if err != nil {
+		t.Fatalf("Expected stored settings beyond the model's limits to resolve, got %v", err)
+	}
+	if settings.MaxTokens != 8192 {
+		t.Errorf("Expected the stored max tokens, got %d", settings.MaxTokens)
+	}
+	if err := ValidateSettings(settings); err == nil {
+		t.Errorf("Expected ValidateSettings to refuse the stored max tokens")
+	}
+
+	var out bytes.Buffer
+	s := &Session{ConversationID: convID, out: &out}
+	if err := s.ReloadSettings(); err != nil {
+		t.Fatalf("ReloadSettings returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: max tokens 8192") {
+		t.Errorf("Expected a warning about the max tokens, got %q", out.String())
+	}
+
+	// Writing the same settings is still refused, and fixing them is not.
+	err = ConfigureConversation(convID, map[string]string{db.OptionTemperature: "0.5"})
+	if err == nil {
+		t.Errorf("Expected options to be refused while max tokens is beyond the model's limit")
+	}
+	err = ConfigureConversation(convID, map[string]string{db.OptionMaxTokens: ""})
+	if err != nil {
+		t.Errorf("Expected unsetting max tokens to be allowed, got %v", err)
+	}
+}
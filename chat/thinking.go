@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/christianhturner/go-claude/claude"
+)
+
+// EncodeThinking returns the thinking blocks of a response as they are stored
+// with the reply, empty when there are none.
+func EncodeThinking(response *claude.ResponseBody) (string, error) {
+	blocks := response.ThinkingBlocks()
+	if len(blocks) == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(blocks)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// DecodeThinking returns the thinking blocks stored with a reply.
+func DecodeThinking(thinking string) ([]claude.RequestContentBlock, error) {
+	if thinking == "" {
+		return nil, nil
+	}
+	var blocks []claude.RequestContentBlock
+	err := json.Unmarshal([]byte(thinking), &blocks)
+	return blocks, err
+}
+
+// withThinking returns an assistant message sending the stored thinking blocks
+// back ahead of its text. Content keeps the text for display.
+func withThinking(message claude.RequestMessages, thinking string) (claude.RequestMessages, error) {
+	blocks, err := DecodeThinking(thinking)
+	if err != nil || len(blocks) == 0 {
+		return message, err
+	}
+	if message.Content != "" {
+		blocks = append(blocks, claude.NewTextBlock(message.Content))
+	}
+	message.ContentBlocks = blocks
+	return message, nil
+}
+
+// withoutThinking returns messages without their thinking blocks, for requests
+// that do not enable extended thinking.
+func withoutThinking(messages []claude.RequestMessages) []claude.RequestMessages {
+	stripped := slices.Clone(messages)
+	for i, m := range stripped {
+		if !slices.ContainsFunc(m.ContentBlocks, isThinkingBlock) {
+			continue
+		}
+		m.ContentBlocks = slices.DeleteFunc(slices.Clone(m.ContentBlocks), isThinkingBlock)
+		if len(m.ContentBlocks) == 0 {
+			m.ContentBlocks = nil
+		}
+		stripped[i] = m
+	}
+	return stripped
+}
+
+func isThinkingBlock(block claude.RequestContentBlock) bool {
+	return block.Type == claude.RequestContentTypeThinkingType || block.Type == claude.RequestContentTypeRedactedThinkingType
+}
+
+// thinkingSize counts the words of the reasoning in thinking blocks and the
+// redacted blocks, whose reasoning is encrypted.
+func thinkingSize(blocks []claude.RequestContentBlock) (words, redacted int) {
+	for _, b := range blocks {
+		switch b.Type {
+		case claude.RequestContentTypeThinkingType:
+			words += len(strings.Fields(b.Thinking))
+		case claude.RequestContentTypeRedactedThinkingType:
+			redacted++
+		}
+	}
+	return words, redacted
+}
+
+// CollapsedThinking is the line standing in for the thinking blocks of a
+// reply when it is shown, empty when there are none.
+func CollapsedThinking(blocks []claude.RequestContentBlock) string {
+	words, redacted := thinkingSize(blocks)
+	switch {
+	case words == 0 && redacted == 0:
+		return ""
+	case redacted == 0:
+		return fmt.Sprintf("[thought for %d words]", words)
+	}
+	return fmt.Sprintf("[thought for %d words, %d redacted]", words, redacted)
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+)
+
+func TestThinkingReplay(t *testing.T) {
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	convID, err := db.CreateConversation("Thinking")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	response := &claude.ResponseBody{Content: []claude.ResponseContent{
+		{Type: claude.ResponseContentTypeThinking, Thinking: "Two plus two is four.", Signature: "c2ln"},
+		{Type: claude.ResponseContentTypeRedactedThinking, Data: "ZW5j"},
+		{Type: claude.ResponseContentTypeText, Text: "4"},
+	}}
+	thinking, err := EncodeThinking(response)
+	if err != nil {
+		t.Fatalf("EncodeThinking returned an error: %v", err)
+	}
+	err = db.AddMessage(convID, claude.MessageRoleUser, "What is 2+2?")
+	if err != nil {
+		t.Fatalf("AddMessage returned an error: %v", err)
+	}
+	_, err = db.AddReplyWithThinking(convID, response.Text(), thinking, db.Usage{Model: "claude-sonnet-4-20250514"})
+	if err != nil {
+		t.Fatalf("AddReplyWithThinking returned an error: %v", err)
+	}
+
+	_, history := GetCompactedHistory(convID)
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(history))
+	}
+	reply := history[1]
+	if reply.Content != "4" {
+		t.Errorf("Expected the reply text to be kept for display, got %q", reply.Content)
+	}
+	if len(reply.ContentBlocks) != 3 || reply.ContentBlocks[0].Signature != "c2ln" || reply.ContentBlocks[1].Data != "ZW5j" || reply.ContentBlocks[2].Text != "4" {
+		t.Fatalf("Expected the thinking blocks replayed ahead of the text, got %+v", reply.ContentBlocks)
+	}
+	if collapsed := CollapsedThinking(reply.ContentBlocks); collapsed != "[thought for 5 words, 1 redacted]" {
+		t.Errorf("Unexpected collapsed thinking %q", collapsed)
+	}
+
+	s := &Session{Settings: Settings{Model: "claude-sonnet-4-20250514", MaxTokens: 16000, ThinkingBudget: 8000}}
+	body := s.RequestBody(history)
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal the request: %v", err)
+	}
+	var sent struct {
+		Thinking struct {
+			Type         string `json:"type"`
+			BudgetTokens int    `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatalf("Failed to unmarshal the request: %v", err)
+	}
+	if sent.Thinking.Type != claude.ThinkingTypeEnabled || sent.Thinking.BudgetTokens != 8000 {
+		t.Errorf("Expected thinking enabled with a budget of 8000, got %+v", sent.Thinking)
+	}
+	if len(body.Messages[1].ContentBlocks) != 3 {
+		t.Errorf("Expected the thinking blocks sent back with thinking on")
+	}
+
+	s.Settings.ThinkingBudget = 0
+	body = s.RequestBody(history)
+	if body.Thinking != nil {
+		t.Errorf("Expected no thinking config with a budget of 0")
+	}
+	if blocks := body.Messages[1].ContentBlocks; len(blocks) != 1 || blocks[0].Text != "4" {
+		t.Errorf("Expected the thinking blocks left out with thinking off, got %+v", blocks)
+	}
+	if len(history[1].ContentBlocks) != 3 {
+		t.Errorf("Expected leaving out the thinking blocks not to change the history")
+	}
+}
+
+func TestValidateThinkingSettings(t *testing.T) {
+	valid := Settings{Model: "claude-sonnet-4-20250514", MaxTokens: 16000, ThinkingBudget: 8000}
+	if err := ValidateSettings(valid); err != nil {
+		t.Errorf("Expected valid thinking settings, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Settings)
+	}{
+		{"budget below the minimum", func(s *Settings) { s.ThinkingBudget = 512 }},
+		{"budget not below max tokens", func(s *Settings) { s.ThinkingBudget = 16000 }},
+		{"model without thinking", func(s *Settings) { s.Model = "claude-3-5-sonnet-20241022"; s.MaxTokens = 8192; s.ThinkingBudget = 4096 }},
+		{"temperature", func(s *Settings) { s.Temperature = floatPtr(0.5) }},
+		{"top-k", func(s *Settings) { s.TopK = floatPtr(40) }},
+		{"top-p", func(s *Settings) { s.TopP = floatPtr(0.9) }},
+	}
+	for _, tt := range tests {
+		settings := valid
+		tt.change(&settings)
+		if err := ValidateSettings(settings); err == nil {
+			t.Errorf("%s: expected the settings to be refused", tt.name)
+		}
+	}
+}
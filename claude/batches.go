@@ -0,0 +1,180 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Message batches process many requests asynchronously, within a day, at
+// half the price. See https://docs.anthropic.com/en/docs/build-with-claude/message-batches
+
+const batchesEndpoint = "/batches"
+
+// Processing statuses of a batch.
+const (
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCanceling  = "canceling"
+	BatchStatusEnded      = "ended"
+)
+
+// Result types of a request in a batch.
+const (
+	BatchResultSucceeded = "succeeded"
+	BatchResultErrored   = "errored"
+	BatchResultCanceled  = "canceled"
+	BatchResultExpired   = "expired"
+)
+
+// BatchRequest is one request of a batch. CustomID identifies its result and
+// must be unique within the batch.
+type BatchRequest struct {
+	CustomID string      `json:"custom_id"`
+	Params   RequestBody `json:"params"`
+}
+
+// MessageBatch is the state of a batch.
+type MessageBatch struct {
+	Id                string             `json:"id"`
+	Type              string             `json:"type"` // always "message_batch"
+	ProcessingStatus  string             `json:"processing_status"`
+	RequestCounts     BatchRequestCounts `json:"request_counts"`
+	CreatedAt         time.Time          `json:"created_at"`
+	EndedAt           *time.Time         `json:"ended_at"`
+	ExpiresAt         time.Time          `json:"expires_at"`
+	CancelInitiatedAt *time.Time         `json:"cancel_initiated_at"`
+	ResultsURL        string             `json:"results_url"` // set once the batch has ended
+}
+
+// BatchRequestCounts counts the requests of a batch by their state.
+type BatchRequestCounts struct {
+	Processing int64 `json:"processing"`
+	Succeeded  int64 `json:"succeeded"`
+	Errored    int64 `json:"errored"`
+	Canceled   int64 `json:"canceled"`
+	Expired    int64 `json:"expired"`
+}
+
+// BatchResult is the outcome of one request of a batch.
+type BatchResult struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string         `json:"type"` // one of the BatchResult constants
+		Message *ResponseBody  `json:"message,omitempty"`
+		Error   *ResponseError `json:"error,omitempty"`
+	} `json:"result"`
+
+	Raw json.RawMessage `json:"-"` // the line of the results file
+}
+
+// Err returns the error of a request that didn't succeed, nil otherwise.
+func (r *BatchResult) Err() error {
+	switch r.Result.Type {
+	case BatchResultSucceeded:
+		return nil
+	case BatchResultErrored:
+		if r.Result.Error != nil {
+			return &APIError{Type: r.Result.Error.Error.Type, Message: r.Result.Error.Error.Message}
+		}
+	}
+	return errors.New("request " + r.Result.Type)
+}
+
+// CreateBatch submits requests as a batch. The request guard runs once,
+// with the largest of the requests. Since a batch created twice is billed
+// twice, it is only retried after a 429 or 529, never after another 5xx.
+func (c *Client) CreateBatch(ctx context.Context, requests []BatchRequest) (*MessageBatch, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("a batch needs at least one request")
+	}
+	largest, tokens := 0, -1
+	for i := range requests {
+		err := encodeContent(requests[i].Params.Messages)
+		if err != nil {
+			return nil, err
+		}
+		if t := EstimateRequestTokens(requests[i].Params); t > tokens {
+			largest, tokens = i, t
+		}
+	}
+	err := c.checkGuard(ctx, requests[largest].Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch MessageBatch
+	err = c.doJSON(onlyRetryRejected(ctx), http.MethodPost, c.config.Endpoint+batchesEndpoint, map[string]interface{}{"requests": requests}, &batch)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetBatch returns the current state of a batch.
+func (c *Client) GetBatch(ctx context.Context, batchID string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := c.doJSON(ctx, http.MethodGet, c.batchPath(batchID), nil, &batch)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// CancelBatch asks for a batch to stop. Requests already processing finish,
+// the batch is canceling until they do.
+func (c *Client) CancelBatch(ctx context.Context, batchID string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := c.doJSON(ctx, http.MethodPost, c.batchPath(batchID)+"/cancel", nil, &batch)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// BatchResults reads the results of an ended batch, calling fn for each of
+// them in turn until it returns an error. Results are in no particular order,
+// match them to their requests by CustomID.
+func (c *Client) BatchResults(ctx context.Context, batchID string, fn func(BatchResult) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, c.batchPath(batchID)+"/results", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var result BatchResult
+		err = json.Unmarshal(raw, &result)
+		if err != nil {
+			return err
+		}
+		result.Raw = raw
+		err = fn(result)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) batchPath(batchID string) string {
+	return c.config.Endpoint + batchesEndpoint + "/" + url.PathEscape(batchID)
+}
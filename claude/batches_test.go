@@ -0,0 +1,161 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// batchServer stands in for the message batches API. A batch ends once it
+// has been polled, every request succeeding with its custom ID as the reply
+// except those whose custom ID starts with "bad".
+type batchServer struct {
+	mu       sync.Mutex
+	requests map[string][]BatchRequest
+	polled   map[string]bool
+	canceled map[string]bool
+}
+
+func (s *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v1/messages/batches")
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		var body struct {
+			Requests []BatchRequest `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := fmt.Sprintf("msgbatch_%d", len(s.requests)+1)
+		s.requests[id] = body.Requests
+		s.writeBatch(w, id)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/cancel"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/cancel")
+		s.canceled[id] = true
+		s.writeBatch(w, id)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/results"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results")
+		for _, req := range s.requests[id] {
+			result := map[string]interface{}{"type": "succeeded", "message": map[string]interface{}{
+				"type":    "message",
+				"model":   req.Params.Model,
+				"content": []map[string]string{{"type": "text", "text": req.CustomID}},
+				"usage":   map[string]int{"input_tokens": 10, "output_tokens": 2},
+			}}
+			if strings.HasPrefix(req.CustomID, "bad") {
+				result = map[string]interface{}{"type": "errored", "error": map[string]interface{}{
+					"type": "error", "error": map[string]string{"type": "invalid_request_error", "message": "max_tokens: Field required"},
+				}}
+			}
+			line, _ := json.Marshal(map[string]interface{}{"custom_id": req.CustomID, "result": result})
+			w.Write(append(line, '\n'))
+		}
+	case r.Method == http.MethodGet:
+		id := strings.TrimPrefix(path, "/")
+		if _, ok := s.requests[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"batch not found"}}`))
+			return
+		}
+		s.polled[id] = true
+		s.writeBatch(w, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *batchServer) writeBatch(w http.ResponseWriter, id string) {
+	batch := map[string]interface{}{
+		"id":                id,
+		"type":              "message_batch",
+		"processing_status": BatchStatusInProgress,
+		"request_counts":    map[string]int{"processing": len(s.requests[id])},
+		"created_at":        "2024-10-08T12:00:00Z",
+		"expires_at":        "2024-10-09T12:00:00Z",
+	}
+	switch {
+	case s.canceled[id]:
+		batch["processing_status"] = BatchStatusCanceling
+	case s.polled[id]:
+		batch["processing_status"] = BatchStatusEnded
+		batch["ended_at"] = "2024-10-08T12:30:00Z"
+		batch["request_counts"] = map[string]int{"succeeded": len(s.requests[id])}
+	}
+	json.NewEncoder(w).Encode(batch)
+}
+
+func TestBatches(t *testing.T) {
+	stand := &batchServer{requests: map[string][]BatchRequest{}, polled: map[string]bool{}, canceled: map[string]bool{}}
+	server := httptest.NewServer(stand)
+	defer server.Close()
+	client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+	ctx := context.Background()
+
+	requests := []BatchRequest{
+		{CustomID: "first", Params: RequestBody{Model: "claude-3-haiku-20240307", MaxTokens: 10, Messages: []RequestMessages{{Role: MessageRoleUser, Content: "hello"}}}},
+		{CustomID: "bad-second", Params: RequestBody{Model: "claude-3-haiku-20240307", Messages: []RequestMessages{{Role: MessageRoleUser, Content: "hi"}}}},
+	}
+	batch, err := client.CreateBatch(ctx, requests)
+	if err != nil {
+		t.Fatalf("CreateBatch returned an error: %v", err)
+	}
+	if batch.Id != "msgbatch_1" || batch.ProcessingStatus != BatchStatusInProgress || batch.RequestCounts.Processing != 2 || batch.EndedAt != nil {
+		t.Errorf("Unexpected batch %+v", batch)
+	}
+	sent := stand.requests["msgbatch_1"]
+	if len(sent) != 2 || sent[0].Params.Messages[0].ContentRaw != "hello" {
+		t.Errorf("Expected the requests' content to be sent, got %+v", sent)
+	}
+
+	batch, err = client.GetBatch(ctx, "msgbatch_1")
+	if err != nil || batch.ProcessingStatus != BatchStatusEnded || batch.EndedAt == nil || batch.RequestCounts.Succeeded != 2 {
+		t.Errorf("Expected the batch to have ended, got %+v, %v", batch, err)
+	}
+
+	var texts []string
+	var failed []error
+	err = client.BatchResults(ctx, "msgbatch_1", func(r BatchResult) error {
+		if err := r.Err(); err != nil {
+			failed = append(failed, err)
+			return nil
+		}
+		texts = append(texts, r.CustomID+"="+r.Result.Message.Text())
+		if r.Result.Message.Usage.InputTokens != 10 || !strings.Contains(string(r.Raw), `"custom_id":"first"`) {
+			t.Errorf("Unexpected result %+v", r)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("BatchResults returned an error: %v", err)
+	}
+	var apiErr *APIError
+	if len(texts) != 1 || texts[0] != "first=first" || len(failed) != 1 || !errors.As(failed[0], &apiErr) || apiErr.Type != ErrorTypeInvalidRequest {
+		t.Errorf("Expected one success and one invalid request, got %v and %v", texts, failed)
+	}
+
+	stop := errors.New("stop")
+	err = client.BatchResults(ctx, "msgbatch_1", func(r BatchResult) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected fn's error to stop reading, got %v", err)
+	}
+
+	batch, err = client.CancelBatch(ctx, "msgbatch_1")
+	if err != nil || batch.ProcessingStatus != BatchStatusCanceling {
+		t.Errorf("Expected the batch to be canceling, got %+v, %v", batch, err)
+	}
+
+	_, err = client.GetBatch(ctx, "msgbatch_9")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	_, err = client.CreateBatch(ctx, nil)
+	if err == nil {
+		t.Errorf("Expected an empty batch to be refused")
+	}
+}
@@ -0,0 +1,63 @@
+package claude
+
+import "slices"
+
+// Prompt caching, see https://docs.anthropic.com/en/docs/build-with-claude/prompt-caching
+// A cache_control marker on a tool, system block or content block caches the
+// request up to and including it. Later requests with the same prefix read it
+// from the cache for a tenth of the input price; writing it costs a quarter
+// more than the input price.
+
+const CacheControlTypeEphemeral = "ephemeral" // kept for five minutes after its last use
+
+// MaxCacheBreakpoints is the most cache_control markers a request may carry.
+const MaxCacheBreakpoints = 4
+
+// DefaultCacheMinTokens is the shortest prefix cached by models not listed in
+// KnownModels.
+const DefaultCacheMinTokens = 1024
+
+// CacheControl marks the end of a prefix to cache.
+type CacheControl struct {
+	Type string `json:"type"` // always "ephemeral"
+}
+
+// EphemeralCache returns the cache_control marking a prefix to cache.
+func EphemeralCache() *CacheControl {
+	return &CacheControl{Type: CacheControlTypeEphemeral}
+}
+
+// CacheMinTokens returns the shortest prefix, in tokens, a model caches, and
+// 0 when the model does not support prompt caching. Models missing from
+// KnownModels are assumed to cache from DefaultCacheMinTokens.
+func CacheMinTokens(model string) int {
+	info, ok := LookupModel(model)
+	if !ok {
+		return DefaultCacheMinTokens
+	}
+	return info.CacheMinTokens
+}
+
+// CacheMessage returns m with a cache_control marker on its last content
+// block, turning string content into a text block. Messages holding only
+// ContentRaw are returned as they are.
+func CacheMessage(m RequestMessages) RequestMessages {
+	var blocks []RequestContentBlock
+	switch {
+	case len(m.ContentBlocks) > 0:
+		blocks = slices.Clone(m.ContentBlocks)
+	case len(m.ContentTypeText) > 0:
+		for _, text := range m.ContentTypeText {
+			blocks = append(blocks, NewTextBlock(text.Text))
+		}
+	case m.Content != "":
+		blocks = []RequestContentBlock{NewTextBlock(m.Content)}
+	default:
+		return m
+	}
+	blocks[len(blocks)-1].CacheControl = EphemeralCache()
+	m.Content = ""
+	m.ContentTypeText = nil
+	m.ContentBlocks = blocks
+	return m
+}
@@ -0,0 +1,84 @@
+package claude
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCacheControlJSON(t *testing.T) {
+	body := RequestBody{
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 100,
+		System:    "Be brief.",
+		Tools:     []Tool{{Name: "lookup", InputSchema: map[string]interface{}{"type": "object"}, CacheControl: EphemeralCache()}},
+		Messages:  []RequestMessages{CacheMessage(RequestMessages{Role: MessageRoleUser, Content: "hello"})},
+	}
+	raw, err := parseBodyJSON(body)
+	if err != nil {
+		t.Fatalf("parseBodyJSON returned an error: %v", err)
+	}
+	for _, want := range []string{
+		`"system":"Be brief."`,
+		`"input_schema":{"type":"object"},"cache_control":{"type":"ephemeral"}`,
+		`"content":[{"type":"text","text":"hello","cache_control":{"type":"ephemeral"}}]`,
+	} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("Expected %s in %s", want, raw)
+		}
+	}
+
+	body.SystemBlocks = []RequestContentBlock{NewTextBlock("Be brief.")}
+	body.SystemBlocks[0].CacheControl = EphemeralCache()
+	raw, err = json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Marshal returned an error: %v", err)
+	}
+	if !strings.Contains(string(raw), `"system":[{"type":"text","text":"Be brief.","cache_control":{"type":"ephemeral"}}]`) || strings.Count(string(raw), `"system"`) != 1 {
+		t.Errorf("Expected the system blocks to replace the system string, got %s", raw)
+	}
+
+	body = RequestBody{Model: "claude-3-haiku-20240307", Messages: []RequestMessages{{Role: MessageRoleUser, Content: "hi"}}}
+	raw, _ = json.Marshal(body)
+	if strings.Contains(string(raw), `"system"`) {
+		t.Errorf("Expected no system without a prompt, got %s", raw)
+	}
+}
+
+func TestCacheMessage(t *testing.T) {
+	original := []RequestContentBlock{NewTextBlock("look"), NewImageBlock(MediaTypePNG, []byte("png"))}
+	for _, m := range []RequestMessages{
+		{Role: MessageRoleUser, Content: "hello"},
+		{Role: MessageRoleUser, ContentTypeText: []RequestContentTypeText{{Text: "one"}, {Text: "two"}}},
+		{Role: MessageRoleUser, ContentBlocks: original},
+	} {
+		cached := CacheMessage(m)
+		blocks := cached.ContentBlocks
+		if cached.Content != "" || cached.ContentTypeText != nil || len(blocks) == 0 || blocks[len(blocks)-1].CacheControl == nil {
+			t.Errorf("Expected the last block of %+v to be cached, got %+v", m, cached)
+			continue
+		}
+		for _, b := range blocks[:len(blocks)-1] {
+			if b.CacheControl != nil {
+				t.Errorf("Expected only the last block to be cached, got %+v", blocks)
+			}
+		}
+	}
+	if original[1].CacheControl != nil {
+		t.Errorf("Expected the message's own blocks to be left alone")
+	}
+	if CacheMinTokens("claude-2.1") != 0 || CacheMinTokens("claude-3-haiku-20240307") != 2048 || CacheMinTokens("claude-9") != DefaultCacheMinTokens {
+		t.Errorf("Unexpected minimum cached prefixes")
+	}
+}
+
+func TestCacheUsage(t *testing.T) {
+	var response ResponseBody
+	err := json.Unmarshal([]byte(`{"usage":{"input_tokens":12,"output_tokens":5,"cache_creation_input_tokens":1500,"cache_read_input_tokens":3000}}`), &response)
+	if err != nil {
+		t.Fatalf("Unmarshal returned an error: %v", err)
+	}
+	if response.Usage != (Usage{InputTokens: 12, OutputTokens: 5, CacheCreationInputTokens: 1500, CacheReadInputTokens: 3000}) {
+		t.Errorf("Unexpected usage %+v", response.Usage)
+	}
+}
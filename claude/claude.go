@@ -1,11 +1,22 @@
 package claude
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
 
 type Client struct {
 	config ClientConfig
+	guard  RequestGuard
 }
 
+// RequestGuard is called before every request is sent. An error stops the
+// request and is returned to the caller, e.g. to enforce a spending budget.
+type RequestGuard func(ctx context.Context, body RequestBody) error
+
 // Found versions and such here https://docs.anthropic.com/en/api/messages-examples
 const (
 	defaultBaseURL  = "https://api.anthropic.com/"
@@ -25,6 +36,9 @@ type ClientConfig struct {
 	BaseURL    string
 	Endpoint   string
 	HTTPCLient *http.Client
+
+	Retry   RetryPolicy // zero to never retry
+	Limiter *Limiter    // nil to send requests as soon as they are made
 }
 
 func defaultConfig(apiKey string) ClientConfig {
@@ -37,6 +51,8 @@ func defaultConfig(apiKey string) ClientConfig {
 		BaseURL:    defaultBaseURL,
 		Endpoint:   defaultEndpoint,
 		HTTPCLient: &http.Client{},
+
+		Retry: DefaultRetryPolicy(),
 	}
 }
 
@@ -55,3 +71,95 @@ func NewClientWithConfig(config ClientConfig) *Client {
 func (c *Client) SetVersion(version string) {
 	c.config.Version = version
 }
+
+// SetRetryPolicy replaces how failed requests are retried.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.config.Retry = policy
+}
+
+// SetLimiter makes requests wait for limiter, nil removes it.
+func (c *Client) SetLimiter(limiter *Limiter) {
+	c.config.Limiter = limiter
+}
+
+// SetRequestGuard makes guard run before every request, nil removes it.
+func (c *Client) SetRequestGuard(guard RequestGuard) {
+	c.guard = guard
+}
+
+func (c *Client) checkGuard(ctx context.Context, body RequestBody) error {
+	if c.guard == nil {
+		return nil
+	}
+	return c.guard(ctx, body)
+}
+
+// httpClient returns the configured HTTP client with the retry policy and
+// the limiter applied to its requests.
+func (c *Client) httpClient() *http.Client {
+	client := c.config.HTTPCLient
+	if client == nil {
+		client = &http.Client{}
+	}
+	if c.config.Retry.MaxRetries <= 0 && c.config.Limiter == nil {
+		return client
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if c.config.Limiter != nil {
+		transport = &limitTransport{next: transport, limiter: c.config.Limiter}
+	}
+	if c.config.Retry.MaxRetries > 0 {
+		transport = &retryTransport{next: transport, policy: c.config.Retry}
+	}
+	wrapped := *client
+	wrapped.Transport = transport
+	return &wrapped
+}
+
+// newRequest returns a request to an API path, relative to the base URL,
+// with the authentication and version headers set.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", c.config.ApiKey)
+	req.Header.Set("Anthropic-Version", c.config.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.config.Beta != "" {
+		req.Header.Set("Anthropic-Beta", c.config.Beta)
+	}
+	return req, nil
+}
+
+// doJSON sends in, when not nil, as the JSON body of a request to an API path
+// and decodes the response into out.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		jsonBody, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonBody)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,45 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestGuard(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+	refused := errors.New("refused")
+	client.SetRequestGuard(func(ctx context.Context, body RequestBody) error {
+		if body.Model == "blocked" {
+			return refused
+		}
+		return nil
+	})
+
+	_, err := client.CreateMessages(context.Background(), RequestBody{Model: "blocked"})
+	if !errors.Is(err, refused) {
+		t.Errorf("Expected the guard's error, got %v", err)
+	}
+	_, err = client.CreateMessagesStream(context.Background(), RequestBody{Model: "blocked"})
+	if !errors.Is(err, refused) {
+		t.Errorf("Expected the guard's error for a stream, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected refused requests not to be sent, got %d", requests)
+	}
+
+	response, err := client.CreateMessages(context.Background(), RequestBody{Model: "allowed"})
+	if err != nil || response.Text() != "hi" || requests != 1 {
+		t.Errorf("Expected the allowed request to be sent, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package claude
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MediaTypeJPEG = "image/jpeg"
+	MediaTypePNG  = "image/png"
+	MediaTypeGIF  = "image/gif"
+	MediaTypeWebP = "image/webp"
+	MediaTypePDF  = "application/pdf"
+)
+
+// Size limits enforced by the API for base64 sources, measured on the raw file.
+const (
+	MaxImageSize    = 5 * 1024 * 1024
+	MaxDocumentSize = 32 * 1024 * 1024
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+var imageExtensions = map[string]string{
+	".jpg":  MediaTypeJPEG,
+	".jpeg": MediaTypeJPEG,
+	".png":  MediaTypePNG,
+	".gif":  MediaTypeGIF,
+	".webp": MediaTypeWebP,
+}
+
+// NewImageBlock returns an image block holding data encoded as base64.
+func NewImageBlock(mediaType string, data []byte) RequestContentBlock {
+	return NewImageBlockBase64(mediaType, base64.StdEncoding.EncodeToString(data))
+}
+
+// NewImageBlockBase64 returns an image block holding data already encoded as
+// base64, e.g. as it was stored.
+func NewImageBlockBase64(mediaType, data string) RequestContentBlock {
+	return RequestContentBlock{
+		Type: RequestContentTypeImageType,
+		Source: &RequestContentSource{
+			Type:      RequestContentSourceTypeBase64,
+			MediaType: mediaType,
+			Data:      data,
+		},
+	}
+}
+
+// NewDocumentBlock returns a PDF document block holding data encoded as base64.
+func NewDocumentBlock(title string, data []byte) RequestContentBlock {
+	return NewDocumentBlockBase64(title, base64.StdEncoding.EncodeToString(data))
+}
+
+// NewDocumentBlockBase64 returns a PDF document block holding data already
+// encoded as base64.
+func NewDocumentBlockBase64(title, data string) RequestContentBlock {
+	return RequestContentBlock{
+		Type:  RequestContentTypeDocumentType,
+		Title: title,
+		Source: &RequestContentSource{
+			Type:      RequestContentSourceTypeBase64,
+			MediaType: MediaTypePDF,
+			Data:      data,
+		},
+	}
+}
+
+// NewImageBlockFromFile reads a jpeg, png, gif or webp file into an image block.
+func NewImageBlockFromFile(path string) (RequestContentBlock, error) {
+	data, mediaType, err := readMediaFile(path)
+	if err != nil {
+		return RequestContentBlock{}, err
+	}
+	if !IsImageMediaType(mediaType) {
+		return RequestContentBlock{}, fmt.Errorf("%s: %w %s, expected an image", path, ErrUnsupportedMediaType, mediaType)
+	}
+	if len(data) > MaxImageSize {
+		return RequestContentBlock{}, fmt.Errorf("%s: image is %d bytes, the limit is %d", path, len(data), MaxImageSize)
+	}
+	return NewImageBlock(mediaType, data), nil
+}
+
+// NewDocumentBlockFromFile reads a PDF file into a document block titled with
+// the file name.
+func NewDocumentBlockFromFile(path string) (RequestContentBlock, error) {
+	data, mediaType, err := readMediaFile(path)
+	if err != nil {
+		return RequestContentBlock{}, err
+	}
+	if mediaType != MediaTypePDF {
+		return RequestContentBlock{}, fmt.Errorf("%s: %w %s, expected a PDF", path, ErrUnsupportedMediaType, mediaType)
+	}
+	if len(data) > MaxDocumentSize {
+		return RequestContentBlock{}, fmt.Errorf("%s: document is %d bytes, the limit is %d", path, len(data), MaxDocumentSize)
+	}
+	return NewDocumentBlock(filepath.Base(path), data), nil
+}
+
+// NewMediaBlockFromFile builds an image or document block depending on the
+// file's media type. Any other file returns ErrUnsupportedMediaType.
+func NewMediaBlockFromFile(path string) (RequestContentBlock, error) {
+	mediaType, err := DetectMediaType(path)
+	if err != nil {
+		return RequestContentBlock{}, err
+	}
+	switch {
+	case IsImageMediaType(mediaType):
+		return NewImageBlockFromFile(path)
+	case mediaType == MediaTypePDF:
+		return NewDocumentBlockFromFile(path)
+	}
+	return RequestContentBlock{}, fmt.Errorf("%s: %w %s", path, ErrUnsupportedMediaType, mediaType)
+}
+
+// DetectMediaType guesses the media type of a file from its extension,
+// falling back to sniffing the first bytes of its contents.
+func DetectMediaType(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if mediaType, ok := imageExtensions[ext]; ok {
+		return mediaType, nil
+	}
+	if ext == ".pdf" {
+		return MediaTypePDF, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := f.Read(head)
+	if err != nil && n == 0 {
+		return "", err
+	}
+	mediaType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return mediaType, nil
+}
+
+// IsImageMediaType reports whether the API accepts mediaType as an image.
+func IsImageMediaType(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeJPEG, MediaTypePNG, MediaTypeGIF, MediaTypeWebP:
+		return true
+	}
+	return false
+}
+
+func readMediaFile(path string) ([]byte, string, error) {
+	mediaType, err := DetectMediaType(path)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, mediaType, nil
+}
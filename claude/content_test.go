@@ -0,0 +1,139 @@
+package claude
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	pngBytes  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpegBytes = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	gifBytes  = []byte("GIF89a\x01\x00\x01\x00")
+	webpBytes = []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+	pdfBytes  = []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+)
+
+// writeFixture writes data to name in a temporary directory and returns its path.
+func writeFixture(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDetectMediaType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"photo.jpg", jpegBytes, MediaTypeJPEG},
+		{"photo.JPEG", jpegBytes, MediaTypeJPEG},
+		{"screen.png", pngBytes, MediaTypePNG},
+		{"anim.gif", gifBytes, MediaTypeGIF},
+		{"pic.webp", webpBytes, MediaTypeWebP},
+		{"spec.pdf", pdfBytes, MediaTypePDF},
+		// Without a known extension the contents decide.
+		{"screen", pngBytes, MediaTypePNG},
+		{"photo.bin", jpegBytes, MediaTypeJPEG},
+		{"spec", pdfBytes, MediaTypePDF},
+		{"notes.txt", []byte("just some text\n"), "text/plain"},
+	}
+	for _, tt := range tests {
+		got, err := DetectMediaType(writeFixture(t, tt.name, tt.data))
+		if err != nil {
+			t.Errorf("%s: DetectMediaType returned an error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := DetectMediaType(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestNewMediaBlockFromFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		blockType string
+		mediaType string
+	}{
+		{"screen.png", pngBytes, RequestContentTypeImageType, MediaTypePNG},
+		{"photo.jpg", jpegBytes, RequestContentTypeImageType, MediaTypeJPEG},
+		{"anim.gif", gifBytes, RequestContentTypeImageType, MediaTypeGIF},
+		{"pic.webp", webpBytes, RequestContentTypeImageType, MediaTypeWebP},
+		{"spec.pdf", pdfBytes, RequestContentTypeDocumentType, MediaTypePDF},
+	}
+	for _, tt := range tests {
+		block, err := NewMediaBlockFromFile(writeFixture(t, tt.name, tt.data))
+		if err != nil {
+			t.Errorf("%s: NewMediaBlockFromFile returned an error: %v", tt.name, err)
+			continue
+		}
+		if block.Type != tt.blockType || block.Source == nil || block.Source.MediaType != tt.mediaType {
+			t.Errorf("%s: unexpected block %+v", tt.name, block)
+			continue
+		}
+		if block.Source.Data != base64.StdEncoding.EncodeToString(tt.data) {
+			t.Errorf("%s: expected the file contents encoded as base64", tt.name)
+		}
+		if tt.blockType == RequestContentTypeDocumentType && block.Title != tt.name {
+			t.Errorf("%s: expected the document titled with the file name, got %q", tt.name, block.Title)
+		}
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	unsupported := []struct {
+		name  string
+		build func(string) (RequestContentBlock, error)
+		data  []byte
+	}{
+		{"notes.txt", NewMediaBlockFromFile, []byte("just some text\n")},
+		{"page.html", NewMediaBlockFromFile, []byte("<html><body>hi</body></html>")},
+		{"spec.pdf", NewImageBlockFromFile, pdfBytes},
+		{"screen.png", NewDocumentBlockFromFile, pngBytes},
+	}
+	for _, tt := range unsupported {
+		_, err := tt.build(writeFixture(t, tt.name, tt.data))
+		if !errors.Is(err, ErrUnsupportedMediaType) {
+			t.Errorf("%s: expected ErrUnsupportedMediaType, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestMediaSizeLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int64
+		data  []byte
+	}{
+		{"large.png", MaxImageSize, pngBytes},
+		{"large.pdf", MaxDocumentSize, pdfBytes},
+	}
+	for _, tt := range tests {
+		// Files are grown sparsely to the limit, keeping their header.
+		path := writeFixture(t, tt.name, tt.data)
+		if err := os.Truncate(path, tt.limit); err != nil {
+			t.Fatalf("Failed to grow %s: %v", tt.name, err)
+		}
+		if _, err := NewMediaBlockFromFile(path); err != nil {
+			t.Errorf("%s: expected a file of exactly the limit to be accepted, got %v", tt.name, err)
+		}
+		if err := os.Truncate(path, tt.limit+1); err != nil {
+			t.Fatalf("Failed to grow %s: %v", tt.name, err)
+		}
+		if _, err := NewMediaBlockFromFile(path); err == nil {
+			t.Errorf("%s: expected a file over the limit to be refused", tt.name)
+		}
+	}
+}
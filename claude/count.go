@@ -0,0 +1,46 @@
+package claude
+
+import (
+	"context"
+	"net/http"
+)
+
+const countTokensEndpoint = "/count_tokens"
+
+// countTokensBody is the part of a request the count_tokens endpoint reads,
+// it rejects the sampling fields.
+type countTokensBody struct {
+	Model      string            `json:"model"`
+	Messages   []RequestMessages `json:"messages"`
+	System     interface{}       `json:"system,omitempty"`
+	Tools      []Tool            `json:"tools,omitempty"`
+	ToolChoice *ToolChoice       `json:"tool_choice,omitempty"`
+	Thinking   *ThinkingConfig   `json:"thinking,omitempty"`
+}
+
+type countTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// CountTokens asks the API how many input tokens body would be billed for.
+// Counting is free and not subject to the request guard or the limiter. See
+// EstimateRequestTokens for an offline estimate.
+func (c *Client) CountTokens(ctx context.Context, body RequestBody) (int, error) {
+	err := encodeContent(body.Messages)
+	if err != nil {
+		return 0, err
+	}
+	var result countTokensResponse
+	err = c.doJSON(ctx, http.MethodPost, c.config.Endpoint+countTokensEndpoint, countTokensBody{
+		Model:      body.Model,
+		Messages:   body.Messages,
+		System:     body.system(),
+		Tools:      body.Tools,
+		ToolChoice: body.ToolChoice,
+		Thinking:   body.Thinking,
+	}, &result)
+	if err != nil {
+		return 0, err
+	}
+	return result.InputTokens, nil
+}
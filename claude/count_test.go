@@ -0,0 +1,65 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCountTokens(t *testing.T) {
+	var path string
+	var fields map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&fields)
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		w.Write([]byte(`{"input_tokens":42}`))
+	}))
+	defer server.Close()
+	config := ClientConfig{ApiKey: "key", BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()}
+
+	body := RequestBody{
+		Model:     "claude-3-haiku-20240307",
+		MaxTokens: 100,
+		System:    "Be brief.",
+		Messages:  []RequestMessages{{Role: MessageRoleUser, Content: "hello"}},
+	}
+	tokens, err := NewClientWithConfig(config).CountTokens(context.Background(), body)
+	if err != nil || tokens != 42 {
+		t.Fatalf("Expected 42 tokens, got %d, %v", tokens, err)
+	}
+	if path != "/v1/messages/count_tokens" {
+		t.Errorf("Unexpected path %s", path)
+	}
+	if _, ok := fields["max_tokens"]; ok || string(fields["system"]) != `"Be brief."` || string(fields["messages"]) != `[{"role":"user","content":"hello"}]` {
+		t.Errorf("Unexpected request %v", fields)
+	}
+	if _, ok := fields["thinking"]; ok {
+		t.Errorf("Expected no thinking without extended thinking, got %s", fields["thinking"])
+	}
+
+	body.Thinking = NewThinkingConfig(2048)
+	body.Tools = []Tool{{Name: "get_weather", InputSchema: map[string]interface{}{"type": "object"}}}
+	_, err = NewClientWithConfig(config).CountTokens(context.Background(), body)
+	if err != nil {
+		t.Fatalf("CountTokens returned an error: %v", err)
+	}
+	if string(fields["thinking"]) != `{"type":"enabled","budget_tokens":2048}` || len(fields["tools"]) == 0 {
+		t.Errorf("Expected thinking and tools to be counted, got %v", fields)
+	}
+	body.Thinking, body.Tools = nil, nil
+
+	config.ApiKey = "wrong"
+	_, err = NewClientWithConfig(config).CountTokens(context.Background(), body)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != ErrorTypeAuthentication {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error types sent by the API, see https://docs.anthropic.com/en/api/errors
+const (
+	ErrorTypeInvalidRequest  = "invalid_request_error"
+	ErrorTypeAuthentication  = "authentication_error"
+	ErrorTypePermission      = "permission_error"
+	ErrorTypeNotFound        = "not_found_error"
+	ErrorTypeRequestTooLarge = "request_too_large"
+	ErrorTypeRateLimit       = "rate_limit_error"
+	ErrorTypeAPI             = "api_error"
+	ErrorTypeOverloaded      = "overloaded_error"
+)
+
+// APIError is an error returned by the API, either as the response to a
+// request or as an error event of a stream. Use errors.As to get it from the
+// errors of the client.
+type APIError struct {
+	StatusCode int    // HTTP status, 0 for an error event of a stream
+	Type       string // one of the ErrorType constants, empty when the body wasn't an API error
+	Message    string
+	RequestID  string // request-id header, to quote when reporting a problem
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, "%d ", e.StatusCode)
+	}
+	if e.Type != "" {
+		b.WriteString(e.Type)
+	} else {
+		b.WriteString("api error")
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.RequestID != "" {
+		b.WriteString(" (request " + e.RequestID + ")")
+	}
+	return b.String()
+}
+
+// Temporary tells whether the same request may succeed later: the API was
+// rate limited, overloaded or failed on its side.
+func (e *APIError) Temporary() bool {
+	switch e.Type {
+	case ErrorTypeRateLimit, ErrorTypeOverloaded, ErrorTypeAPI:
+		return true
+	}
+	return e.StatusCode != 0 && retryable(e.StatusCode)
+}
+
+// newAPIError reads the error from a response that wasn't successful.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("Request-Id"),
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var result ResponseError
+	if json.Unmarshal(body, &result) == nil && result.Error.Message != "" {
+		apiErr.Type = result.Error.Type
+		apiErr.Message = result.Error.Message
+		return apiErr
+	}
+	apiErr.Message = strings.TrimSpace(string(body))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// streamAPIError returns the error carried by an error event of a stream.
+func streamAPIError(data []byte, requestID string) error {
+	var result ResponseError
+	err := json.Unmarshal(data, &result)
+	if err != nil {
+		return err
+	}
+	return &APIError{
+		Type:      result.Error.Type,
+		Message:   result.Error.Message,
+		RequestID: requestID,
+	}
+}
@@ -0,0 +1,103 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   APIError
+	}{
+		{429, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`,
+			APIError{StatusCode: 429, Type: ErrorTypeRateLimit, Message: "Number of requests has exceeded your rate limit", RequestID: "req_1"}},
+		{400, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: Field required"}}`,
+			APIError{StatusCode: 400, Type: ErrorTypeInvalidRequest, Message: "max_tokens: Field required", RequestID: "req_1"}},
+		{502, `<html>Bad Gateway</html>`,
+			APIError{StatusCode: 502, Message: "<html>Bad Gateway</html>", RequestID: "req_1"}},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Request-Id", "req_1")
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+
+		_, err := client.CreateMessages(context.Background(), RequestBody{})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || *apiErr != tt.want {
+			t.Errorf("Expected %+v for status %d, got %#v", tt.want, tt.status, err)
+		}
+
+		stream, err := client.CreateMessagesStream(context.Background(), RequestBody{})
+		if err != nil {
+			t.Fatalf("CreateMessagesStream returned an error: %v", err)
+		}
+		_, err = stream.Recv()
+		if !errors.As(err, &apiErr) || *apiErr != tt.want {
+			t.Errorf("Expected %+v from the stream for status %d, got %#v", tt.want, tt.status, err)
+		}
+		server.Close()
+	}
+}
+
+func TestAPIErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Request-Id", "req_2")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n" +
+			"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+	client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+
+	stream, err := client.CreateMessagesStream(context.Background(), RequestBody{})
+	if err != nil {
+		t.Fatalf("CreateMessagesStream returned an error: %v", err)
+	}
+	for i := 0; i < 2 && err == nil; i++ {
+		_, err = stream.Recv()
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != ErrorTypeOverloaded || apiErr.RequestID != "req_2" || !apiErr.Temporary() {
+		t.Errorf("Expected a temporary overloaded error, got %#v", err)
+	}
+	if apiErr != nil && apiErr.Error() != "overloaded_error: Overloaded (request req_2)" {
+		t.Errorf("Unexpected message %q", apiErr.Error())
+	}
+}
+
+func TestStreamContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/html; charset=utf-8", "<html><body>Bad gateway</body></html>"},
+		{"application/json", `{"type":"message","content":[]}`},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			w.Write([]byte(tt.body))
+		}))
+		client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()})
+
+		stream, err := client.CreateMessagesStream(context.Background(), RequestBody{})
+		if err != nil {
+			t.Fatalf("CreateMessagesStream returned an error: %v", err)
+		}
+		_, err = stream.Recv()
+		if err == nil || errors.Is(err, io.EOF) || !strings.Contains(err.Error(), "text/event-stream") {
+			t.Errorf("Expected a %s response to fail the stream, got %v", tt.contentType, err)
+		}
+		server.Close()
+	}
+}
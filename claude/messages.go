@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
 func (c *Client) CreateMessages(ctx context.Context, body RequestBody) (*ResponseBody, error) {
+	err := c.checkGuard(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	err = c.waitLimiter(ctx, body)
+	if err != nil {
+		return nil, err
+	}
 	reqURL := c.config.BaseURL + c.config.Endpoint
 	reqHeaders := map[string]string{
 		"X-Api-Key":         c.config.ApiKey,
@@ -32,7 +39,7 @@ func (c *Client) CreateMessages(ctx context.Context, body RequestBody) (*Respons
 		req.Header.Set(k, v)
 	}
 
-	resp, err := c.config.HTTPCLient.Do(req)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -45,23 +52,26 @@ func (c *Client) CreateMessages(ctx context.Context, body RequestBody) (*Respons
 		if err != nil {
 			return nil, err
 		}
+		result.RateLimit = ParseRateLimit(resp.Header)
 		return &result, nil
 	}
-	if (resp.StatusCode >= 400 && resp.StatusCode <= 500) || resp.StatusCode == 529 {
-		var result ResponseError
-		err = json.NewDecoder(resp.Body).Decode(&result)
-		if err != nil {
-			return nil, fmt.Errorf("json decode error: %w, status code: %d", err, resp.StatusCode)
-		}
-		return nil, fmt.Errorf("%s: %s", resp.Status, result.Error.Message)
-	}
-	return nil, fmt.Errorf("unexpected error: %d", resp.StatusCode)
+	return nil, newAPIError(resp)
 }
 
 func parseBodyJSON(req RequestBody) ([]byte, error) {
-	for i, m := range req.Messages {
+	err := encodeContent(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(req)
+}
+
+// encodeContent sets the ContentRaw sent for each message from the content
+// field it was built with.
+func encodeContent(messages []RequestMessages) error {
+	for i, m := range messages {
 		if m.Content != "" {
-			req.Messages[i].ContentRaw = m.Content
+			messages[i].ContentRaw = m.Content
 		}
 
 		if len(m.ContentTypeText) > 0 {
@@ -70,20 +80,18 @@ func parseBodyJSON(req RequestBody) ([]byte, error) {
 			}
 			raw, err := json.Marshal(m.ContentTypeText)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			req.Messages[i].ContentRaw = json.RawMessage(raw)
+			messages[i].ContentRaw = json.RawMessage(raw)
+		}
+
+		if len(m.ContentBlocks) > 0 {
+			raw, err := json.Marshal(m.ContentBlocks)
+			if err != nil {
+				return err
+			}
+			messages[i].ContentRaw = json.RawMessage(raw)
 		}
-		// if len(m.ContentTypeImage) > 0 {
-		// 	for j := range m.ContentTypeImage {
-		// 		m.ContentTypeImage[j].Type = "image"
-		// 	}
-		// 	raw, err := json.Marshal(m.ContentTypeImage)
-		// 	if err != nil {
-		// 		return nil, err
-		// 	}
-		// 	req.Messages[i].ContentRaw = json.RawMessage(raw)
-		// }
 	}
-	return json.Marshal(req)
+	return nil
 }
@@ -0,0 +1,88 @@
+package claude
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ModelInfo is what go-claude knows about a family of models without asking
+// the API. See https://docs.anthropic.com/en/docs/about-claude/models
+type ModelInfo struct {
+	ID              string // model name prefix it applies to, e.g. claude-3-5-sonnet
+	Name            string
+	ContextWindow   int     // tokens accepted, input and output together
+	MaxOutputTokens int     // largest max_tokens accepted
+	Vision          bool    // whether image blocks are accepted
+	InputPrice      float64 // list price in US dollars per million input tokens
+	OutputPrice     float64 // list price in US dollars per million output tokens
+	CacheMinTokens  int     // shortest prefix cached, 0 when prompt caching is not supported
+	Thinking        bool    // whether extended thinking is supported
+}
+
+// KnownModels is the registry of models, matched to a model name by the
+// longest ID it starts with, so dated versions and -latest aliases share the
+// entry of their family.
+var KnownModels = []ModelInfo{
+	{ID: "claude-opus-4", Name: "Claude Opus 4", ContextWindow: 200000, MaxOutputTokens: 32000, Vision: true, InputPrice: 15, OutputPrice: 75, CacheMinTokens: 1024, Thinking: true},
+	{ID: "claude-sonnet-4", Name: "Claude Sonnet 4", ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, InputPrice: 3, OutputPrice: 15, CacheMinTokens: 1024, Thinking: true},
+	{ID: "claude-3-7-sonnet", Name: "Claude 3.7 Sonnet", ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, InputPrice: 3, OutputPrice: 15, CacheMinTokens: 1024, Thinking: true},
+	{ID: "claude-3-5-sonnet", Name: "Claude 3.5 Sonnet", ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true, InputPrice: 3, OutputPrice: 15, CacheMinTokens: 1024},
+	{ID: "claude-3-5-sonnet-20240620", Name: "Claude 3.5 Sonnet (June 2024)", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, InputPrice: 3, OutputPrice: 15, CacheMinTokens: 1024},
+	{ID: "claude-3-5-haiku", Name: "Claude 3.5 Haiku", ContextWindow: 200000, MaxOutputTokens: 8192, InputPrice: 0.8, OutputPrice: 4, CacheMinTokens: 2048},
+	{ID: "claude-3-opus", Name: "Claude 3 Opus", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, InputPrice: 15, OutputPrice: 75, CacheMinTokens: 1024},
+	{ID: "claude-3-sonnet", Name: "Claude 3 Sonnet", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, InputPrice: 3, OutputPrice: 15},
+	{ID: "claude-3-haiku", Name: "Claude 3 Haiku", ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, InputPrice: 0.25, OutputPrice: 1.25, CacheMinTokens: 2048},
+	{ID: "claude-2.1", Name: "Claude 2.1", ContextWindow: 200000, MaxOutputTokens: 4096, InputPrice: 8, OutputPrice: 24},
+	{ID: "claude-2.0", Name: "Claude 2.0", ContextWindow: 100000, MaxOutputTokens: 4096, InputPrice: 8, OutputPrice: 24},
+	{ID: "claude-instant", Name: "Claude Instant", ContextWindow: 100000, MaxOutputTokens: 4096, InputPrice: 0.8, OutputPrice: 2.4},
+}
+
+// LookupModel returns the registry entry of a model, and false when the
+// model is not in KnownModels.
+func LookupModel(model string) (ModelInfo, bool) {
+	var found ModelInfo
+	for _, info := range KnownModels {
+		if strings.HasPrefix(model, info.ID) && len(info.ID) > len(found.ID) {
+			found = info
+		}
+	}
+	return found, found.ID != ""
+}
+
+const modelsEndpoint = "v1/models"
+
+// Model is a model available to the API key, as listed by the API.
+type Model struct {
+	Type        string    `json:"type"` // always "model"
+	Id          string    `json:"id"`
+	DisplayName string    `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type modelsPage struct {
+	Data    []Model `json:"data"`
+	HasMore bool    `json:"has_more"`
+	LastId  string  `json:"last_id"`
+}
+
+// ListModels returns every model the API lists, most recently released
+// first.
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	query := url.Values{"limit": {"100"}}
+	for {
+		var page modelsPage
+		err := c.doJSON(ctx, http.MethodGet, modelsEndpoint+"?"+query.Encode(), nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, page.Data...)
+		if !page.HasMore || page.LastId == "" {
+			return models, nil
+		}
+		query.Set("after_id", page.LastId)
+	}
+}
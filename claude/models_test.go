@@ -0,0 +1,65 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLookupModel(t *testing.T) {
+	for model, want := range map[string]string{
+		"claude-3-5-sonnet-20241022": "claude-3-5-sonnet",
+		"claude-3-5-sonnet-latest":   "claude-3-5-sonnet",
+		"claude-3-5-sonnet-20240620": "claude-3-5-sonnet-20240620",
+		"claude-3-haiku-20240307":    "claude-3-haiku",
+		"claude-instant-1.2":         "claude-instant",
+		"gpt-4":                      "",
+	} {
+		info, ok := LookupModel(model)
+		if info.ID != want || ok != (want != "") {
+			t.Errorf("LookupModel(%q) = %q, %v, expected %q", model, info.ID, ok, want)
+		}
+	}
+	if ContextWindow("claude-2.0") != 100000 || ContextWindow("unknown") != DefaultContextWindow {
+		t.Errorf("Expected context windows from the registry")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"not found"}}`))
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("after_id") == "" {
+			w.Write([]byte(`{"data":[{"type":"model","id":"claude-3-5-sonnet-20241022","display_name":"Claude 3.5 Sonnet (New)","created_at":"2024-10-22T00:00:00Z"}],"has_more":true,"first_id":"claude-3-5-sonnet-20241022","last_id":"claude-3-5-sonnet-20241022"}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"type":"model","id":"claude-3-haiku-20240307","display_name":"Claude 3 Haiku","created_at":"2024-03-07T00:00:00Z"}],"has_more":false,"first_id":"claude-3-haiku-20240307","last_id":"claude-3-haiku-20240307"}`))
+	}))
+	defer server.Close()
+	config := ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client()}
+
+	models, err := NewClientWithConfig(config).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels returned an error: %v", err)
+	}
+	if len(models) != 2 || models[0].DisplayName != "Claude 3.5 Sonnet (New)" || models[1].Id != "claude-3-haiku-20240307" || models[1].CreatedAt.Year() != 2024 {
+		t.Errorf("Expected both pages of models, got %+v", models)
+	}
+	if len(queries) != 2 || queries[1] != "after_id=claude-3-5-sonnet-20241022&limit=100" {
+		t.Errorf("Expected the second page to be asked after the first, got %v", queries)
+	}
+
+	config.BaseURL = server.URL + "/missing/"
+	_, err = NewClientWithConfig(config).ListModels(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != ErrorTypeNotFound {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package claude
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitBucket is the state of one of the API's rate limits.
+type RateLimitBucket struct {
+	Limit     int64     // allowed per period, 0 when the header is missing
+	Remaining int64     // left until Reset
+	Reset     time.Time // when Remaining is back to Limit
+}
+
+// RateLimit holds the anthropic-ratelimit-* headers of a response, see
+// https://docs.anthropic.com/en/api/rate-limits#response-headers
+type RateLimit struct {
+	Requests     RateLimitBucket
+	Tokens       RateLimitBucket // the most restrictive of the token limits
+	InputTokens  RateLimitBucket
+	OutputTokens RateLimitBucket
+}
+
+// ParseRateLimit reads the rate limit headers of a response, nil when there
+// are none.
+func ParseRateLimit(header http.Header) *RateLimit {
+	var rl RateLimit
+	found := false
+	for name, bucket := range map[string]*RateLimitBucket{
+		"requests":      &rl.Requests,
+		"tokens":        &rl.Tokens,
+		"input-tokens":  &rl.InputTokens,
+		"output-tokens": &rl.OutputTokens,
+	} {
+		prefix := "Anthropic-Ratelimit-" + name + "-"
+		limit, err := strconv.ParseInt(header.Get(prefix+"Limit"), 10, 64)
+		if err != nil {
+			continue
+		}
+		found = true
+		bucket.Limit = limit
+		bucket.Remaining, _ = strconv.ParseInt(header.Get(prefix+"Remaining"), 10, 64)
+		bucket.Reset, _ = time.Parse(time.RFC3339, header.Get(prefix+"Reset"))
+	}
+	if !found {
+		return nil
+	}
+	return &rl
+}
+
+// Limiter paces the requests of one or more clients so they stay under the
+// API's rate limits instead of being answered with 429. It spreads requests
+// and estimated input tokens evenly over each minute, and holds requests back
+// while the latest response says a limit is used up. A Limiter is safe for
+// concurrent use, share one between the clients of a workload.
+type Limiter struct {
+	mu       sync.Mutex
+	requests tokenBucket
+	tokens   tokenBucket
+	server   *RateLimit // of the latest response
+}
+
+// NewLimiter returns a limiter allowing requestsPerMinute requests and
+// inputTokensPerMinute estimated input tokens a minute, 0 for no limit. Even
+// with no limit, requests wait for the limits reported by the API.
+func NewLimiter(requestsPerMinute, inputTokensPerMinute int) *Limiter {
+	return &Limiter{
+		requests: newTokenBucket(requestsPerMinute),
+		tokens:   newTokenBucket(inputTokensPerMinute),
+	}
+}
+
+// Wait blocks until a request of the given estimated input tokens may be
+// sent, or the context is done.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	delay := l.reserve(time.Now(), tokens)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Update records the rate limits reported by a response.
+func (l *Limiter) Update(rl *RateLimit) {
+	if rl == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.server = rl
+}
+
+// reserve takes a request and its tokens from the limits and returns how
+// long to wait before sending it.
+func (l *Limiter) reserve(now time.Time, tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delay := max(l.requests.take(now, 1), l.tokens.take(now, float64(tokens)))
+	if l.server == nil {
+		return delay
+	}
+	input := &l.server.InputTokens
+	if input.Limit == 0 {
+		input = &l.server.Tokens
+	}
+	for _, b := range []struct {
+		bucket *RateLimitBucket
+		need   int64
+	}{
+		{&l.server.Requests, 1},
+		{input, int64(tokens)},
+		{&l.server.OutputTokens, 1},
+	} {
+		if b.bucket.Limit == 0 || !now.Before(b.bucket.Reset) {
+			continue
+		}
+		if b.bucket.Remaining < b.need {
+			delay = max(delay, b.bucket.Reset.Sub(now))
+		}
+		// Later requests count this one before its response updates
+		// the limits.
+		b.bucket.Remaining -= b.need
+	}
+	return delay
+}
+
+// tokenBucket refills at a steady rate up to a minute's worth. Taking more
+// than is left puts it in debt, which later takers wait out.
+type tokenBucket struct {
+	perSecond float64
+	capacity  float64
+	level     float64
+	last      time.Time
+}
+
+func newTokenBucket(perMinute int) tokenBucket {
+	return tokenBucket{
+		perSecond: float64(perMinute) / 60,
+		capacity:  float64(perMinute),
+		level:     float64(perMinute),
+	}
+}
+
+// take removes n from the bucket and returns how long until it is no longer
+// in debt. A take larger than the capacity counts as the capacity.
+func (b *tokenBucket) take(now time.Time, n float64) time.Duration {
+	if b.capacity <= 0 {
+		return 0
+	}
+	if !b.last.IsZero() {
+		b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSecond)
+	}
+	b.last = now
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.perSecond * float64(time.Second))
+}
+
+// limitTransport hands the rate limits of every response, retried ones
+// included, to a limiter.
+type limitTransport struct {
+	next    http.RoundTripper
+	limiter *Limiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.limiter.Update(ParseRateLimit(resp.Header))
+	}
+	return resp, err
+}
+
+// waitLimiter waits for the configured limiter, if any, to allow body.
+func (c *Client) waitLimiter(ctx context.Context, body RequestBody) error {
+	if c.config.Limiter == nil {
+		return nil
+	}
+	return c.config.Limiter.Wait(ctx, EstimateRequestTokens(body))
+}
@@ -0,0 +1,76 @@
+package claude
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	if ParseRateLimit(http.Header{}) != nil {
+		t.Errorf("Expected no rate limit without headers")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "49")
+		w.Header().Set("anthropic-ratelimit-requests-reset", "2024-07-01T12:00:01Z")
+		w.Header().Set("anthropic-ratelimit-input-tokens-limit", "40000")
+		w.Header().Set("anthropic-ratelimit-input-tokens-remaining", "0")
+		w.Header().Set("anthropic-ratelimit-input-tokens-reset", "2024-07-01T12:00:30Z")
+		replyMessage(w)
+	}))
+	defer server.Close()
+	limiter := NewLimiter(0, 0)
+	client := NewClientWithConfig(ClientConfig{BaseURL: server.URL + "/", Endpoint: "v1/messages", HTTPCLient: server.Client(), Limiter: limiter})
+
+	response, err := client.CreateMessages(context.Background(), RequestBody{})
+	if err != nil {
+		t.Fatalf("CreateMessages returned an error: %v", err)
+	}
+	rl := response.RateLimit
+	want := RateLimitBucket{Limit: 50, Remaining: 49, Reset: time.Date(2024, 7, 1, 12, 0, 1, 0, time.UTC)}
+	if rl == nil || rl.Requests != want || rl.InputTokens.Remaining != 0 || rl.InputTokens.Limit != 40000 || rl.OutputTokens.Limit != 0 {
+		t.Fatalf("Unexpected rate limit %+v", rl)
+	}
+
+	// The limiter waits for the input tokens to be reset.
+	delay := limiter.reserve(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), 10)
+	if delay != 30*time.Second {
+		t.Errorf("Expected to wait for the input tokens reset, got %s", delay)
+	}
+	if delay := limiter.reserve(time.Date(2024, 7, 1, 12, 0, 31, 0, time.UTC), 10); delay != 0 {
+		t.Errorf("Expected no wait after the reset, got %s", delay)
+	}
+}
+
+func TestLimiterPacing(t *testing.T) {
+	limiter := NewLimiter(60, 6000)
+	now := time.Now()
+
+	// The first minute's worth goes through at once, then one request a
+	// second.
+	for i := 0; i < 60; i++ {
+		if delay := limiter.reserve(now, 10); delay != 0 {
+			t.Fatalf("Expected request %d to go through, waited %s", i, delay)
+		}
+	}
+	if delay := limiter.reserve(now, 10); delay != time.Second {
+		t.Errorf("Expected the 61st request to wait a second, got %s", delay)
+	}
+
+	// Large requests wait for their tokens.
+	limiter = NewLimiter(0, 6000)
+	limiter.reserve(now, 6000)
+	if delay := limiter.reserve(now.Add(10*time.Second), 3000); delay != 20*time.Second {
+		t.Errorf("Expected to wait for 2000 more tokens, got %s", delay)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, 6000); err != context.Canceled {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}
@@ -1,16 +1,94 @@
 package claude
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type RequestBody struct {
 	Model         string                 `json:"model"`
 	Messages      []RequestMessages      `json:"messages"`
-	System        string                 `json:"system"` // optional
+	System        string                 `json:"system,omitempty"` // optional
+	SystemBlocks  []RequestContentBlock  `json:"-"`                // optional, sent instead of System, e.g. to cache it
 	MaxTokens     int                    `json:"max_tokens"`
-	MetaData      map[string]interface{} `json:"metadata"`       // optional
-	StopSequences []string               `json:"stop_sequences"` // optional
-	Stream        bool                   `json:"stream"`         // optional
-	Temparature   float64                `json:"temperature"`    // optional
-	TopP          float64                `json:"top_p"`          // optional
-	TopK          float64                `json:"top_k"`
+	MetaData      map[string]interface{} `json:"metadata,omitempty"`       // optional
+	StopSequences []string               `json:"stop_sequences,omitempty"` // optional
+	Stream        bool                   `json:"stream"`                   // optional
+	Temparature   *float64               `json:"temperature,omitempty"`    // optional, nil leaves the API default
+	TopP          *float64               `json:"top_p,omitempty"`          // optional, nil leaves the API default
+	TopK          *float64               `json:"top_k,omitempty"`          // optional, nil leaves the API default
+	Tools         []Tool                 `json:"tools,omitempty"`          // optional
+	ToolChoice    *ToolChoice            `json:"tool_choice,omitempty"`    // optional
+	Thinking      *ThinkingConfig        `json:"thinking,omitempty"`       // optional, enables extended thinking
+}
+
+const ThinkingTypeEnabled = "enabled"
+
+// MinThinkingBudget is the fewest tokens extended thinking may be given.
+const MinThinkingBudget = 1024
+
+// ThinkingConfig enables extended thinking: the model reasons in thinking
+// blocks before answering, using up to BudgetTokens of max_tokens for it.
+type ThinkingConfig struct {
+	Type         string `json:"type"` // always "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// NewThinkingConfig returns the config enabling extended thinking with a
+// budget of tokens.
+func NewThinkingConfig(budgetTokens int) *ThinkingConfig {
+	return &ThinkingConfig{Type: ThinkingTypeEnabled, BudgetTokens: budgetTokens}
+}
+
+// MarshalJSON sends the system prompt as SystemBlocks when there are any, so
+// they can carry cache_control, and as the System string otherwise.
+func (r RequestBody) MarshalJSON() ([]byte, error) {
+	type plain RequestBody
+	return json.Marshal(struct {
+		plain
+		System interface{} `json:"system,omitempty"`
+	}{plain(r), r.system()})
+}
+
+// system is the system prompt as it is sent, nil when there is none.
+func (r RequestBody) system() interface{} {
+	if len(r.SystemBlocks) > 0 {
+		return r.SystemBlocks
+	}
+	if r.System != "" {
+		return r.System
+	}
+	return nil
+}
+
+// UnmarshalJSON reads the system prompt as either a string, into System, or an
+// array of blocks, into SystemBlocks. Fields missing from the JSON are left
+// as they are, so a request can be decoded over defaults.
+func (r *RequestBody) UnmarshalJSON(data []byte) error {
+	type plain RequestBody
+	body := struct {
+		*plain
+		System json.RawMessage `json:"system"`
+	}{plain: (*plain)(r)}
+	err := json.Unmarshal(data, &body)
+	if err != nil {
+		return err
+	}
+	if len(body.System) == 0 || string(body.System) == "null" {
+		return nil
+	}
+	var system string
+	if json.Unmarshal(body.System, &system) == nil {
+		r.System, r.SystemBlocks = system, nil
+		return nil
+	}
+	var blocks []RequestContentBlock
+	err = json.Unmarshal(body.System, &blocks)
+	if err != nil {
+		return fmt.Errorf("system must be a string or an array of content blocks: %w", err)
+	}
+	r.System, r.SystemBlocks = "", blocks
+	return nil
 }
 
 type RequestMessages struct {
@@ -18,11 +96,47 @@ type RequestMessages struct {
 	ContentRaw      interface{}              `json:"content"`
 	Content         string                   `json:"-"`
 	ContentTypeText []RequestContentTypeText `json:"-"`
-	// add option for images
+	ContentBlocks   []RequestContentBlock    `json:"-"` // mixed blocks, e.g. text with images, documents or tool results
+}
+
+// UnmarshalJSON reads the content as either a string, into Content, or an
+// array of blocks, into ContentBlocks, so decoded messages are handled like
+// built ones. ContentRaw keeps the content as it was read.
+func (m *RequestMessages) UnmarshalJSON(data []byte) error {
+	var message struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return err
+	}
+	*m = RequestMessages{Role: message.Role}
+	if len(message.Content) == 0 || string(message.Content) == "null" {
+		return nil
+	}
+	var content string
+	if json.Unmarshal(message.Content, &content) == nil {
+		m.Content, m.ContentRaw = content, content
+		return nil
+	}
+	err = json.Unmarshal(message.Content, &m.ContentBlocks)
+	if err != nil {
+		return fmt.Errorf("content must be a string or an array of content blocks: %w", err)
+	}
+	m.ContentRaw = message.Content
+	return nil
 }
 
 const (
-	RequestContentTypeTextType = "text"
+	RequestContentTypeTextType       = "text"
+	RequestContentTypeToolUseType    = "tool_use"
+	RequestContentTypeToolResultType = "tool_result"
+	RequestContentTypeImageType      = "image"
+	RequestContentTypeDocumentType   = "document"
+
+	RequestContentTypeThinkingType         = "thinking"
+	RequestContentTypeRedactedThinkingType = "redacted_thinking"
 )
 
 type RequestContentTypeText struct {
@@ -30,9 +144,88 @@ type RequestContentTypeText struct {
 	Text string `json:"text"`
 }
 
+// RequestContentBlock is a single block of a message's content. Which fields
+// are used depends on Type, the rest are left empty and omitted from the JSON.
+type RequestContentBlock struct {
+	Type string `json:"type"` // "text", "image", "document", "tool_use", "tool_result", "thinking" or "redacted_thinking"
+	Text string `json:"text,omitempty"`
+
+	// image and document
+	Source *RequestContentSource `json:"source,omitempty"`
+	Title  string                `json:"title,omitempty"` // document only
+
+	// tool_use
+	Id    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseId string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// thinking and redacted_thinking, sent back as the model wrote them
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // redacted_thinking only, encrypted
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"` // ends a prefix to cache
+}
+
+const RequestContentSourceTypeBase64 = "base64"
+
+// RequestContentSource holds the data of an image or document block.
+type RequestContentSource struct {
+	Type      string `json:"type"`       // always "base64"
+	MediaType string `json:"media_type"` // e.g. "image/png" or "application/pdf"
+	Data      string `json:"data"`       // base64 encoded file contents
+}
+
+// Tool describes a tool the model may call. InputSchema is a JSON schema
+// object describing the input the tool expects.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"` // ends a prefix to cache
+}
+
+const (
+	ToolChoiceAuto = "auto"
+	ToolChoiceAny  = "any"
+	ToolChoiceTool = "tool"
+	ToolChoiceNone = "none"
+)
+
+type ToolChoice struct {
+	Type                   string `json:"type"`           // "auto", "any", "tool" or "none"
+	Name                   string `json:"name,omitempty"` // required when Type is "tool"
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
 const (
 	MessageRoleUser      = "user"
 	MessageRoleAssistant = "assistant"
 )
 
+// NewTextBlock returns a text content block.
+func NewTextBlock(text string) RequestContentBlock {
+	return RequestContentBlock{
+		Type: RequestContentTypeTextType,
+		Text: text,
+	}
+}
+
+// NewToolResultBlock returns the block answering the tool_use block with the
+// given id. Set isError when the tool failed and content describes the error.
+func NewToolResultBlock(toolUseId, content string, isError bool) RequestContentBlock {
+	return RequestContentBlock{
+		Type:      RequestContentTypeToolResultType,
+		ToolUseId: toolUseId,
+		Content:   content,
+		IsError:   isError,
+	}
+}
+
 // Inspirational struct https://github.com/potproject/claude-sdk-go/blob/main/request.go
@@ -1,26 +1,162 @@
 package claude
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type ResponseBody struct {
 	Id           string            `json:"id"`
 	Type         string            `json:"type"` // always "message"
 	Role         string            `json:"role"` // always "assistant"
 	Content      []ResponseContent `json:"content"`
 	Model        string            `json:"model"`
-	StopReason   string            `json:"stop_reason"` // "end_turn" or "max_tokens", "stop_sequence", null
+	StopReason   string            `json:"stop_reason"` // "end_turn" or "max_tokens", "stop_sequence", "tool_use", null
 	StopSequence string            `json:"stop_sequence"`
-	Usage        struct {
-		InputTokens  int64 `json:"input_tokens"`
-		OutputTokens int64 `json:"output_tokens"`
-	} `json:"usage"`
+	Usage        Usage             `json:"usage"`
+
+	RateLimit *RateLimit `json:"-"` // from the response headers, nil when there were none
+}
+
+// Usage is the number of tokens a request was billed for. InputTokens leaves
+// out the tokens written to and read from the prompt cache.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
 }
 
+const (
+	ResponseContentTypeText             = "text"
+	ResponseContentTypeToolUse          = "tool_use"
+	ResponseContentTypeThinking         = "thinking"
+	ResponseContentTypeRedactedThinking = "redacted_thinking"
+)
+
+const StopReasonToolUse = "tool_use"
+
 type ResponseContent struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
+
+	// tool_use
+	Id    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// thinking and redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // redacted_thinking only, encrypted
 }
 
 type ResponseError struct {
+	Type  string `json:"type"` // always "error"
 	Error struct {
+		Type    string `json:"type"`
 		Message string `json:"message"`
 	} `json:"error"`
 }
+
+// ToolCall is a tool_use block requested by the model.
+type ToolCall struct {
+	Id    string
+	Name  string
+	Input json.RawMessage
+}
+
+// DecodeInput unmarshals the tool input into v.
+func (t ToolCall) DecodeInput(v interface{}) error {
+	return json.Unmarshal(t.Input, v)
+}
+
+// Text returns the concatenated text of all text blocks in the response.
+func (r *ResponseBody) Text() string {
+	var sb strings.Builder
+	for _, c := range r.Content {
+		if c.Type == ResponseContentTypeText {
+			sb.WriteString(c.Text)
+		}
+	}
+	return sb.String()
+}
+
+// ToolCalls returns the tool_use blocks of the response in order.
+func (r *ResponseBody) ToolCalls() []ToolCall {
+	var calls []ToolCall
+	for _, c := range r.Content {
+		if c.Type == ResponseContentTypeToolUse {
+			calls = append(calls, ToolCall{
+				Id:    c.Id,
+				Name:  c.Name,
+				Input: c.Input,
+			})
+		}
+	}
+	return calls
+}
+
+// ThinkingBlocks returns the thinking and redacted_thinking blocks of the
+// response in order, as they are sent back in later requests.
+func (r *ResponseBody) ThinkingBlocks() []RequestContentBlock {
+	var blocks []RequestContentBlock
+	for _, c := range r.Content {
+		if c.Type == ResponseContentTypeThinking || c.Type == ResponseContentTypeRedactedThinking {
+			blocks = append(blocks, thinkingBlock(c))
+		}
+	}
+	return blocks
+}
+
+// thinkingBlock is the request block sending back a thinking or
+// redacted_thinking block.
+func thinkingBlock(c ResponseContent) RequestContentBlock {
+	if c.Type == ResponseContentTypeRedactedThinking {
+		return RequestContentBlock{Type: RequestContentTypeRedactedThinkingType, Data: c.Data}
+	}
+	return RequestContentBlock{Type: RequestContentTypeThinkingType, Thinking: c.Thinking, Signature: c.Signature}
+}
+
+// Thinking returns the concatenated reasoning of all thinking blocks in the
+// response. Redacted thinking is left out.
+func (r *ResponseBody) Thinking() string {
+	var sb strings.Builder
+	for _, c := range r.Content {
+		if c.Type == ResponseContentTypeThinking {
+			sb.WriteString(c.Thinking)
+		}
+	}
+	return sb.String()
+}
+
+// ToRequestMessage converts the response into an assistant message that can be
+// appended to the history, keeping tool_use blocks so the matching tool_result
+// blocks can be sent in the next user message, and thinking blocks, which
+// must be sent back unchanged with them.
+func (r *ResponseBody) ToRequestMessage() RequestMessages {
+	blocks := make([]RequestContentBlock, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch c.Type {
+		case ResponseContentTypeThinking, ResponseContentTypeRedactedThinking:
+			blocks = append(blocks, thinkingBlock(c))
+		case ResponseContentTypeText:
+			blocks = append(blocks, NewTextBlock(c.Text))
+		case ResponseContentTypeToolUse:
+			input := c.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, RequestContentBlock{
+				Type:  RequestContentTypeToolUseType,
+				Id:    c.Id,
+				Name:  c.Name,
+				Input: input,
+			})
+		}
+	}
+	return RequestMessages{
+		Role:          MessageRoleAssistant,
+		ContentBlocks: blocks,
+	}
+}
@@ -0,0 +1,147 @@
+package claude
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Requests answered with 429 (rate limited), 529 (overloaded) or another 5xx
+// status are sent again after a growing delay. Retries happen before the
+// response is handed to the caller, so a stream is only retried while none of
+// its events has been read, a stream failing halfway is never sent again.
+
+// RetryPolicy configures how failed requests are retried. The zero value
+// doesn't retry.
+type RetryPolicy struct {
+	MaxRetries   int           // retries after the first attempt, 0 to never retry
+	InitialDelay time.Duration // wait before the first retry, doubled after every retry
+	MaxDelay     time.Duration // longest single wait, a longer retry-after gives up
+	MaxElapsed   time.Duration // time after which no retry is started, 0 for no limit
+	Jitter       float64       // share of every delay, 0 to 1, taken off at random
+
+	// OnRetry, when set, is called before waiting for a retry, e.g. to tell
+	// the user why the reply is late.
+	OnRetry func(attempt int, status int, wait time.Duration)
+}
+
+// DefaultRetryPolicy returns the policy of clients created by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:   4,
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     30 * time.Second,
+		MaxElapsed:   2 * time.Minute,
+		Jitter:       0.25,
+	}
+}
+
+// retryable tells whether a response status is worth sending the request
+// again for.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == 529 || status >= 500
+}
+
+// rejected tells whether a response status means the API turned the request
+// away without acting on it, so sending it again cannot do anything twice.
+func rejected(status int) bool {
+	return status == http.StatusTooManyRequests || status == 529
+}
+
+type onlyRetryRejectedKey struct{}
+
+// onlyRetryRejected marks the requests sent with ctx as not safe to send
+// twice, e.g. creating a batch. They are retried when the API rejected them,
+// never after a 5xx that may have come once the request was accepted.
+func onlyRetryRejected(ctx context.Context) context.Context {
+	return context.WithValue(ctx, onlyRetryRejectedKey{}, true)
+}
+
+// delay returns how long to wait before retry number attempt, counted from 1,
+// and false when the policy gives up instead.
+func (p RetryPolicy) delay(attempt int, resp *http.Response, elapsed time.Duration) (time.Duration, bool) {
+	if attempt > p.MaxRetries {
+		return 0, false
+	}
+	wait, ok := retryAfter(resp)
+	if !ok {
+		wait = p.InitialDelay << (attempt - 1)
+		if p.MaxDelay > 0 && (wait > p.MaxDelay || wait <= 0) {
+			wait = p.MaxDelay
+		}
+		if p.Jitter > 0 {
+			wait -= time.Duration(rand.Float64() * p.Jitter * float64(wait))
+		}
+	} else if p.MaxDelay > 0 && wait > p.MaxDelay {
+		return 0, false
+	}
+	if p.MaxElapsed > 0 && elapsed+wait > p.MaxElapsed {
+		return 0, false
+	}
+	return wait, true
+}
+
+// retryAfter reads the wait asked for by the retry-after-ms or retry-after
+// headers of a response.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if ms, err := strconv.ParseFloat(resp.Header.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// retryTransport sends requests through next, retrying them as policy says.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || !retryable(resp.StatusCode) || req.GetBody == nil {
+			return resp, err
+		}
+		if req.Context().Value(onlyRetryRejectedKey{}) != nil && !rejected(resp.StatusCode) {
+			return resp, nil
+		}
+		wait, ok := t.policy.delay(attempt, resp, time.Since(start))
+		if !ok {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if t.policy.OnRetry != nil {
+			t.policy.OnRetry(attempt, resp.StatusCode, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			body.Close()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+}
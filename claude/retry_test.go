@@ -0,0 +1,189 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingServer answers with the given statuses in turn, then with reply.
+func failingServer(t *testing.T, statuses []int, header http.Header, reply func(w http.ResponseWriter)) (*httptest.Server, *[]string) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statuses[len(bodies)-1])
+			w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+			return
+		}
+		reply(w)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func replyMessage(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`))
+}
+
+func retryClient(server *httptest.Server, policy RetryPolicy) *Client {
+	return NewClientWithConfig(ClientConfig{
+		BaseURL:    server.URL + "/",
+		Endpoint:   "v1/messages",
+		HTTPCLient: server.Client(),
+		Retry:      policy,
+	})
+}
+
+func TestRetry(t *testing.T) {
+	server, bodies := failingServer(t, []int{529, 429, 500}, nil, replyMessage)
+	var retried []int
+	client := retryClient(server, RetryPolicy{
+		MaxRetries:   3,
+		InitialDelay: time.Millisecond,
+		Jitter:       0.5,
+		OnRetry: func(attempt int, status int, wait time.Duration) {
+			retried = append(retried, status)
+		},
+	})
+
+	response, err := client.CreateMessages(context.Background(), RequestBody{Model: "m", Messages: []RequestMessages{{Role: MessageRoleUser, Content: "hello"}}})
+	if err != nil || response.Text() != "hi" {
+		t.Fatalf("Expected the request to succeed on the fourth attempt, got %v", err)
+	}
+	if len(*bodies) != 4 || (*bodies)[3] != (*bodies)[0] || !strings.Contains((*bodies)[3], "hello") {
+		t.Errorf("Expected the same body sent 4 times, got %q", *bodies)
+	}
+	if len(retried) != 3 || retried[0] != 529 || retried[2] != 500 {
+		t.Errorf("Expected OnRetry for every failure, got %v", retried)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond}
+
+	server, bodies := failingServer(t, []int{529, 529, 529}, nil, replyMessage)
+	_, err := retryClient(server, policy).CreateMessages(context.Background(), RequestBody{})
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") || len(*bodies) != 3 {
+		t.Errorf("Expected the last failure after 3 attempts, got %v after %d", err, len(*bodies))
+	}
+
+	server, bodies = failingServer(t, []int{400}, nil, replyMessage)
+	_, err = retryClient(server, policy).CreateMessages(context.Background(), RequestBody{})
+	if err == nil || len(*bodies) != 1 {
+		t.Errorf("Expected a 400 not to be retried, got %v after %d attempts", err, len(*bodies))
+	}
+
+	// A retry-after longer than MaxDelay isn't waited for.
+	policy.MaxDelay = time.Second
+	server, bodies = failingServer(t, []int{429}, http.Header{"Retry-After": {"60"}}, replyMessage)
+	_, err = retryClient(server, policy).CreateMessages(context.Background(), RequestBody{})
+	if err == nil || len(*bodies) != 1 {
+		t.Errorf("Expected a long retry-after to give up, got %v after %d attempts", err, len(*bodies))
+	}
+
+	// Retries stop with the request's context.
+	policy = RetryPolicy{MaxRetries: 2, InitialDelay: time.Minute}
+	server, _ = failingServer(t, []int{503}, nil, replyMessage)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = retryClient(server, policy).CreateMessages(ctx, RequestBody{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error while waiting, got %v", err)
+	}
+}
+
+func TestRetryCreateBatch(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, InitialDelay: time.Millisecond}
+	replyBatch := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msgbatch_1","processing_status":"in_progress"}`))
+	}
+	requests := []BatchRequest{{CustomID: "q1", Params: RequestBody{Model: "claude-3-haiku-20240307", MaxTokens: 10, Messages: []RequestMessages{{Role: MessageRoleUser, Content: "hi"}}}}}
+
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		created  bool
+	}{
+		{"rejected", []int{429, 529}, 3, true},
+		{"server error", []int{500}, 1, false},
+		{"rejected then server error", []int{529, 503}, 2, false},
+	}
+	for _, tt := range tests {
+		server, bodies := failingServer(t, tt.statuses, nil, replyBatch)
+		batch, err := retryClient(server, policy).CreateBatch(context.Background(), requests)
+		if len(*bodies) != tt.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", tt.name, tt.attempts, len(*bodies))
+		}
+		if created := err == nil && batch.Id == "msgbatch_1"; created != tt.created {
+			t.Errorf("%s: expected the batch created to be %v, got %+v, %v", tt.name, tt.created, batch, err)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, InitialDelay: time.Second, MaxDelay: 3 * time.Second, MaxElapsed: 10 * time.Second}
+	resp := &http.Response{Header: http.Header{}}
+
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		wait, ok := policy.delay(attempt+1, resp, 0)
+		if !ok || wait != want {
+			t.Errorf("Expected retry %d after %s, got %s, %v", attempt+1, want, wait, ok)
+		}
+	}
+	if _, ok := policy.delay(2, resp, 9*time.Second); ok {
+		t.Errorf("Expected no retry past MaxElapsed")
+	}
+	if _, ok := policy.delay(6, resp, 0); ok {
+		t.Errorf("Expected no retry past MaxRetries")
+	}
+
+	resp.Header.Set("Retry-After", "2")
+	if wait, ok := policy.delay(1, resp, 0); !ok || wait != 2*time.Second {
+		t.Errorf("Expected retry-after to be honored, got %s, %v", wait, ok)
+	}
+	resp.Header.Set("Retry-After-Ms", "250")
+	if wait, ok := policy.delay(1, resp, 0); !ok || wait != 250*time.Millisecond {
+		t.Errorf("Expected retry-after-ms to take precedence, got %s, %v", wait, ok)
+	}
+}
+
+func TestRetryStream(t *testing.T) {
+	server, bodies := failingServer(t, []int{529}, http.Header{"Retry-After": {"0"}}, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":3}}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hi\"}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	})
+	client := retryClient(server, RetryPolicy{MaxRetries: 1, InitialDelay: time.Millisecond})
+
+	stream, err := client.CreateMessagesStream(context.Background(), RequestBody{})
+	if err != nil {
+		t.Fatalf("CreateMessagesStream returned an error: %v", err)
+	}
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv returned an error: %v", err)
+		}
+	}
+	if stream.Message().Text() != "hi" || len(*bodies) != 2 {
+		t.Errorf("Expected the stream to be read once after a retry, got %q after %d attempts", stream.Message().Text(), len(*bodies))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/tmaxmax/go-sse"
 )
@@ -23,15 +24,31 @@ const (
 	MessagesStreamResponseTypeError             = "error"
 )
 
+const (
+	StreamDeltaTypeText      = "text_delta"
+	StreamDeltaTypeInputJSON = "input_json_delta"
+	StreamDeltaTypeThinking  = "thinking_delta"
+	StreamDeltaTypeSignature = "signature_delta" // ends a thinking block
+)
+
 type CreateMessagesStream struct {
 	Connection                 *sse.Connection
 	Unsubscribe                func()
 	Event                      chan sse.Event
 	Error                      chan error
 	ResponseBodyMessagesStream ResponseBodyStream
+	RateLimit                  *RateLimit // of the response, set before the first event
+
+	// blocks holds every content block received so far, by index, with text
+	// and tool inputs accumulated from their deltas.
+	blocks      []ResponseContent
+	partialJSON map[int64]*strings.Builder
+
+	requestID string // of the response, set before the first event
 }
 
 type ResponseBodyStream struct {
+	Event        string                   `json:"-"` // type of the event that produced this value
 	Id           string                   `json:"id"`
 	Type         string                   `json:"type"` // always "message"
 	Role         string                   `json:"role"` // always "assistant"
@@ -39,15 +56,21 @@ type ResponseBodyStream struct {
 	Model        string                   `json:"model"`
 	StopReason   string                   `json:"stop_reason"` // "end_turn" or "max_tokens", "stop_sequence", null
 	StopSequence string                   `json:"stop_sequence"`
-	Usage        struct {
-		InputTokens  int64 `json:"input_tokens"`
-		OutputTokens int64 `json:"output_tokens"`
-	} `json:"usage"`
+	Usage        Usage                    `json:"usage"`
 }
 
+// ResponseMessagesStream is the part of a content block carried by a single
+// event. Text, Thinking and PartialJSON hold only the delta, Input is set
+// once the tool_use block is complete.
 type ResponseMessagesStream struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string          `json:"type"`
+	Text        string          `json:"text"`
+	Thinking    string          `json:"thinking,omitempty"`
+	Index       int64           `json:"index"`
+	Id          string          `json:"id,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	PartialJSON string          `json:"partial_json,omitempty"`
+	Input       json.RawMessage `json:"input,omitempty"`
 }
 
 type ResponseMessageStartStream struct {
@@ -55,15 +78,29 @@ type ResponseMessageStartStream struct {
 	Message ResponseBodyStream `json:"message"`
 }
 
+type ResponseBlockStartStream struct {
+	Type         string          `json:"type"`
+	Index        int64           `json:"index"`
+	ContentBlock ResponseContent `json:"content_block"`
+}
+
 type ResponseBlockDeltaStream struct {
 	Type  string `json:"type"`
 	Index int64  `json:"index"`
 	Delta struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
 	} `json:"delta"`
 }
 
+type ResponseBlockStopStream struct {
+	Type  string `json:"type"`
+	Index int64  `json:"index"`
+}
+
 type ResponseMessageDeltaStream struct {
 	Type  string `json:"type"`
 	Delta struct {
@@ -76,6 +113,14 @@ type ResponseMessageDeltaStream struct {
 }
 
 func (c *Client) CreateMessagesStream(ctx context.Context, body RequestBody) (*CreateMessagesStream, error) {
+	err := c.checkGuard(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	err = c.waitLimiter(ctx, body)
+	if err != nil {
+		return nil, err
+	}
 	reqURL := c.config.BaseURL + c.config.Endpoint
 	body.Stream = true
 	reqHeaders := map[string]string{
@@ -92,8 +137,25 @@ func (c *Client) CreateMessagesStream(ctx context.Context, body RequestBody) (*C
 		return nil, err
 	}
 
+	stream := &CreateMessagesStream{}
 	client := sse.Client{
-		HTTPClient: c.config.HTTPCLient,
+		HTTPClient: c.httpClient(),
+		ResponseValidator: func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK {
+				return newAPIError(resp)
+			}
+			// An error page or JSON body would otherwise read as an empty
+			// stream.
+			err := sse.DefaultValidator(resp)
+			if err != nil {
+				return err
+			}
+			stream.requestID = resp.Header.Get("Request-Id")
+			stream.RateLimit = ParseRateLimit(resp.Header)
+			return nil
+		},
+		// Failed requests are retried by the client before any event is
+		// read, reconnecting afterwards would repeat the reply.
 		Backoff: sse.Backoff{
 			MaxRetries: -1,
 		},
@@ -112,26 +174,26 @@ func (c *Client) CreateMessagesStream(ctx context.Context, body RequestBody) (*C
 	connectionError := make(chan error)
 
 	unsubscribe := conn.SubscribeToAll(func(e sse.Event) {
-		if e.Type == MessagesStreamResponseTypePing ||
-			e.Type == MessagesStreamResponseTypeContentBlockStart ||
-			e.Type == MessagesStreamResponseTypeContentBlockStop {
+		if e.Type == MessagesStreamResponseTypePing {
 			return
 		}
 		chanEvent <- e
 	})
 	go func() {
 		err := conn.Connect()
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			err = apiErr
+		}
 		if !errors.Is(err, io.EOF) && err != nil {
 			connectionError <- err
 		}
 	}()
-	return &CreateMessagesStream{
-		Connection:                 conn,
-		Unsubscribe:                unsubscribe,
-		Event:                      chanEvent,
-		Error:                      connectionError,
-		ResponseBodyMessagesStream: ResponseBodyStream{},
-	}, nil
+	stream.Connection = conn
+	stream.Unsubscribe = unsubscribe
+	stream.Event = chanEvent
+	stream.Error = connectionError
+	return stream, nil
 }
 
 func (c *CreateMessagesStream) Close() {
@@ -140,9 +202,29 @@ func (c *CreateMessagesStream) Close() {
 	c.Unsubscribe()
 }
 
+// Message returns the message assembled from every event received so far. Once
+// Recv has returned io.EOF it is the complete response, including tool_use
+// blocks with their full input.
+func (c *CreateMessagesStream) Message() *ResponseBody {
+	r := c.ResponseBodyMessagesStream
+	res := &ResponseBody{
+		Id:           r.Id,
+		Type:         r.Type,
+		Role:         r.Role,
+		Content:      make([]ResponseContent, len(c.blocks)),
+		Model:        r.Model,
+		StopReason:   r.StopReason,
+		StopSequence: r.StopSequence,
+		Usage:        r.Usage,
+	}
+	copy(res.Content, c.blocks)
+	return res
+}
+
 func (c *CreateMessagesStream) Recv() (ResponseBodyStream, error) {
 	select {
 	case e := <-c.Event:
+		c.ResponseBodyMessagesStream.Event = e.Type
 		switch e.Type {
 		case MessagesStreamResponseTypeMessageStart:
 			d := []byte(e.Data)
@@ -152,6 +234,8 @@ func (c *CreateMessagesStream) Recv() (ResponseBodyStream, error) {
 				return ResponseBodyStream{}, err
 			}
 			c.ResponseBodyMessagesStream = r.Message
+			c.ResponseBodyMessagesStream.Event = e.Type
+			c.blocks = nil
 			c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
 				{
 					Type: "text",
@@ -159,6 +243,32 @@ func (c *CreateMessagesStream) Recv() (ResponseBodyStream, error) {
 				},
 			}
 			return c.ResponseBodyMessagesStream, nil
+		case MessagesStreamResponseTypeContentBlockStart:
+			d := []byte(e.Data)
+			var r ResponseBlockStartStream
+			err := json.Unmarshal(d, &r)
+			if err != nil {
+				return ResponseBodyStream{}, err
+			}
+			block := r.ContentBlock
+			if block.Type == ResponseContentTypeToolUse {
+				// The start event carries an empty input, the real one
+				// arrives through input_json_delta events.
+				block.Input = nil
+				c.inputBuffer(r.Index).Reset()
+			}
+			c.setBlock(r.Index, block)
+			c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
+				{
+					Type:     block.Type,
+					Text:     block.Text,
+					Thinking: block.Thinking,
+					Index:    r.Index,
+					Id:       block.Id,
+					Name:     block.Name,
+				},
+			}
+			return c.ResponseBodyMessagesStream, nil
 		case MessagesStreamResponseTypeContentBlockDelta:
 			d := []byte(e.Data)
 			var r ResponseBlockDeltaStream
@@ -166,10 +276,73 @@ func (c *CreateMessagesStream) Recv() (ResponseBodyStream, error) {
 			if err != nil {
 				return ResponseBodyStream{}, err
 			}
+			block := c.block(r.Index)
+			switch r.Delta.Type {
+			case StreamDeltaTypeInputJSON:
+				c.inputBuffer(r.Index).WriteString(r.Delta.PartialJSON)
+				c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
+					{
+						Type:        ResponseContentTypeToolUse,
+						Index:       r.Index,
+						Id:          block.Id,
+						Name:        block.Name,
+						PartialJSON: r.Delta.PartialJSON,
+					},
+				}
+			case StreamDeltaTypeThinking:
+				block.Thinking += r.Delta.Thinking
+				c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
+					{
+						Type:     ResponseContentTypeThinking,
+						Thinking: r.Delta.Thinking,
+						Index:    r.Index,
+					},
+				}
+			case StreamDeltaTypeSignature:
+				block.Signature += r.Delta.Signature
+				c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
+					{
+						Type:  ResponseContentTypeThinking,
+						Index: r.Index,
+					},
+				}
+			default:
+				if block.Type == "" {
+					block.Type = ResponseContentTypeText
+				}
+				block.Text += r.Delta.Text
+				c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
+					{
+						Type:  "text",
+						Text:  r.Delta.Text,
+						Index: r.Index,
+					},
+				}
+			}
+			return c.ResponseBodyMessagesStream, nil
+		case MessagesStreamResponseTypeContentBlockStop:
+			d := []byte(e.Data)
+			var r ResponseBlockStopStream
+			err := json.Unmarshal(d, &r)
+			if err != nil {
+				return ResponseBodyStream{}, err
+			}
+			block := c.block(r.Index)
+			if b, ok := c.partialJSON[r.Index]; ok {
+				input := b.String()
+				if input == "" {
+					input = "{}"
+				}
+				block.Input = json.RawMessage(input)
+				delete(c.partialJSON, r.Index)
+			}
 			c.ResponseBodyMessagesStream.Content = []ResponseMessagesStream{
 				{
-					Type: "text",
-					Text: r.Delta.Text,
+					Type:  block.Type,
+					Index: r.Index,
+					Id:    block.Id,
+					Name:  block.Name,
+					Input: block.Input,
 				},
 			}
 			return c.ResponseBodyMessagesStream, nil
@@ -195,16 +368,37 @@ func (c *CreateMessagesStream) Recv() (ResponseBodyStream, error) {
 		case MessagesStreamResponseTypeMessageStop:
 			return c.ResponseBodyMessagesStream, io.EOF
 		case MessagesStreamResponseTypeError:
-			d := []byte(e.Data)
-			var r ResponseError
-			err := json.Unmarshal(d, &r)
-			if err != nil {
-				return ResponseBodyStream{}, err
-			}
-			return c.ResponseBodyMessagesStream, errors.New(r.Error.Message)
+			return c.ResponseBodyMessagesStream, streamAPIError([]byte(e.Data), c.requestID)
 		}
 	case err := <-c.Error:
 		return ResponseBodyStream{}, err
 	}
 	return c.ResponseBodyMessagesStream, nil
 }
+
+// block returns the content block at index, growing the list when a delta
+// arrives for a block whose start event was not seen.
+func (c *CreateMessagesStream) block(index int64) *ResponseContent {
+	for int64(len(c.blocks)) <= index {
+		c.blocks = append(c.blocks, ResponseContent{})
+	}
+	return &c.blocks[index]
+}
+
+// inputBuffer returns the buffer accumulating the input_json_delta fragments
+// of the tool_use block at index.
+func (c *CreateMessagesStream) inputBuffer(index int64) *strings.Builder {
+	if c.partialJSON == nil {
+		c.partialJSON = map[int64]*strings.Builder{}
+	}
+	b, ok := c.partialJSON[index]
+	if !ok {
+		b = &strings.Builder{}
+		c.partialJSON[index] = b
+	}
+	return b
+}
+
+func (c *CreateMessagesStream) setBlock(index int64, block ResponseContent) {
+	*c.block(index) = block
+}
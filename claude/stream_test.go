@@ -0,0 +1,150 @@
+package claude
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestRecvAccumulatesToolUse(t *testing.T) {
+	events := []sse.Event{
+		{Type: MessagesStreamResponseTypeMessageStart, Data: `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-3-5-sonnet-20240620","usage":{"input_tokens":12,"output_tokens":1}}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStart, Data: `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStop, Data: `{"type":"content_block_stop","index":0}`},
+		{Type: MessagesStreamResponseTypeContentBlockStart, Data: `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"location\": \"San"}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" Francisco\"}"}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStop, Data: `{"type":"content_block_stop","index":1}`},
+		{Type: MessagesStreamResponseTypeMessageDelta, Data: `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":40}}`},
+		{Type: MessagesStreamResponseTypeMessageStop, Data: `{"type":"message_stop"}`},
+	}
+
+	stream := &CreateMessagesStream{
+		Event: make(chan sse.Event),
+		Error: make(chan error),
+	}
+	go func() {
+		for _, e := range events {
+			stream.Event <- e
+		}
+	}()
+
+	var seen []string
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv returned an error: %v", err)
+		}
+		seen = append(seen, res.Event)
+		if res.Event == MessagesStreamResponseTypeContentBlockStop && res.Content[0].Index == 1 {
+			if string(res.Content[0].Input) != `{"location": "San Francisco"}` {
+				t.Errorf("Expected complete input on block stop, got %s", res.Content[0].Input)
+			}
+		}
+	}
+
+	if len(seen) != len(events)-1 {
+		t.Errorf("Expected %d events before message_stop, got %d", len(events)-1, len(seen))
+	}
+
+	message := stream.Message()
+	if message.Text() != "Let me check." {
+		t.Errorf("Expected accumulated text %q, got %q", "Let me check.", message.Text())
+	}
+	if message.StopReason != StopReasonToolUse {
+		t.Errorf("Expected stop reason %s, got %s", StopReasonToolUse, message.StopReason)
+	}
+
+	calls := message.ToolCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 tool call, got %d", len(calls))
+	}
+	if calls[0].Id != "toolu_1" || calls[0].Name != "get_weather" {
+		t.Errorf("Unexpected tool call %+v", calls[0])
+	}
+	var input struct {
+		Location string `json:"location"`
+	}
+	if err := calls[0].DecodeInput(&input); err != nil {
+		t.Fatalf("DecodeInput returned an error: %v", err)
+	}
+	if input.Location != "San Francisco" {
+		t.Errorf("Expected location San Francisco, got %s", input.Location)
+	}
+}
+
+func TestRecvAccumulatesThinking(t *testing.T) {
+	events := []sse.Event{
+		{Type: MessagesStreamResponseTypeMessageStart, Data: `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-20250514","usage":{"input_tokens":12,"output_tokens":1}}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStart, Data: `{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Two plus two "}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"is four."}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"c2lnbmF0dXJl"}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStop, Data: `{"type":"content_block_stop","index":0}`},
+		{Type: MessagesStreamResponseTypeContentBlockStart, Data: `{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"ZW5jcnlwdGVk"}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStop, Data: `{"type":"content_block_stop","index":1}`},
+		{Type: MessagesStreamResponseTypeContentBlockStart, Data: `{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`},
+		{Type: MessagesStreamResponseTypeContentBlockDelta, Data: `{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"4"}}`},
+		{Type: MessagesStreamResponseTypeContentBlockStop, Data: `{"type":"content_block_stop","index":2}`},
+		{Type: MessagesStreamResponseTypeMessageDelta, Data: `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":30}}`},
+		{Type: MessagesStreamResponseTypeMessageStop, Data: `{"type":"message_stop"}`},
+	}
+
+	stream := &CreateMessagesStream{
+		Event: make(chan sse.Event),
+		Error: make(chan error),
+	}
+	go func() {
+		for _, e := range events {
+			stream.Event <- e
+		}
+	}()
+
+	var text string
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv returned an error: %v", err)
+		}
+		if res.Event == MessagesStreamResponseTypeContentBlockDelta && res.Content[0].Type == ResponseContentTypeText {
+			text += res.Content[0].Text
+		}
+	}
+	if text != "4" {
+		t.Errorf("Expected only the text deltas to be text, got %q", text)
+	}
+
+	message := stream.Message()
+	if message.Text() != "4" {
+		t.Errorf("Expected accumulated text %q, got %q", "4", message.Text())
+	}
+	if message.Thinking() != "Two plus two is four." {
+		t.Errorf("Expected accumulated thinking, got %q", message.Thinking())
+	}
+
+	blocks := message.ThinkingBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("Expected 2 thinking blocks, got %d", len(blocks))
+	}
+	if blocks[0].Type != RequestContentTypeThinkingType || blocks[0].Thinking != "Two plus two is four." || blocks[0].Signature != "c2lnbmF0dXJl" {
+		t.Errorf("Unexpected thinking block %+v", blocks[0])
+	}
+	if blocks[1].Type != RequestContentTypeRedactedThinkingType || blocks[1].Data != "ZW5jcnlwdGVk" {
+		t.Errorf("Unexpected redacted thinking block %+v", blocks[1])
+	}
+
+	replayed := message.ToRequestMessage()
+	if len(replayed.ContentBlocks) != 3 || replayed.ContentBlocks[0].Type != RequestContentTypeThinkingType {
+		t.Errorf("Expected the thinking blocks ahead of the text when sent back, got %+v", replayed.ContentBlocks)
+	}
+}
@@ -0,0 +1,103 @@
+package claude
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Offline token estimates. They are deliberately rough, about four characters
+// per token, and meant for deciding what fits in a context window rather than
+// for billing.
+const (
+	charsPerToken = 4
+
+	// EstimatedImageTokens is the cost of a large image; the API scales
+	// images down to about 1600 tokens.
+	EstimatedImageTokens = 1600
+
+	// estimatedDocumentPageTokens is a typical PDF page, text plus the image
+	// of the page, and estimatedDocumentPageBytes a typical page's size.
+	estimatedDocumentPageTokens = 2000
+	estimatedDocumentPageBytes  = 50 * 1024
+
+	// messageOverheadTokens covers the role and formatting of a message.
+	messageOverheadTokens = 4
+)
+
+// DefaultContextWindow is the context window, in tokens, assumed for models
+// not listed in KnownModels.
+const DefaultContextWindow = 200000
+
+// ContextWindow returns the number of tokens a model accepts, input and
+// output together.
+func ContextWindow(model string) int {
+	info, ok := LookupModel(model)
+	if !ok {
+		return DefaultContextWindow
+	}
+	return info.ContextWindow
+}
+
+// EstimateTokens estimates the number of tokens of a text.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateMessageTokens estimates the number of input tokens of a message.
+func EstimateMessageTokens(m RequestMessages) int {
+	tokens := messageOverheadTokens
+	if len(m.ContentBlocks) == 0 && len(m.ContentTypeText) == 0 {
+		return tokens + EstimateTokens(m.Content)
+	}
+	for _, text := range m.ContentTypeText {
+		tokens += EstimateTokens(text.Text)
+	}
+	for _, block := range m.ContentBlocks {
+		tokens += EstimateBlockTokens(block)
+	}
+	return tokens
+}
+
+// EstimateBlockTokens estimates the number of input tokens of a content block.
+func EstimateBlockTokens(block RequestContentBlock) int {
+	switch block.Type {
+	case RequestContentTypeImageType:
+		return EstimatedImageTokens
+	case RequestContentTypeDocumentType:
+		if block.Source == nil {
+			return 0
+		}
+		size := base64.StdEncoding.DecodedLen(len(block.Source.Data))
+		pages := (size + estimatedDocumentPageBytes - 1) / estimatedDocumentPageBytes
+		return pages * estimatedDocumentPageTokens
+	case RequestContentTypeToolUseType:
+		return EstimateTokens(block.Name) + EstimateTokens(string(block.Input))
+	case RequestContentTypeToolResultType:
+		return EstimateTokens(block.Content)
+	case RequestContentTypeThinkingType, RequestContentTypeRedactedThinkingType:
+		// The API leaves the thinking of earlier turns out of the context.
+		return 0
+	}
+	return EstimateTokens(block.Text)
+}
+
+// EstimateRequestTokens estimates the number of input tokens of a request:
+// its system prompt, tools and messages.
+func EstimateRequestTokens(body RequestBody) int {
+	tokens := EstimateTokens(body.System)
+	if len(body.SystemBlocks) > 0 {
+		tokens = 0
+		for _, block := range body.SystemBlocks {
+			tokens += EstimateBlockTokens(block)
+		}
+	}
+	for _, tool := range body.Tools {
+		schema, _ := json.Marshal(tool.InputSchema)
+		tokens += EstimateTokens(tool.Name) + EstimateTokens(tool.Description) + EstimateTokens(string(schema))
+	}
+	for _, m := range body.Messages {
+		tokens += EstimateMessageTokens(m)
+	}
+	return tokens
+}
@@ -0,0 +1,141 @@
+package claude
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolDefinitionJSON(t *testing.T) {
+	body := RequestBody{
+		Model:     "claude-3-5-sonnet-20240620",
+		MaxTokens: 100,
+		Messages:  []RequestMessages{{Role: MessageRoleUser, Content: "What is the weather?"}},
+		Tools: []Tool{{
+			Name:        "get_weather",
+			Description: "Get the current weather",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"location": map[string]interface{}{"type": "string"}},
+				"required":   []string{"location"},
+			},
+		}},
+		ToolChoice: &ToolChoice{Type: ToolChoiceTool, Name: "get_weather"},
+	}
+	raw, err := parseBodyJSON(body)
+	if err != nil {
+		t.Fatalf("parseBodyJSON returned an error: %v", err)
+	}
+
+	var sent struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			InputSchema struct {
+				Type     string   `json:"type"`
+				Required []string `json:"required"`
+			} `json:"input_schema"`
+		} `json:"tools"`
+		ToolChoice map[string]interface{} `json:"tool_choice"`
+	}
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatalf("Failed to unmarshal the request: %v", err)
+	}
+	if len(sent.Tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(sent.Tools))
+	}
+	tool := sent.Tools[0]
+	if tool.Name != "get_weather" || tool.Description != "Get the current weather" || tool.InputSchema.Type != "object" {
+		t.Errorf("Unexpected tool %+v", tool)
+	}
+	if len(tool.InputSchema.Required) != 1 || tool.InputSchema.Required[0] != "location" {
+		t.Errorf("Expected the input schema to be sent as given, got %+v", tool.InputSchema)
+	}
+	if sent.ToolChoice["type"] != ToolChoiceTool || sent.ToolChoice["name"] != "get_weather" {
+		t.Errorf("Unexpected tool choice %v", sent.ToolChoice)
+	}
+	if _, ok := sent.ToolChoice["disable_parallel_tool_use"]; ok {
+		t.Errorf("Expected disable_parallel_tool_use to be left out when false")
+	}
+}
+
+func TestParseToolUse(t *testing.T) {
+	data := `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20240620","stop_reason":"tool_use",
+		"content":[
+			{"type":"text","text":"Let me check."},
+			{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"location":"Paris"}},
+			{"type":"tool_use","id":"toolu_2","name":"get_time","input":{}}
+		]}`
+	var response ResponseBody
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("Failed to unmarshal the response: %v", err)
+	}
+	if response.StopReason != StopReasonToolUse {
+		t.Errorf("Expected stop reason %s, got %s", StopReasonToolUse, response.StopReason)
+	}
+	if response.Text() != "Let me check." {
+		t.Errorf("Expected the text without the tool calls, got %q", response.Text())
+	}
+
+	calls := response.ToolCalls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %d", len(calls))
+	}
+	if calls[0].Id != "toolu_1" || calls[0].Name != "get_weather" || calls[1].Id != "toolu_2" {
+		t.Errorf("Unexpected tool calls %+v", calls)
+	}
+	var input struct {
+		Location string `json:"location"`
+	}
+	if err := calls[0].DecodeInput(&input); err != nil {
+		t.Fatalf("DecodeInput returned an error: %v", err)
+	}
+	if input.Location != "Paris" {
+		t.Errorf("Expected location Paris, got %s", input.Location)
+	}
+}
+
+func TestToRequestMessage(t *testing.T) {
+	response := ResponseBody{Content: []ResponseContent{
+		{Type: ResponseContentTypeText, Text: "Let me check."},
+		{Type: ResponseContentTypeToolUse, Id: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"location":"Paris"}`)},
+		{Type: ResponseContentTypeToolUse, Id: "toolu_2", Name: "get_time"},
+	}}
+	message := response.ToRequestMessage()
+	if message.Role != MessageRoleAssistant {
+		t.Errorf("Expected role %s, got %s", MessageRoleAssistant, message.Role)
+	}
+	if len(message.ContentBlocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d", len(message.ContentBlocks))
+	}
+	if b := message.ContentBlocks[0]; b.Type != RequestContentTypeTextType || b.Text != "Let me check." {
+		t.Errorf("Unexpected text block %+v", b)
+	}
+	if b := message.ContentBlocks[1]; b.Type != RequestContentTypeToolUseType || b.Id != "toolu_1" || string(b.Input) != `{"location":"Paris"}` {
+		t.Errorf("Unexpected tool_use block %+v", b)
+	}
+	if b := message.ContentBlocks[2]; string(b.Input) != "{}" {
+		t.Errorf("Expected a missing input to be sent as an empty object, got %q", b.Input)
+	}
+
+	// The answer goes back in a user message of tool_result blocks.
+	messages := []RequestMessages{message, {
+		Role:          MessageRoleUser,
+		ContentBlocks: []RequestContentBlock{NewToolResultBlock("toolu_1", "Sunny", false), NewToolResultBlock("toolu_2", "no clock", true)},
+	}}
+	if err := encodeContent(messages); err != nil {
+		t.Fatalf("encodeContent returned an error: %v", err)
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(messages[1].ContentRaw.(json.RawMessage), &results); err != nil {
+		t.Fatalf("Failed to unmarshal the tool results: %v", err)
+	}
+	if results[0]["type"] != RequestContentTypeToolResultType || results[0]["tool_use_id"] != "toolu_1" || results[0]["content"] != "Sunny" {
+		t.Errorf("Unexpected tool_result block %v", results[0])
+	}
+	if _, ok := results[0]["is_error"]; ok {
+		t.Errorf("Expected is_error to be left out for a successful result")
+	}
+	if results[1]["is_error"] != true {
+		t.Errorf("Expected is_error on the failed result, got %v", results[1])
+	}
+}
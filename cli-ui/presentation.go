@@ -20,6 +20,10 @@ func PresentHistoricMessagePairs(displayAmount int, messagePairs []chat.MessageP
 	for i := 0; i < displayAmount && i < maxPairs; i++ {
 		pair := messagePairs[i]
 		fmt.Printf("\nUser: %s\n", pair.UserMessage.Content)
+		if thinking := chat.CollapsedThinking(pair.AssistantMessage.ContentBlocks); thinking != "" {
+			fmt.Printf("\nClaude: %s\n%s\n", thinking, pair.AssistantMessage.Content)
+			continue
+		}
 		fmt.Printf("\nClaude: %s\n", pair.AssistantMessage.Content)
 	}
 	fmt.Println("\n")
@@ -94,3 +94,23 @@ func PromptForConversationId() int64 {
 
 	return id
 }
+
+func PromptForMessageId(conversationId int64) int64 {
+	messages, err := db.GetActiveBranch(conversationId)
+	if err != nil {
+		logger.PanicError(err, "Error listing messages")
+	}
+	options := make(map[interface{}]string)
+	for _, messOptions := range messages {
+		options[messOptions.ID] = fmt.Sprintf("%s: %s", messOptions.Role, messOptions.Content)
+	}
+	selected := terminal.New().PromptOptionsSelect(options)
+	fmt.Printf("Selected: ID=%v, Description=%s\n", selected.ID, selected.Description)
+
+	id, ok := selected.ID.(int64)
+	if !ok {
+		logger.PanicError(err, "Invalid ID type; Expected int64 for message ID")
+	}
+
+	return id
+}
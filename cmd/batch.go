@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/batch"
+	"github.com/christianhturner/go-claude/chat"
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Send many requests at half the price, answered within a day.",
+	Long: `Batch sends requests through the Message Batches API, which processes them
+    asynchronously, usually within an hour and at most within a day, for half the price of
+    sending them one by one. Follow this command with a supported subcommand.
+
+    go-claude batch submit prompts.jsonl
+    go-claude batch status
+    go-claude batch results msgbatch_01...`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Please provide a subcommand [submit, status, results, cancel]")
+	},
+}
+
+// go-claude batch submit
+var batchSubmitCmd = &cobra.Command{
+	Use:   "submit file.jsonl",
+	Short: "Submit the requests of a JSONL file as a batch.",
+	Long: `Submit reads one request per line, in the format of the Message Batches API:
+
+    {"custom_id": "q1", "params": {"messages": [{"role": "user", "content": "Hello"}]}}
+
+    params is a Messages API request. The model, max_tokens, system prompt and sampling
+    settings it leaves out are taken from the configuration and flags like --model, and
+    a missing custom_id becomes line-<number>. Results are matched to requests by custom_id.
+
+    go-claude batch submit prompts.jsonl
+    go-claude batch submit prompts.jsonl --model claude-3-haiku-20240307 --max-tokens 500`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliBatchSubmit(cmd, args)
+	},
+}
+
+// go-claude batch status
+var batchStatusCmd = &cobra.Command{
+	Use:   "status [batch-id]",
+	Short: "Show the status of a batch, or of every batch submitted.",
+	Long: `Status asks the API for the progress of a batch. Without a batch ID it lists every
+    batch submitted from this database, updating those still in progress.
+
+    go-claude batch status
+    go-claude batch status msgbatch_01...`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliBatchStatus(cmd, args)
+	},
+}
+
+// go-claude batch results
+var batchResultsCmd = &cobra.Command{
+	Use:   "results batch-id",
+	Short: "Write the results of an ended batch.",
+	Long: `Results writes the result of every request of an ended batch, in jsonl, the API's
+    results file, or text, the custom ID and reply or error of each request. The usage of
+    the results is added to go-claude stats, and spending budgets, at the batch price.
+
+    go-claude batch results msgbatch_01... -o results.jsonl
+    go-claude batch results msgbatch_01... --format text`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliBatchResults(cmd, args)
+	},
+}
+
+// go-claude batch cancel
+var batchCancelCmd = &cobra.Command{
+	Use:   "cancel batch-id",
+	Short: "Stop a batch that is in progress.",
+	Long: `Cancel stops a batch. Requests already being processed still finish, the results of
+    the others are reported as canceled.
+
+    go-claude batch cancel msgbatch_01...`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliBatchCancel(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.AddCommand(batchSubmitCmd)
+	batchCmd.AddCommand(batchStatusCmd)
+	batchCmd.AddCommand(batchResultsCmd)
+	batchCmd.AddCommand(batchCancelCmd)
+	batchCmdFlags()
+}
+
+func cliBatchSubmit(cmd *cobra.Command, args []string) {
+	settings, err := chat.ResolveSettings(0, flagOverrides(cmd))
+	if err != nil {
+		logger.FatalError(err, "Error resolving settings")
+	}
+	defaults := claude.RequestBody{
+		Model:       settings.Model,
+		MaxTokens:   settings.MaxTokens,
+		System:      settings.System,
+		Temparature: settings.Temperature,
+		TopP:        settings.TopP,
+		TopK:        settings.TopK,
+	}
+
+	submitted, requests, err := batch.Submit(context.Background(), newClient(forceBudget), args[0], defaults)
+	if submitted == nil && err != nil {
+		logger.FatalError(err, "Error submitting batch")
+	}
+	fmt.Printf("Submitted %d requests as batch %s, expiring %s.\n", requests, submitted.Id, submitted.ExpiresAt.Local().Format(time.DateTime))
+	if err != nil {
+		logger.FatalError(err, "Error recording batch job")
+	}
+	fmt.Printf("Follow it with: go-claude batch status %s\n", submitted.Id)
+}
+
+func cliBatchStatus(cmd *cobra.Command, args []string) {
+	client := newClient(false)
+	ctx := context.Background()
+	if len(args) == 1 {
+		job, err := batch.Refresh(ctx, client, args[0])
+		if err != nil {
+			logger.FatalError(err, "Error getting batch")
+		}
+		printBatchJob(*job)
+		return
+	}
+
+	jobs, err := db.ListBatchJobs()
+	if err != nil {
+		logger.FatalError(err, "Error listing batch jobs")
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No batch submitted yet.")
+		return
+	}
+	for _, job := range jobs {
+		if job.Status != claude.BatchStatusEnded {
+			refreshed, err := batch.Refresh(ctx, client, job.BatchID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not update batch %s: %v\n", job.BatchID, err)
+			} else {
+				job = *refreshed
+			}
+		}
+		printBatchJob(job)
+	}
+}
+
+func cliBatchResults(cmd *cobra.Command, args []string) {
+	if !slices.Contains(batch.Formats, batchFormat) {
+		logger.FatalError(fmt.Errorf("unknown format %q, expected %s", batchFormat, strings.Join(batch.Formats, " or ")), "Error reading --format")
+	}
+	var out io.Writer = os.Stdout
+	if batchOut != "" && batchOut != "-" {
+		file, err := os.Create(batchOut)
+		if err != nil {
+			logger.FatalError(err, "Error creating output file")
+		}
+		defer file.Close()
+		out = file
+	}
+
+	report, err := batch.WriteResults(context.Background(), newClient(false), args[0], out, batchFormat)
+	if err != nil {
+		logger.FatalError(err, "Error getting batch results")
+	}
+	fmt.Fprintf(os.Stderr, "%d requests succeeded, %d failed.", report.Succeeded, report.Failed)
+	if report.UsageRecorded {
+		fmt.Fprint(os.Stderr, " Their usage was added to go-claude stats.")
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func cliBatchCancel(cmd *cobra.Command, args []string) {
+	job, err := batch.Cancel(context.Background(), newClient(false), args[0])
+	if err != nil {
+		logger.FatalError(err, "Error canceling batch")
+	}
+	printBatchJob(*job)
+}
+
+func printBatchJob(job db.BatchJob) {
+	fmt.Printf("%s  %-11s  %d requests: %d succeeded, %d errored, %d canceled, %d expired",
+		job.BatchID, job.Status, job.Requests, job.Succeeded, job.Errored, job.Canceled, job.Expired)
+	if job.InputFile != "" {
+		fmt.Printf("  (%s)", job.InputFile)
+	}
+	fmt.Println()
+}
@@ -4,18 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"strings"
 
 	"github.com/christianhturner/go-claude/chat"
 	"github.com/christianhturner/go-claude/claude"
 	cliui "github.com/christianhturner/go-claude/cli-ui"
-	"github.com/christianhturner/go-claude/config"
 	"github.com/christianhturner/go-claude/db"
 	"github.com/christianhturner/go-claude/logger"
 	"github.com/christianhturner/go-claude/terminal"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // chatCmd represents the chat command
@@ -23,20 +19,21 @@ var chatCmd = &cobra.Command{
 	Use:   "chat",
 	Short: "Chat with Claude AI",
 	Long: `This command allows you to chat with Claude AI. You can either provide a message and 
-    conversationId directly using the --message and --id flag or enter a message when prompted.`,
+    conversationId directly using the --message and --id flag, which sends that single message and
+    exits, or leave out --message to open an interactive session. Type /help in the session to list
+    its commands, and /exit or Ctrl-D to leave it.
+
+    go-claude chat --id 1 --persona reviewer
+    go-claude chat --id 1 -m "What is wrong with this screenshot?" --attach ./screen.png`,
 	Run: func(cmd *cobra.Command, args []string) {
-		model := viper.GetString(config.ModelKey)
-		MaxTokens := viper.GetInt(config.MaxTokensKey)
-		apiKey := viper.GetString(config.AnthropicApiKeyKey)
-		stream := viper.GetBool(config.StreamKey)
-		c := claude.NewClient(apiKey)
+		c := newClient(forceBudget)
 
 		convs, err := db.ListConversations()
 		if err != nil {
 			logger.PanicError(err, "Error listing conversations from DB")
 		}
 		if len(convs) == 0 {
-			fmt.Println("No Conversations found.\nLet's get one created for you!\n")
+			fmt.Print("No Conversations found.\nLet's get one created for you!\n\n")
 			runCreateConversation()
 		}
 
@@ -55,67 +52,37 @@ var chatCmd = &cobra.Command{
 			}
 		}
 
-		if userMessage == "" {
-			userMessage = cliui.PromptUserForMessage()
+		err = configureConversationPersona(conversationId)
+		if err != nil {
+			logger.PanicError(err, "Error configuring the conversation's persona")
 		}
 
-		history := chat.GetConversationHistory(conversationId)
-
-		messageRequest := chat.MessageToRequest(userMessage)
-
-		messages := chat.AppendHistoryToMessageRequest(messageRequest, history)
-
+		session, err := chat.NewSession(conversationId, c, flagOverrides(cmd))
+		if err != nil {
+			logger.FatalError(err, fmt.Sprintf("Error resolving conversation settings, see go-claude configure conversation --id %d --unset <option>", conversationId))
+		}
+		session.AttachToNextMessage(attachPaths)
 		ctx := context.Background()
 
-		if stream {
-			requestBody := claude.RequestBody{
-				Model:     model,
-				MaxTokens: MaxTokens,
-				Messages:  messages,
-				Stream:    stream,
+		if userMessage != "" {
+			err := session.Send(ctx, userMessage)
+			if errors.Is(err, chat.ErrBudgetExceeded) {
+				logger.FatalError(err, "Request refused")
 			}
-			stream := chat.StreamMessagesToClaude(ctx, requestBody, *c)
-			var finalMessage claude.RequestMessages
-			finalMessage.Role = claude.MessageRoleAssistant
-
-			var contentBuilder strings.Builder
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return
+			var apiErr *claude.APIError
+			if errors.As(err, &apiErr) {
+				logger.FatalError(apiErr, "Claude API returned an error")
 			}
-			defer stream.Close()
-			for {
-				res, err := stream.Recv()
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				if err != nil {
-					logger.PanicError(err, "Error with stream")
-				}
-				fmt.Printf("%s", res.Content[0].Text)
-				contentBuilder.WriteString(res.Content[0].Text)
-			}
-			finalMessage.Content = contentBuilder.String()
-			chat.AddMessageToConversationTable(conversationId, messageRequest)
-			chat.AddMessageToConversationTable(conversationId, finalMessage)
-
-		} else {
-
-			requestBody := claude.RequestBody{
-				Model:     model,
-				MaxTokens: MaxTokens,
-				Messages:  messages,
+			if err != nil {
+				logger.PanicError(err, "Error sending message to Claude")
 			}
+			return
+		}
 
-			response := chat.SendMessageToClaude(ctx, requestBody, *c)
-
-			fmt.Printf("Claude: %s/n", response.Content[0].Text)
-
-			chat.AddMessageToConversationTable(conversationId, messageRequest)
-			chat.AddMessageToConversationTable(conversationId, claude.RequestMessages{
-				Role:    claude.MessageRoleAssistant,
-				Content: response.Content[0].Text,
-			})
+		session.ShowTokens(true)
+		err = session.Run(ctx)
+		if err != nil {
+			logger.PanicError(err, "Error reading chat input")
 		}
 	},
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/christianhturner/go-claude/chat"
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+	"github.com/spf13/viper"
+)
+
+// newClient returns a client for the configured API key that refuses requests
+// past the configured budget unless force is set, and prints the budget's
+// warnings to stderr. Failed requests are retried as configured, printing
+// every retry to stderr, and requests are paced to the configured rate limits.
+func newClient(force bool) *claude.Client {
+	c := claude.NewClient(viper.GetString(config.AnthropicApiKeyKey))
+	c.SetRequestGuard(chat.BudgetGuard(chat.GlobalBudget(), force, os.Stderr))
+
+	retries := config.GetInt(config.MaxRetriesKey)
+	policy := claude.DefaultRetryPolicy()
+	policy.MaxRetries = retries
+	policy.MaxElapsed = time.Duration(config.GetInt(config.RetryMaxElapsedKey)) * time.Second
+	policy.OnRetry = func(attempt int, status int, wait time.Duration) {
+		fmt.Fprintf(os.Stderr, "Request failed with status %d, retrying in %s (%d of %d)\n",
+			status, wait.Round(100*time.Millisecond), attempt, retries)
+	}
+	c.SetRetryPolicy(policy)
+	c.SetLimiter(claude.NewLimiter(config.GetInt(config.RequestsPerMinuteKey), config.GetInt(config.InputTokensPerMinuteKey)))
+	return c
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/christianhturner/go-claude/chat"
+	cliui "github.com/christianhturner/go-claude/cli-ui"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(compactCmd)
+	compactCmdFlags()
+}
+
+// compactCmd represents the compact command
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Summarize the older messages of a conversation.",
+	Long: `Compact asks Claude to summarize a conversation's active branch, except for its
+    latest message pairs. Later turns send the summary, as part of the system prompt, and
+    the messages after it instead of the full history. The summarized messages are kept,
+    so history, search and export still show every one of them.
+
+    Chat compacts a conversation on its own once its history fills more of the context
+    budget than --compact-threshold allows, keeping the --compact-keep-recent latest pairs.
+
+    go-claude compact --id 1
+    go-claude compact --id 1 --keep 2
+    go-claude compact --id 1 --clear`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliCompact(cmd, args)
+	},
+}
+
+func cliCompact(cmd *cobra.Command, args []string) {
+	if conversationId == 0 {
+		conversationId = cliui.PromptForConversationId()
+	}
+
+	if compactClear {
+		deleted, err := db.DeleteSummaries(conversationId)
+		if err != nil {
+			logger.FatalError(err, "Error deleting summaries")
+		}
+		fmt.Printf("Deleted %d summaries, conversation %d is sent in full again.\n", deleted, conversationId)
+		return
+	}
+
+	settings, err := chat.ResolveSettings(conversationId, flagOverrides(cmd))
+	if err != nil {
+		logger.FatalError(err, "Error resolving conversation settings")
+	}
+	keep := settings.CompactKeepRecent
+	if cmd.Flags().Changed("keep") {
+		keep = compactKeep
+	}
+
+	client := newClient(forceBudget)
+	report, err := chat.Compact(context.Background(), client, conversationId, settings, keep)
+	if err != nil {
+		logger.FatalError(err, "Error compacting conversation")
+	}
+	summary, err := db.GetMessage(report.SummaryID)
+	if err != nil {
+		logger.FatalError(err, "Error getting summary")
+	}
+	fmt.Printf("%s\n\nSummarized %d messages, about %d tokens, in about %d tokens. The latest %d messages are sent as they are.\n",
+		summary.Content, report.SummarizedMessages, report.TokensBefore, report.TokensAfter, report.KeptMessages)
+}
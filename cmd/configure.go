@@ -5,8 +5,13 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/christianhturner/go-claude/chat"
+	cliui "github.com/christianhturner/go-claude/cli-ui"
 	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -27,15 +32,38 @@ var configureCmd = &cobra.Command{
 			config.ResetToDefaults()
 			fmt.Println("configuration reset to defaults")
 		} else {
+			validateConfigFlags(cmd)
 			config.UpdateConfig(cmd)
 			fmt.Println("Configuration file updated")
 		}
 	},
 }
 
+// go-claude configure conversation
+var configureConversationCmd = &cobra.Command{
+	Use:   "conversation",
+	Short: "Settings for a single conversation.",
+	Long: `Configure conversation stores settings with a single conversation. They are
+    used on every turn of that conversation instead of the global configuration, and
+    can still be overridden for one invocation by passing the same flag to chat.
+
+    The settings that can be stored are --model, --max-tokens, --temperature, --top-p,
+    --top-k, --stream, --thinking-budget, --system, --persona, --context-strategy,
+    --context-keep-first, --context-budget, --compact-threshold and --compact-keep-recent. Running the command
+    without any of them prints the conversation's current settings.
+
+    go-claude configure conversation --id 1 --model claude-3-opus-20240229 --temperature 0.2
+    go-claude configure conversation --id 1 --unset temperature -> Falls back to the global temperature.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliConfigureConversation(cmd, args)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configureCmd)
+	configureCmd.AddCommand(configureConversationCmd)
 	configureCmd.Flags().Bool("defaults", false, "Reset configuration to default values.")
+	configureCmdFlags()
 
 	// Here you will define your flags and configuration settings.
 
@@ -47,3 +75,67 @@ func init() {
 	// is called directly, e.g.:
 	// configureCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// validateConfigFlags checks the conversation settings given as flags before
+// they are written to the config file, alone and with the settings already in
+// it.
+func validateConfigFlags(cmd *cobra.Command) {
+	overrides := flagOverrides(cmd)
+	for option, value := range overrides {
+		err := chat.ValidateOption(option, value)
+		if err != nil {
+			logger.FatalError(fmt.Errorf("%s: %w", option, err), "Invalid configuration")
+		}
+	}
+	settings, err := chat.ResolveSettings(0, overrides)
+	if err == nil {
+		err = chat.ValidateSettings(settings)
+	}
+	if err != nil {
+		logger.FatalError(err, "Invalid configuration")
+	}
+}
+
+func cliConfigureConversation(cmd *cobra.Command, args []string) {
+	if conversationId == 0 {
+		conversationId = cliui.PromptForConversationId()
+	}
+
+	options := map[string]string(flagOverrides(cmd))
+	if cmd.Flags().Changed("system") {
+		options[db.OptionSystem] = systemPrompt
+	}
+	if cmd.Flags().Changed("persona") {
+		options[db.OptionPersona] = personaName
+	}
+	unset, _ := cmd.Flags().GetStringArray("unset")
+	for _, name := range unset {
+		if !slices.Contains(chat.SettingOptions, name) {
+			logger.FatalError(fmt.Errorf("unknown option %s", name), "Error unsetting conversation option")
+		}
+		options[name] = ""
+	}
+
+	if len(options) > 0 {
+		err := chat.ConfigureConversation(conversationId, options)
+		if err != nil {
+			logger.FatalError(err, "Error configuring conversation")
+		}
+		fmt.Println("Conversation settings updated")
+	}
+
+	stored, err := chat.ConversationOptions(conversationId)
+	if err != nil {
+		logger.FatalError(err, "Error getting conversation options")
+	}
+	if len(stored) == 0 {
+		fmt.Printf("Conversation %d uses the global configuration.\n", conversationId)
+		return
+	}
+	fmt.Printf("Conversation %d settings:\n", conversationId)
+	for _, name := range chat.SettingOptions {
+		if value, ok := stored[name]; ok {
+			fmt.Printf("  %s: %s\n", name, value)
+		}
+	}
+}
@@ -4,7 +4,11 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+
+	"github.com/christianhturner/go-claude/chat"
 	cliui "github.com/christianhturner/go-claude/cli-ui"
+	"github.com/christianhturner/go-claude/config"
 	"github.com/christianhturner/go-claude/db"
 	"github.com/christianhturner/go-claude/logger"
 	"github.com/christianhturner/go-claude/terminal"
@@ -19,31 +23,87 @@ var createCmd = &cobra.Command{
     added, and create will not work without the use of the additional subcommands. Currently,
     no other items to be created, so leaving this as is.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		var id int64
+		var err error
 		if cmd.Flags().Changed("title") {
 			if conversationTitle == "none" {
-				id, err := db.CreateConversation("")
+				id, err = db.CreateConversation("")
 				if err != nil {
 					logger.FatalError(err, "Error creating conversation with no title at go-claude create --title \"title\"")
 				}
 				logger.Debug("Created a conversation with no name. Id: ", id)
 			} else {
-				id, err := db.CreateConversation(conversationTitle)
+				id, err = db.CreateConversation(conversationTitle)
 				if err != nil {
 					logger.FatalError(err, "Error creating conversation with title")
 				}
 				logger.Debug("Created conversation: \nId: ", id, ": Title", conversationTitle)
 			}
 		} else {
-			err := runCreateConversation()
+			id, err = runCreateConversation()
 			if err != nil {
 				logger.FatalError(err, "Error executing runCreate")
 			}
 		}
+		err = configureConversationPersona(id)
+		if err != nil {
+			logger.FatalError(err, "Error configuring the conversation's persona")
+		}
+	},
+}
+
+// go-claude create persona
+var createPersonaCmd = &cobra.Command{
+	Use:   "persona",
+	Short: "Create or update a persona.",
+	Long: `A persona is a named system prompt with a default model and sampling parameters.
+    Conversations created or chatted with --persona use it on every turn. The global
+    --model, --max-tokens, --temperature, --top-p and --top-k flags set the persona's
+    defaults; any left out fall back to the conversation and global configuration.
+    Creating a persona with an existing name updates it.
+
+    go-claude create persona --name reviewer --system "You are a strict code reviewer." --model claude-3-opus-20240229`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if personaName == "" {
+			input, err := terminal.New().Prompt("Please provide a name for your persona:")
+			logger.FatalError(err, "Error inputting name at create persona")
+			personaName = input
+		}
+		persona := db.Persona{
+			Name:         personaName,
+			SystemPrompt: systemPrompt,
+		}
+		if cmd.Flags().Changed("model") {
+			err := chat.ValidateModel(config.Model)
+			if err != nil {
+				logger.FatalError(err, "Error reading --model")
+			}
+			persona.Model = config.Model
+		}
+		if cmd.Flags().Changed("max-tokens") {
+			persona.MaxTokens = config.MaxTokens
+		}
+		if cmd.Flags().Changed("temperature") {
+			persona.Temperature = config.Temperature
+		}
+		if cmd.Flags().Changed("top-p") {
+			persona.TopP = config.TopP
+		}
+		if cmd.Flags().Changed("top-k") {
+			persona.TopK = config.TopK
+		}
+		err := db.SavePersona(persona)
+		if err != nil {
+			logger.FatalError(err, "Error saving persona")
+		}
+		fmt.Printf("Saved persona %s\n", persona.Name)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(createCmd)
+	createCmd.AddCommand(createPersonaCmd)
+	createCmdFlags()
 
 	// Here you will define your flags and configuration settings.
 
@@ -56,9 +116,9 @@ func init() {
 	// createCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func runCreateConversation() error {
+func runCreateConversation() (int64, error) {
 	term := terminal.New()
-	userSelect := cliui.PromptForBool("Would you like to give your conversation a name?", nil)
+	userSelect := cliui.PromptForBool("Would you like to give your conversation a name?")
 	switch userSelect {
 	case true:
 		input, err := term.Prompt("Please provide a name for your conversation:")
@@ -66,10 +126,29 @@ func runCreateConversation() error {
 		id, err := db.CreateConversation(input)
 		logger.FatalError(err, "Error creating conversation in database at runCreate")
 		logger.Debug("Created a conversation:\nId:", id, ": Title: ", input)
-	case false:
+		return id, nil
+	default:
 		id, err := db.CreateConversation("")
 		logger.FatalError(err, "Error creating conversation with no title at runCreate")
 		logger.Debug("Created a conversation with no name. Id: ", id)
+		return id, nil
+	}
+}
+
+// configureConversationPersona stores the --persona and --system flags with
+// the conversation so every later turn uses them.
+func configureConversationPersona(id int64) error {
+	if personaName != "" {
+		err := chat.SetConversationPersona(id, personaName)
+		if err != nil {
+			return err
+		}
+	}
+	if systemPrompt != "" {
+		err := chat.SetConversationSystem(id, systemPrompt)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd, dbStatusCmd)
+}
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain the go-claude database.",
+	Long: `db groups the commands used to maintain the SQLite database that stores your
+    conversations. The schema is versioned; every other go-claude command brings it up to
+    date when it opens the database. These commands open it as it is, so status shows the
+    migrations still pending and migrate reports the one that fails, if any.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Please provide a subcommand [migrate, status]")
+	},
+}
+
+// go-claude db migrate
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations.",
+	Long:  `Apply every schema migration the database has not seen yet, in order, and print the resulting schema version.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliDbMigrate(cmd, args)
+	},
+}
+
+// go-claude db status
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the schema version and applied migrations.",
+	Long:  `List every known schema migration along with when it was applied to the database.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliDbStatus(cmd, args)
+	},
+}
+
+func cliDbMigrate(cmd *cobra.Command, args []string) {
+	applied, err := db.Migrate()
+	if err != nil {
+		logger.FatalError(err, "Error migrating database")
+	}
+	for _, m := range applied {
+		fmt.Printf("Applied %d: %s\n", m.Version, m.Name)
+	}
+	version, err := db.SchemaVersion()
+	if err != nil {
+		logger.FatalError(err, "Error reading schema version")
+	}
+	fmt.Printf("Database is at schema version %d\n", version)
+}
+
+func cliDbStatus(cmd *cobra.Command, args []string) {
+	err := writeDbStatus(os.Stdout)
+	if err != nil {
+		logger.FatalError(err, "Error reading migration status")
+	}
+}
+
+// writeDbStatus writes the schema version of the database and every known
+// migration with when it was applied, or "pending".
+func writeDbStatus(w io.Writer) error {
+	status, err := db.GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Schema version %d of %d\n\n", version, db.LatestSchemaVersion())
+	for _, s := range status {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "  %3d  %-19s  %s\n", s.Version, applied, s.Name)
+	}
+	return nil
+}
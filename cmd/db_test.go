@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+// oldDatabase writes a database with the schema go-claude used before
+// migrations were introduced and returns its path.
+func oldDatabase(t *testing.T) string {
+	t.Helper()
+	fixture, err := os.ReadFile(filepath.Join("..", "db", "testdata", "baseline.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	conn, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer conn.Close()
+	_, err = conn.Exec(string(fixture))
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	return dbPath
+}
+
+func TestDbStatusPendingMigrations(t *testing.T) {
+	dbPath := oldDatabase(t)
+
+	err := openDatabase(dbStatusCmd, dbPath)
+	if err != nil {
+		t.Fatalf("openDatabase returned an error: %v", err)
+	}
+	var out bytes.Buffer
+	err = writeDbStatus(&out)
+	db.Close()
+	if err != nil {
+		t.Fatalf("writeDbStatus returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("Schema version 0 of %d", db.LatestSchemaVersion())) {
+		t.Errorf("Expected the database to be left at version 0, got:\n%s", out.String())
+	}
+	if pending := strings.Count(out.String(), "pending"); pending != db.LatestSchemaVersion() {
+		t.Errorf("Expected %d pending migrations, got %d:\n%s", db.LatestSchemaVersion(), pending, out.String())
+	}
+
+	// Any other command brings the database up to date.
+	err = openDatabase(chatCmd, dbPath)
+	if err != nil {
+		t.Fatalf("openDatabase returned an error: %v", err)
+	}
+	out.Reset()
+	err = writeDbStatus(&out)
+	db.Close()
+	if err != nil {
+		t.Fatalf("writeDbStatus returned an error: %v", err)
+	}
+	if strings.Contains(out.String(), "pending") {
+		t.Errorf("Expected every migration to be applied, got:\n%s", out.String())
+	}
+}
@@ -93,9 +93,25 @@ var deleteMessages = &cobra.Command{
 	},
 }
 
+// go-claude delete personas
+var deletePersonas = &cobra.Command{
+	Use:   "personas [name...]",
+	Short: "Delete personas by name.",
+	Long: `Delete one or more personas by name. Conversations that used a deleted persona keep
+    their own options and otherwise fall back to the global configuration.
+
+    go-claude delete personas reviewer -> Will prompt if you're sure before deleting the persona.
+    go-claude delete personas reviewer translator -y -> Deletes both personas without a prompt.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliDeletePersonas(cmd, args)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
-	deleteCmd.AddCommand(deleteConversation, deleteMessages)
+	deleteCmd.AddCommand(deleteConversation, deleteMessages, deletePersonas)
+	deletePersonas.Flags().BoolP("yes", "y", false, "Automatically confirm without prompts.")
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -170,3 +186,26 @@ func cliDelteConversation(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+func cliDeletePersonas(cmd *cobra.Command, args []string) {
+	confirm, _ := cmd.Flags().GetBool("yes")
+	for _, name := range args {
+		persona, err := db.GetPersona(name)
+		if err != nil {
+			logger.PanicError(err, "Error getting persona")
+		}
+		if persona == nil {
+			fmt.Printf("No persona named %s.\n", name)
+			continue
+		}
+		if !confirm && !cliui.PromptForBool("Are you sure you want to delete persona %s?", name) {
+			fmt.Println("Cancelling request to delete persona.")
+			continue
+		}
+		err = db.DeletePersona(name)
+		if err != nil {
+			logger.PanicError(err, "Error deleting persona")
+		}
+		fmt.Printf("Successfully deleted persona %s.\n", name)
+	}
+}
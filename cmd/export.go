@@ -7,34 +7,89 @@ package cmd
 import (
 	"fmt"
 
+	cliui "github.com/christianhturner/go-claude/cli-ui"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/export"
+	"github.com/christianhturner/go-claude/logger"
 	"github.com/spf13/cobra"
 )
 
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "Export conversations to Markdown, JSON, JSONL or HTML files.",
+	Long: `Export writes conversations to files you can read, share or import again. Follow
+    this command with a supported subcommand.
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+    go-claude export conversations --id 1 --format md`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("export called")
+		fmt.Println("Please provide a subcommand [conversations]")
+	},
+}
+
+// go-claude export conversations
+var exportConversationsCmd = &cobra.Command{
+	Use:   "conversations",
+	Short: "Export a conversation, or all of them.",
+	Long: `Export a conversation with its title, options, and every message with its role and
+    timestamp. The supported formats are md (Markdown), json, jsonl (one message per line)
+    and html. Markdown and HTML show the active branch of the conversation, JSON and JSONL
+    hold every message of every branch along with the files attached to them.
+
+    go-claude export conversations --id 1 -> Writes 1-<title>.md to the current directory.
+    go-claude export conversations --id 1 --format html --out chat.html
+    go-claude export conversations --id 1 --format json --out - -> Writes to stdout.
+    go-claude export conversations --all --format json --out backup -> One file per conversation in ./backup.
+    go-claude export conversations --all --out backup.zip -> One file per conversation in a zip archive.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliExportConversations(cmd, args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportConversationsCmd)
+	exportCmdFlags()
+}
 
-	// Here you will define your flags and configuration settings.
+func cliExportConversations(cmd *cobra.Command, args []string) {
+	format, err := export.ParseFormat(exportFormat)
+	if err != nil {
+		logger.FatalError(err, "Error reading --format")
+	}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// exportCmd.PersistentFlags().String("foo", "", "A help for foo")
+	if exportAll {
+		out := exportOut
+		if out == "" {
+			out = "go-claude-export"
+		}
+		written, err := export.ExportAll(format, out)
+		if err != nil {
+			logger.FatalError(err, "Error exporting conversations")
+		}
+		fmt.Printf("Exported %d conversations to %s\n", len(written), out)
+		return
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// exportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	if conversationId == 0 {
+		conversationId = cliui.PromptForConversationId()
+	}
+	out := exportOut
+	if out == "" {
+		conversation, err := db.GetConversation(conversationId)
+		if err != nil {
+			logger.FatalError(err, "Error getting conversation")
+		}
+		if conversation == nil {
+			logger.FatalError(fmt.Errorf("conversation %d not found", conversationId), "Error exporting conversation")
+		}
+		out = export.FileName(conversation.ID, conversation.Title, format)
+	}
+	err = export.ExportConversation(conversationId, format, out)
+	if err != nil {
+		logger.FatalError(err, "Error exporting conversation")
+	}
+	if out != "-" {
+		fmt.Printf("Exported conversation %d to %s\n", conversationId, out)
+	}
 }
@@ -1,26 +1,84 @@
 package cmd
 
+import (
+	"github.com/christianhturner/go-claude/chat"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// settingFlags maps the global flags that can be set per conversation to the
+// conversation option they override.
+var settingFlags = map[string]string{
+	"model":       db.OptionModel,
+	"max-tokens":  db.OptionMaxTokens,
+	"temperature": db.OptionTemperature,
+	"top-p":       db.OptionTopP,
+	"top-k":       db.OptionTopK,
+	"stream":      db.OptionStream,
+
+	"thinking-budget": db.OptionThinkingBudget,
+
+	"context-strategy":    db.OptionContextStrategy,
+	"context-keep-first":  db.OptionContextKeepFirst,
+	"context-budget":      db.OptionContextBudget,
+	"compact-threshold":   db.OptionCompactThreshold,
+	"compact-keep-recent": db.OptionCompactKeepRecent,
+}
+
 var (
-	confirm           bool   // false, "--yes", "-y"
-	userMessage       string // "", "--message", "-m"
-	showHistory       bool   // true, "--history", "-H"
-	conversationTitle string // "", "--title", "-t"
-	conversationId    int64  // 0, "--id"
-	messageId         int64  // 0, "--messId"
-	messageIds        string // "", "--messIds"
+	confirm           bool     // false, "--yes", "-y"
+	userMessage       string   // "", "--message", "-m"
+	showHistory       bool     // true, "--history", "-H"
+	conversationTitle string   // "", "--title", "-t"
+	conversationId    int64    // 0, "--id"
+	messageId         int64    // 0, "--messId"
+	messageIds        string   // "", "--messIds"
+	attachPaths       []string // nil, "--attach", "-a"
+	systemPrompt      string   // "", "--system"
+	personaName       string   // "", "--persona"
+	searchRole        string   // "", "--role"
+	searchSince       string   // "", "--since"
+	searchLimit       int      // 20, "--limit"
+	searchRaw         bool     // false, "--raw"
+	exportFormat      string   // "md", "--format", "-f"
+	exportOut         string   // "", "--out", "-o"
+	exportAll         bool     // false, "--all"
+	importFormat      string   // "", "--format", "-f"
+	compactKeep       int      // 0, "--keep"
+	compactClear      bool     // false, "--clear"
+	statsSince        string   // "", "--since"
+	statsBy           string   // "", "--by"
+	forceBudget       bool     // false, "--force"
+	tokensOffline     bool     // false, "--offline"
+	batchOut          string   // "", "--out", "-o"
+	batchFormat       string   // "jsonl", "--format", "-f"
+	modelsRefresh     bool     // false, "--refresh"
 )
 
 func chatCmdFlags() {
 	chatCmd.Flags().StringVarP(&userMessage, "message", "m", "", "Send a message to Claude")
 	chatCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
 	chatCmd.Flags().BoolVarP(&showHistory, "history", "H", true, "Specify whether you want to see your last messages")
+	chatCmd.Flags().StringArrayVarP(&attachPaths, "attach", "a", nil, "Attach a file (text, image or PDF) to the message. Can be repeated.")
+	chatCmd.Flags().StringVar(&systemPrompt, "system", "", "Set the system prompt used for every turn of the conversation")
+	chatCmd.Flags().StringVar(&personaName, "persona", "", "Use a stored persona for every turn of the conversation")
+	chatCmd.Flags().BoolVar(&forceBudget, "force", false, "Send requests even when a spending budget is exceeded")
 }
 
 func configureCmdFlags() {
+	configureConversationCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
+	configureConversationCmd.Flags().StringVar(&systemPrompt, "system", "", "System prompt used for every turn of the conversation. Pass \"\" to remove it.")
+	configureConversationCmd.Flags().StringVar(&personaName, "persona", "", "Stored persona used for every turn of the conversation. Pass \"\" to remove it.")
+	configureConversationCmd.Flags().StringArray("unset", nil, "Remove a conversation option so it falls back to the global configuration. Can be repeated.")
 }
 
 func createCmdFlags() {
 	createCmd.Flags().StringVarP(&conversationTitle, "title", "t", "", "Title for conversation.")
+	createCmd.Flags().StringVar(&systemPrompt, "system", "", "System prompt used for every turn of the conversation.")
+	createCmd.Flags().StringVar(&personaName, "persona", "", "Stored persona used for every turn of the conversation.")
+	createPersonaCmd.Flags().StringVar(&personaName, "name", "", "Name of the persona.")
+	createPersonaCmd.Flags().StringVar(&systemPrompt, "system", "", "System prompt of the persona.")
 }
 
 func deleteCmdFlags() {
@@ -32,9 +90,58 @@ func deleteCmdFlags() {
 }
 
 func exportCmdFlags() {
+	exportConversationsCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
+	exportConversationsCmd.Flags().StringVarP(&exportFormat, "format", "f", "md", "Export format: md, json, jsonl or html")
+	exportConversationsCmd.Flags().StringVarP(&exportOut, "out", "o", "", "File to write, - for stdout. With --all, a directory or a .zip archive")
+	exportConversationsCmd.Flags().BoolVar(&exportAll, "all", false, "Export every conversation, one file each")
 }
 
 func importCmdFlags() {
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "Format of the files: go-claude, go-claude-jsonl, claude.ai or chatgpt (Default: detected)")
+}
+
+func forkCmdFlags() {
+	forkCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
+	forkCmd.Flags().Int64Var(&messageId, "messId", 0, "Specify the message to continue from by it's ID")
+}
+
+func compactCmdFlags() {
+	compactCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
+	compactCmd.Flags().IntVar(&compactKeep, "keep", 0, "Number of the latest message pairs left out of the summary (Default: --compact-keep-recent)")
+	compactCmd.Flags().BoolVar(&compactClear, "clear", false, "Delete the conversation's summaries so its full history is sent again")
+	compactCmd.Flags().BoolVar(&forceBudget, "force", false, "Summarize even when a spending budget is exceeded")
+}
+
+func statsCmdFlags() {
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "Only count requests sent after a date (2024-06-01) or within an age (36h, 7d, 2w)")
+	statsCmd.Flags().Int64Var(&conversationId, "id", 0, "Only count requests of the conversation with this ID")
+	statsCmd.Flags().StringVar(&statsBy, "by", "", "Only break usage down by model, day or conversation (Default: all three)")
+}
+
+func tokensCmdFlags() {
+	tokensCmd.Flags().Int64Var(&conversationId, "id", 0, "Specify a Conversation by it's ID")
+	tokensCmd.Flags().StringVarP(&userMessage, "message", "m", "", "Count the request that would send this message")
+	tokensCmd.Flags().StringArrayVarP(&attachPaths, "attach", "a", nil, "Attach a file (text, image or PDF) to the message. Can be repeated.")
+	tokensCmd.Flags().BoolVar(&tokensOffline, "offline", false, "Estimate the tokens instead of counting them with the API")
+}
+
+func batchCmdFlags() {
+	batchSubmitCmd.Flags().BoolVar(&forceBudget, "force", false, "Submit even when a spending budget is exceeded")
+	batchResultsCmd.Flags().StringVarP(&batchOut, "out", "o", "", "File to write, - for stdout (Default: stdout)")
+	batchResultsCmd.Flags().StringVarP(&batchFormat, "format", "f", "jsonl", "Results format: jsonl or text")
+}
+
+func modelsCmdFlags() {
+	modelsCmd.Flags().BoolVar(&modelsRefresh, "refresh", false, "List the models with the API again instead of using the cached list")
+}
+
+func searchCmdFlags() {
+	searchCmd.Flags().StringVar(&searchRole, "role", "", "Only match messages sent by this role (user or assistant)")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Only match messages sent after a date (2024-06-01) or within an age (36h, 7d, 2w)")
+	searchCmd.Flags().Int64Var(&conversationId, "id", 0, "Only match messages of the conversation with this ID")
+	searchCmd.Flags().Int64Var(&conversationId, "conversation", 0, "Same as --id")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "Maximum number of results")
+	searchCmd.Flags().BoolVar(&searchRaw, "raw", false, "Pass the query to SQLite FTS5 unchanged")
 }
 
 func listCmdFlags() {
@@ -43,3 +150,15 @@ func listCmdFlags() {
 func rootCmdFlags() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// flagOverrides returns the conversation options given through the global
+// flags on this invocation.
+func flagOverrides(cmd *cobra.Command) chat.Overrides {
+	overrides := chat.Overrides{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if option, ok := settingFlags[f.Name]; ok {
+			overrides[option] = f.Value.String()
+		}
+	})
+	return overrides
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/christianhturner/go-claude/chat"
+	cliui "github.com/christianhturner/go-claude/cli-ui"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(forkCmd)
+	forkCmdFlags()
+}
+
+// forkCmd represents the fork command
+var forkCmd = &cobra.Command{
+	Use:   "fork",
+	Short: "Continue a conversation from an earlier message.",
+	Long: `Fork makes an earlier message the end of the conversation's active branch. The
+    next message sent with chat replies to it, starting a new branch; the messages that
+    followed it are kept and can be switched back to with /branches and /switch inside
+    a chat session. Forking on one of your own messages replaces it: the next message
+    follows the reply before it.
+
+    go-claude fork --id 1 --messId 12
+    go-claude chat --id 1 -m "Let's try that differently"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliFork(cmd, args)
+	},
+}
+
+func cliFork(cmd *cobra.Command, args []string) {
+	if conversationId == 0 {
+		conversationId = cliui.PromptForConversationId()
+	}
+	if messageId == 0 {
+		messageId = cliui.PromptForMessageId(conversationId)
+	}
+
+	message, err := chat.Fork(conversationId, messageId)
+	if err != nil {
+		logger.FatalError(err, "Error forking conversation")
+	}
+	if message == nil {
+		fmt.Printf("Conversation %d now starts a new branch from its beginning.\n", conversationId)
+		return
+	}
+	fmt.Printf("Conversation %d now continues after message %d (%s): %s\n", conversationId, message.ID, message.Role, message.Content)
+}
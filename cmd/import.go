@@ -7,34 +7,66 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/christianhturner/go-claude/importer"
+	"github.com/christianhturner/go-claude/logger"
 	"github.com/spf13/cobra"
 )
 
 // importCmd represents the import command
 var importCmd = &cobra.Command{
-	Use:   "import",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Use:   "import [file...]",
+	Short: "Import conversations exported by go-claude, claude.ai or ChatGPT.",
+	Long: `Import reads conversation exports and stores them as go-claude conversations, keeping
+    titles, timestamps, branches and text attachments. The supported formats are:
+
+    go-claude        a JSON file written by export conversations --format json
+    go-claude-jsonl  a JSONL file written by export conversations --format jsonl
+    claude.ai        conversations.json from a claude.ai data export
+    chatgpt          conversations.json from a ChatGPT data export
+
+    The format is detected from the file; pass --format when that fails. Each file is imported
+    in a single transaction, so a file that fails to import leaves nothing behind. Importing a
+    conversation again is skipped. Messages that cannot be sent to Claude, such as system
+    messages, tool calls or images missing from the export, are left out and listed.
+
+    go-claude import conversation.json
+    go-claude import --format chatgpt ~/Downloads/chatgpt-export/conversations.json`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("import called")
+		cliImport(cmd, args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(importCmd)
+	importCmdFlags()
+}
 
-	// Here you will define your flags and configuration settings.
+func cliImport(cmd *cobra.Command, args []string) {
+	var format importer.Format
+	if importFormat != "" {
+		var err error
+		format, err = importer.ParseFormat(importFormat)
+		if err != nil {
+			logger.FatalError(err, "Error reading --format")
+		}
+	}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// importCmd.PersistentFlags().String("foo", "", "A help for foo")
+	for _, path := range args {
+		report, err := importer.ImportFile(path, format)
+		if err != nil {
+			logger.FatalError(err, "Error importing "+path)
+		}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// importCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+		fmt.Printf("%s: imported %d conversations, skipped %d\n", path, len(report.Imported), len(report.Skipped))
+		for _, imported := range report.Imported {
+			fmt.Printf("  %d  %s (%d messages)\n", imported.ConversationID, imported.Title, imported.Messages)
+			for _, skipped := range imported.Skipped {
+				fmt.Printf("       left out %s\n", skipped)
+			}
+		}
+		for _, skipped := range report.Skipped {
+			fmt.Printf("  skipped %s: %s\n", skipped.Title, skipped.Reason)
+		}
+	}
 }
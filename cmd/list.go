@@ -13,7 +13,7 @@ import (
 
 func init() {
 	rootCmd.AddCommand(listCmd)
-	listCmd.AddCommand(listConversationsCmd, listMessagesCmd)
+	listCmd.AddCommand(listConversationsCmd, listMessagesCmd, listPersonasCmd)
 
 	// Here you will define your flags and configuration settings.
 
@@ -33,7 +33,7 @@ var listCmd = &cobra.Command{
 	Long: `list various items by following this command with a supported subcommand. You can
     list conversations, messages, global configuration, and conversation level configurations.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Please provide a subcommand [conversations, messages, global-configuration, or conversation-configuration]")
+		fmt.Println("Please provide a subcommand [conversations, messages, personas, global-configuration, or conversation-configuration]")
 	},
 }
 
@@ -56,6 +56,15 @@ var listMessagesCmd = &cobra.Command{
 	},
 }
 
+var listPersonasCmd = &cobra.Command{
+	Use:   "personas",
+	Short: "List personas in a table view",
+	Long:  "List personas in a table view that provides columns which include Name, Model, System Prompt, and Updated",
+	Run: func(cmd *cobra.Command, args []string) {
+		list.ShowPersonaList()
+	},
+}
+
 // go-claude
 
 func cliRunListConversationsFunction(cmd *cobra.Command, args []string) {
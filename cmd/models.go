@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/christianhturner/go-claude/chat"
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+	modelsCmdFlags()
+}
+
+// modelsCmd represents the models command
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models available, with their limits and prices.",
+	Long: `Models lists the models the API offers your key, with the context window, largest
+    max tokens, image support and price per million tokens go-claude knows for them. The
+    configured model is marked with *.
+
+    The list is fetched once and kept in the database; --refresh fetches it again, e.g.
+    after a new model is released. Only listed models, and those go-claude knows, can be
+    configured with --model.
+
+    go-claude models
+    go-claude models --refresh`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliModels(cmd, args)
+	},
+}
+
+func cliModels(cmd *cobra.Command, args []string) {
+	models, err := db.GetModels()
+	if err != nil {
+		logger.FatalError(err, "Error getting cached models")
+	}
+	if modelsRefresh || len(models) == 0 {
+		refreshed, err := chat.RefreshModels(context.Background(), newClient(false))
+		switch {
+		case err == nil:
+			models = refreshed
+		case modelsRefresh:
+			logger.FatalError(err, "Error listing models")
+		default:
+			fmt.Fprintf(os.Stderr, "Could not list the models with the API, showing the models go-claude knows: %v\n\n", err)
+		}
+	}
+
+	configured := config.GetString(config.ModelKey)
+	fmt.Printf("  %-30s  %-30s  %8s  %6s  %6s  %s\n", "MODEL", "NAME", "CONTEXT", "OUTPUT", "VISION", "PRICE IN/OUT")
+	if len(models) == 0 {
+		// The registry holds model families, matched by prefix.
+		known, _ := claude.LookupModel(configured)
+		for _, info := range claude.KnownModels {
+			printModel(info.ID, info.Name, known.ID == info.ID)
+		}
+		return
+	}
+	for _, m := range models {
+		printModel(m.ID, m.DisplayName, configured == m.ID)
+	}
+	fmt.Printf("\nListed by the API on %s.\n", models[0].FetchedAt.Local().Format(time.DateTime))
+}
+
+// printModel prints a row of the models table, with what the registry knows
+// about the model and its configured price.
+func printModel(id, name string, current bool) {
+	marker := " "
+	if current {
+		marker = "*"
+	}
+	info, ok := claude.LookupModel(id)
+	if !ok {
+		fmt.Printf("%s %-30s  %-30s  %8s  %6s  %6s  %s\n", marker, id, name, "?", "?", "?", "?")
+		return
+	}
+	if name == "" {
+		name = info.Name
+	}
+	vision := "no"
+	if info.Vision {
+		vision = "yes"
+	}
+	price := "?"
+	if p, ok := config.PriceOf(id); ok {
+		price = fmt.Sprintf("$%g/$%g", p.Input, p.Output)
+	}
+	fmt.Printf("%s %-30s  %-30s  %8d  %6d  %6s  %s\n",
+		marker, id, name, info.ContextWindow, info.MaxOutputTokens, vision, price)
+}
@@ -31,6 +31,9 @@ to quickly create a Cobra application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initDB(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -43,7 +46,7 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(config.InitConfig, logger.InitLogger, initDB, sessionInit)
+	cobra.OnInitialize(config.InitConfig, logger.InitLogger, sessionInit)
 	config.AddFlags(rootCmd)
 
 	// Cobra also supports local flags, which will only run
@@ -61,7 +64,9 @@ func sessionInit() {
 	}()
 }
 
-func initDB() {
+// initDB opens the database the command runs against, migrating it first
+// unless the command is one of the db commands.
+func initDB(cmd *cobra.Command) {
 	home, err := os.UserHomeDir()
 	logger.FatalError(err, "Failed to get user home directory")
 
@@ -75,6 +80,16 @@ func initDB() {
 		_, err := os.Create(dbPath)
 		logger.LogError(err, "Failed to create database file")
 	}
-	err = db.InitDatabase(dbPath)
-	logger.FatalError(err, "Failed to initialize database")
+	err = openDatabase(cmd, dbPath)
+	logger.FatalError(err, "Failed to initialize database, see go-claude db status")
+}
+
+// openDatabase opens the database at dbPath. The db commands see it as it is,
+// so db status can list pending migrations and db migrate can apply them, or
+// report why they fail. Every other command brings the schema up to date.
+func openDatabase(cmd *cobra.Command, dbPath string) error {
+	if cmd == dbCmd || cmd.Parent() == dbCmd {
+		return db.Open(dbPath)
+	}
+	return db.InitDatabase(dbPath)
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/christianhturner/go-claude/search"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmdFlags()
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search \"query\"",
+	Short: "Search the messages of every conversation.",
+	Long: `Search finds messages containing every word of the query, across all conversations and
+    branches, and lists them best match first with the matching words highlighted.
+
+    Words ending in * match as a prefix. Pass --raw to use the full SQLite FTS5 query syntax
+    instead, e.g. OR, NOT, NEAR() and "quoted phrases".
+
+    go-claude search "channel close"
+    go-claude search goroutine* --role assistant --since 7d
+    go-claude search --raw '"context window" OR tokens' --id 3`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cliSearch(cmd, args)
+	},
+}
+
+func cliSearch(cmd *cobra.Command, args []string) {
+	query := strings.Join(args, " ")
+	if !searchRaw {
+		query = db.QuoteSearchQuery(query)
+	}
+
+	opts := db.SearchOptions{
+		Role:           searchRole,
+		ConversationID: conversationId,
+		Limit:          searchLimit,
+	}
+	if searchSince != "" {
+		since, err := search.ParseSince(searchSince)
+		if err != nil {
+			logger.FatalError(err, "Error parsing --since")
+		}
+		opts.Since = since
+	}
+
+	err := search.ShowSearchResults(query, opts)
+	if err != nil {
+		logger.FatalError(err, "Error searching messages")
+	}
+}
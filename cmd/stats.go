@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/christianhturner/go-claude/chat"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/christianhturner/go-claude/search"
+	"github.com/christianhturner/go-claude/stats"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmdFlags()
+}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the tokens used and what they cost.",
+	Long: `Stats adds up the tokens of every request sent to Claude, replies and conversation
+    summaries alike, and shows them with their cost per model, per day and per conversation.
+    Usage of deleted conversations still counts, and so do the results of go-claude batch,
+    at the batch price of half the list price. Tokens written to the prompt cache (Cache W)
+    cost a quarter more than other input tokens, those read from it (Cache R) a tenth.
+
+    Costs use the list prices per million tokens built into go-claude. Prices for other
+    models, or different ones, can be set in the config file:
+
+    "pricing": {"claude-3-5-sonnet": {"input": 3, "output": 15}}
+
+    go-claude stats
+    go-claude stats --since 7d --by day
+    go-claude stats --id 3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliStats(cmd, args)
+	},
+}
+
+func cliStats(cmd *cobra.Command, args []string) {
+	filter := db.UsageFilter{ConversationID: conversationId}
+	if statsSince != "" {
+		since, err := search.ParseSince(statsSince)
+		if err != nil {
+			logger.FatalError(err, "Error parsing --since")
+		}
+		filter.Since = since
+	}
+
+	groups := stats.Groups
+	if statsBy != "" {
+		groups = nil
+		for _, group := range stats.Groups {
+			if string(group) == statsBy {
+				groups = append(groups, group)
+			}
+		}
+		if len(groups) == 0 {
+			logger.FatalError(fmt.Errorf("unknown group %q, expected model, day or conversation", statsBy), "Error parsing --by")
+		}
+	}
+
+	err := stats.ShowStats(groups, filter)
+	if err != nil {
+		logger.FatalError(err, "Error reading usage")
+	}
+
+	limits, err := chat.GlobalBudget().Limits(time.Now())
+	if err != nil {
+		logger.FatalError(err, "Error reading budgets")
+	}
+	if len(limits) > 0 {
+		fmt.Println("\nBudgets")
+		for _, l := range limits {
+			fmt.Printf("  %s\n", l)
+		}
+	}
+}
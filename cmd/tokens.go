@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/christianhturner/go-claude/chat"
+	cliui "github.com/christianhturner/go-claude/cli-ui"
+	"github.com/christianhturner/go-claude/logger"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	tokensCmdFlags()
+}
+
+// tokensCmd represents the tokens command
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Count the input tokens of a conversation's next request.",
+	Long: `Tokens shows how many input tokens the next request of a conversation would be billed
+    for, and what they would cost, without sending it. The request is built as chat would
+    build it: with the conversation's summary, its history fitted to the context budget,
+    and the message and attachments given, if any.
+
+    Tokens are counted by the API's count_tokens endpoint, which is free. With --offline,
+    or when counting fails, they are estimated at about four characters per token.
+
+    go-claude tokens --id 1
+    go-claude tokens --id 1 -m "And what about @./main.go?"
+    go-claude tokens --id 1 --model claude-3-haiku-20240307 --offline`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cliTokens(cmd, args)
+	},
+}
+
+func cliTokens(cmd *cobra.Command, args []string) {
+	if conversationId == 0 {
+		conversationId = cliui.PromptForConversationId()
+	}
+
+	client := newClient(false)
+	session, err := chat.NewSession(conversationId, client, flagOverrides(cmd))
+	if err != nil {
+		logger.FatalError(err, "Error resolving conversation settings")
+	}
+	session.AttachToNextMessage(attachPaths)
+	body, report, err := session.NextRequest(userMessage)
+	if err != nil {
+		logger.FatalError(err, "Error building the request")
+	}
+	if len(body.Messages) == 0 {
+		logger.FatalError(fmt.Errorf("conversation %d has no messages, pass one with -m", conversationId), "Nothing to count")
+	}
+
+	count := chat.CountInputTokens(context.Background(), client, body, !tokensOffline)
+	if count.Err != nil {
+		fmt.Printf("Could not count with the API, estimating instead: %v\n\n", count.Err)
+	}
+	fmt.Printf("Model:    %s\n", body.Model)
+	fmt.Printf("Messages: %d\n", len(body.Messages))
+	if report.DroppedMessages > 0 {
+		fmt.Printf("          %s are left out, about %d tokens\n", report.LeftOut(), report.DroppedTokens)
+	}
+	fmt.Printf("Input:    %s\n", count)
+	budget := chat.ContextBudget(session.Settings)
+	fmt.Printf("Context:  %.0f%% of the %d token budget\n", 100*float64(count.InputTokens)/float64(budget), budget)
+}
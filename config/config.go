@@ -19,37 +19,73 @@ type ConfigItem struct {
 }
 
 var (
-	DataDir           = ""
-	CfgFile           = ""
-	DbFile            = ""
-	LogLevel          = "INFO"
-	AnthropicApiKey   = ""
-	AnthropicUrl      = "https://api.anthropic.com/"
-	AnthropicEndpoint = "v1/messages"
-	AnthropicVersion  = "2023-06-01"
-	AnthropicBeta     = ""
-	MaxTokens         = 2000
-	Model             = "claude-3-5-sonnet-20240620"
-	Stream            = true
-	Temperature       float64 // might should be a string in order to support an empty value?
-	TopP              float64 // might should be a string in order to support an empty value?
-	TopK              float64 // might should be a string in order to support an empty value?
-
-	DataDirKey           = "data_dir"
-	CfgFileKey           = "cfg_file"
-	DbFileKey            = "db_file"
-	LogLevelKey          = "log_level"
-	AnthropicApiKeyKey   = "anthropic_api_key"
-	AnthropicUrlKey      = "anthropic_url"
-	AnthropicEndpointKey = "anthropic_endpoint"
-	AnthropicVersionKey  = "anthropic_version"
-	AnthripicBetaKey     = "anthropic_beta"
-	MaxTokensKey         = "max_tokens"
-	ModelKey             = "model_key"
-	StreamKey            = "enableHttpStream"
-	TemperatureKey       = "temperature_key"
-	TopPKey              = "top_p"
-	TopKKey              = "top_k"
+	DataDir              = ""
+	CfgFile              = ""
+	DbFile               = ""
+	LogLevel             = "INFO"
+	AnthropicApiKey      = ""
+	AnthropicUrl         = "https://api.anthropic.com/"
+	AnthropicEndpoint    = "v1/messages"
+	AnthropicVersion     = "2023-06-01"
+	AnthropicBeta        = ""
+	MaxTokens            = 2000
+	Model                = "claude-3-5-sonnet-20240620"
+	Stream               = true
+	Temperature          float64 // might should be a string in order to support an empty value?
+	TopP                 float64 // might should be a string in order to support an empty value?
+	TopK                 float64 // might should be a string in order to support an empty value?
+	ThinkingBudget       = 0
+	ContextStrategy      = "drop-oldest"
+	ContextKeepFirst     = 1
+	ContextBudget        = 0
+	CompactThreshold     = 80
+	CompactKeepRecent    = 4
+	BudgetDailyTokens    = 0
+	BudgetMonthlyTokens  = 0
+	BudgetDailyUSD       float64
+	BudgetMonthlyUSD     float64
+	BudgetWarnPercent    = 80
+	MaxInputTokens       = 0
+	MaxRetries           = 4
+	RetryMaxElapsed      = 120
+	RequestsPerMinute    = 0
+	InputTokensPerMinute = 0
+	CountTokens          = true
+	PromptCaching        = true
+
+	DataDirKey              = "data_dir"
+	CfgFileKey              = "cfg_file"
+	DbFileKey               = "db_file"
+	LogLevelKey             = "log_level"
+	AnthropicApiKeyKey      = "anthropic_api_key"
+	AnthropicUrlKey         = "anthropic_url"
+	AnthropicEndpointKey    = "anthropic_endpoint"
+	AnthropicVersionKey     = "anthropic_version"
+	AnthripicBetaKey        = "anthropic_beta"
+	MaxTokensKey            = "max_tokens"
+	ModelKey                = "model_key"
+	StreamKey               = "enableHttpStream"
+	TemperatureKey          = "temperature_key"
+	TopPKey                 = "top_p"
+	TopKKey                 = "top_k"
+	ThinkingBudgetKey       = "thinking_budget"
+	ContextStrategyKey      = "context_strategy"
+	ContextKeepFirstKey     = "context_keep_first"
+	ContextBudgetKey        = "context_budget"
+	CompactThresholdKey     = "compact_threshold"
+	CompactKeepRecentKey    = "compact_keep_recent"
+	BudgetDailyTokensKey    = "budget_daily_tokens"
+	BudgetMonthlyTokensKey  = "budget_monthly_tokens"
+	BudgetDailyUSDKey       = "budget_daily_usd"
+	BudgetMonthlyUSDKey     = "budget_monthly_usd"
+	BudgetWarnPercentKey    = "budget_warn_percent"
+	MaxInputTokensKey       = "max_input_tokens"
+	MaxRetriesKey           = "max_retries"
+	RetryMaxElapsedKey      = "retry_max_elapsed"
+	RequestsPerMinuteKey    = "requests_per_minute"
+	InputTokensPerMinuteKey = "input_tokens_per_minute"
+	CountTokensKey          = "count_tokens"
+	PromptCachingKey        = "prompt_caching"
 )
 
 var ConfigItems = []ConfigItem{
@@ -69,6 +105,24 @@ var ConfigItems = []ConfigItem{
 	{Flag: "temperature", ConfigKey: TemperatureKey, Value: &Temperature},
 	{Flag: "top-p", ConfigKey: TopPKey, Value: &TopP},
 	{Flag: "top-k", ConfigKey: TopKKey, Value: &TopK},
+	{Flag: "thinking-budget", ConfigKey: ThinkingBudgetKey, Value: &ThinkingBudget},
+	{Flag: "context-strategy", ConfigKey: ContextStrategyKey, Value: &ContextStrategy},
+	{Flag: "context-keep-first", ConfigKey: ContextKeepFirstKey, Value: &ContextKeepFirst},
+	{Flag: "context-budget", ConfigKey: ContextBudgetKey, Value: &ContextBudget},
+	{Flag: "compact-threshold", ConfigKey: CompactThresholdKey, Value: &CompactThreshold},
+	{Flag: "compact-keep-recent", ConfigKey: CompactKeepRecentKey, Value: &CompactKeepRecent},
+	{Flag: "budget-daily-tokens", ConfigKey: BudgetDailyTokensKey, Value: &BudgetDailyTokens},
+	{Flag: "budget-monthly-tokens", ConfigKey: BudgetMonthlyTokensKey, Value: &BudgetMonthlyTokens},
+	{Flag: "budget-daily-usd", ConfigKey: BudgetDailyUSDKey, Value: &BudgetDailyUSD},
+	{Flag: "budget-monthly-usd", ConfigKey: BudgetMonthlyUSDKey, Value: &BudgetMonthlyUSD},
+	{Flag: "budget-warn-percent", ConfigKey: BudgetWarnPercentKey, Value: &BudgetWarnPercent},
+	{Flag: "max-input-tokens", ConfigKey: MaxInputTokensKey, Value: &MaxInputTokens},
+	{Flag: "max-retries", ConfigKey: MaxRetriesKey, Value: &MaxRetries},
+	{Flag: "retry-max-elapsed", ConfigKey: RetryMaxElapsedKey, Value: &RetryMaxElapsed},
+	{Flag: "requests-per-minute", ConfigKey: RequestsPerMinuteKey, Value: &RequestsPerMinute},
+	{Flag: "input-tokens-per-minute", ConfigKey: InputTokensPerMinuteKey, Value: &InputTokensPerMinute},
+	{Flag: "count-tokens", ConfigKey: CountTokensKey, Value: &CountTokens},
+	{Flag: "prompt-caching", ConfigKey: PromptCachingKey, Value: &PromptCaching},
 }
 
 func AddFlags(cmd *cobra.Command) {
@@ -92,7 +146,7 @@ func AddFlags(cmd *cobra.Command) {
 
 	cmd.PersistentFlags().IntVar(&MaxTokens, "max-tokens", MaxTokens, "Specifies the maximum number of tokens for the conversation. (Global)")
 
-	cmd.PersistentFlags().StringVar(&Model, "model", Model, "Specifies the Claude model to use. (Global, Default: claude-3-5-sonnet-20240620, Options: claude-3-5-sonnet, claude-3-sonnet, claude-3-haiku, claude-3-opus)")
+	cmd.PersistentFlags().StringVar(&Model, "model", Model, "Specifies the Claude model to use, see go-claude models for the options. (Global, Default: claude-3-5-sonnet-20240620)")
 
 	cmd.PersistentFlags().BoolVar(&Stream, "stream", Stream, "Enables Http streaming within the Anthropic Client and provides real-time delivery of message generation. (Global, Default: True)")
 
@@ -101,6 +155,42 @@ func AddFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().Float64Var(&TopP, "top-p", TopP, "Specifies the top-p value for response generation. (Global)")
 
 	cmd.PersistentFlags().Float64Var(&TopK, "top-k", TopK, "Specifies the top-k value for response generation. (Global)")
+
+	cmd.PersistentFlags().IntVar(&ThinkingBudget, "thinking-budget", ThinkingBudget, "Specifies how many tokens Claude may think for before replying, at least 1024 and less than max tokens. Models without extended thinking need 0. (Global, Default: 0, off)")
+
+	cmd.PersistentFlags().StringVar(&ContextStrategy, "context-strategy", ContextStrategy, "Specifies how history that does not fit the context window is dropped. (Global, Default: drop-oldest, Options: drop-oldest, keep-first-last, none)")
+
+	cmd.PersistentFlags().IntVar(&ContextKeepFirst, "context-keep-first", ContextKeepFirst, "Specifies how many of the first message pairs the keep-first-last strategy keeps. (Global, Default: 1)")
+
+	cmd.PersistentFlags().IntVar(&ContextBudget, "context-budget", ContextBudget, "Specifies the most input tokens sent per request, 0 for the model's context window. (Global)")
+
+	cmd.PersistentFlags().IntVar(&CompactThreshold, "compact-threshold", CompactThreshold, "Specifies the share of the context budget, in percent, the history may fill before older messages are summarized, 0 to never summarize. (Global, Default: 80)")
+
+	cmd.PersistentFlags().IntVar(&CompactKeepRecent, "compact-keep-recent", CompactKeepRecent, "Specifies how many of the latest message pairs are sent as they are when a conversation is summarized. (Global, Default: 4)")
+
+	cmd.PersistentFlags().IntVar(&BudgetDailyTokens, "budget-daily-tokens", BudgetDailyTokens, "Specifies the most tokens, input and output, sent per day before requests are refused, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().IntVar(&BudgetMonthlyTokens, "budget-monthly-tokens", BudgetMonthlyTokens, "Specifies the most tokens, input and output, sent per month before requests are refused, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().Float64Var(&BudgetDailyUSD, "budget-daily-usd", BudgetDailyUSD, "Specifies the most US dollars spent per day before requests are refused, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().Float64Var(&BudgetMonthlyUSD, "budget-monthly-usd", BudgetMonthlyUSD, "Specifies the most US dollars spent per month before requests are refused, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().IntVar(&BudgetWarnPercent, "budget-warn-percent", BudgetWarnPercent, "Specifies the share of a budget, in percent, spent before every request warns about it. (Global, Default: 80)")
+
+	cmd.PersistentFlags().IntVar(&MaxInputTokens, "max-input-tokens", MaxInputTokens, "Specifies the most input tokens a single request may send before it is refused, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().IntVar(&MaxRetries, "max-retries", MaxRetries, "Specifies how many times a request rate limited, overloaded or failed on the server is sent again, 0 to never retry. (Global, Default: 4)")
+
+	cmd.PersistentFlags().IntVar(&RetryMaxElapsed, "retry-max-elapsed", RetryMaxElapsed, "Specifies the seconds after which a failing request is no longer retried, 0 for no limit. (Global, Default: 120)")
+
+	cmd.PersistentFlags().IntVar(&RequestsPerMinute, "requests-per-minute", RequestsPerMinute, "Specifies the most requests sent per minute, later ones wait, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().IntVar(&InputTokensPerMinute, "input-tokens-per-minute", InputTokensPerMinute, "Specifies the most estimated input tokens sent per minute, later requests wait, 0 for no limit. (Global)")
+
+	cmd.PersistentFlags().BoolVar(&CountTokens, "count-tokens", CountTokens, "Specifies whether input tokens shown before sending a request close to the context budget are counted by the API, or estimated offline. (Global, Default: true)")
+
+	cmd.PersistentFlags().BoolVar(&PromptCaching, "prompt-caching", PromptCaching, "Specifies whether chat marks the system prompt and history for prompt caching, so later turns read them from the cache for a tenth of the price. (Global, Default: true)")
 }
 
 func InitConfig() {
@@ -124,7 +214,7 @@ func InitConfig() {
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Printf("Using config file: %s\n", viper.ConfigFileUsed())
+		fmt.Fprintf(os.Stderr, "Using config file: %s\n", viper.ConfigFileUsed())
 	} else if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 		// Config file not found; ignore error if desired
 		viper.SafeWriteConfig()
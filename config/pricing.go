@@ -0,0 +1,101 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/christianhturner/go-claude/claude"
+	"github.com/spf13/viper"
+)
+
+// PricingKey holds prices in the config file that replace or add to
+// DefaultPricing, keyed by model name prefix:
+//
+//	"pricing": {"claude-3-5-sonnet": {"input": 3, "output": 15}}
+const PricingKey = "pricing"
+
+// ModelPrice is what a model costs in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64 `mapstructure:"input" json:"input"`
+	Output float64 `mapstructure:"output" json:"output"`
+}
+
+// DefaultPricing lists Anthropic's list prices by model name prefix, taken
+// from the model registry.
+var DefaultPricing = defaultPricing()
+
+func defaultPricing() map[string]ModelPrice {
+	pricing := make(map[string]ModelPrice, len(claude.KnownModels))
+	for _, info := range claude.KnownModels {
+		pricing[info.ID] = ModelPrice{Input: info.InputPrice, Output: info.OutputPrice}
+	}
+	return pricing
+}
+
+// Pricing returns DefaultPricing with the prices set in the config file
+// applied over it.
+func Pricing() map[string]ModelPrice {
+	pricing := make(map[string]ModelPrice, len(DefaultPricing))
+	for prefix, price := range DefaultPricing {
+		pricing[prefix] = price
+	}
+	var configured map[string]ModelPrice
+	err := viper.UnmarshalKey(PricingKey, &configured)
+	if err == nil {
+		for prefix, price := range configured {
+			pricing[strings.ToLower(prefix)] = price
+		}
+	}
+	return pricing
+}
+
+// PriceOf returns the price of a model, matched by the longest model name
+// prefix in Pricing, and false when the model has no price.
+func PriceOf(model string) (ModelPrice, bool) {
+	longest := ""
+	pricing := Pricing()
+	for prefix := range pricing {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return ModelPrice{}, false
+	}
+	return pricing[longest], true
+}
+
+// Cost returns what the tokens cost in US dollars on a model, and false when
+// the model has no price.
+func Cost(model string, inputTokens, outputTokens int64) (float64, bool) {
+	price, ok := PriceOf(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6, true
+}
+
+// BatchDiscount is the share of the list price message batches are billed at.
+const BatchDiscount = 0.5
+
+// Prompt cache writes and reads are billed at these shares of the input
+// price.
+const (
+	CacheWriteMultiplier = 1.25
+	CacheReadMultiplier  = 0.1
+)
+
+// UsageCost returns what a request cost on a model, its prompt cache writes
+// and reads included, at the batch price when batch is set, and false when
+// the model has no price.
+func UsageCost(model string, inputTokens, outputTokens, cacheWriteTokens, cacheReadTokens int64, batch bool) (float64, bool) {
+	price, ok := PriceOf(model)
+	if !ok {
+		return 0, false
+	}
+	input := float64(inputTokens) + CacheWriteMultiplier*float64(cacheWriteTokens) + CacheReadMultiplier*float64(cacheReadTokens)
+	cost := (input*price.Input + float64(outputTokens)*price.Output) / 1e6
+	if batch {
+		cost *= BatchDiscount
+	}
+	return cost, true
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// BatchJob is a message batch submitted from this database, with the status
+// the API last reported for it.
+type BatchJob struct {
+	ID            int64
+	BatchID       string // the API's, e.g. msgbatch_...
+	InputFile     string
+	Status        string // in_progress, canceling or ended
+	Requests      int64
+	Succeeded     int64
+	Errored       int64
+	Canceled      int64
+	Expired       int64
+	UsageRecorded bool // whether the usage of its results was added to message_usage
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	EndedAt       *time.Time
+}
+
+const batchJobColumns = `id, batch_id, input_file, status, requests, succeeded, errored, canceled, expired,
+	usage_recorded, created_at, updated_at, ended_at`
+
+// AddBatchJob records a batch submitted with the given number of requests
+// and returns the job's ID
+func AddBatchJob(batchID, inputFile, status string, requests int64) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO batch_jobs (batch_id, input_file, status, requests)
+		VALUES (?, ?, ?, ?)`, batchID, inputFile, status, requests)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdateBatchJob stores the status and request counts of a job
+func UpdateBatchJob(job BatchJob) error {
+	var endedAt interface{}
+	if job.EndedAt != nil {
+		endedAt = formatTimestamp(*job.EndedAt)
+	}
+	_, err := db.Exec(`
+		UPDATE batch_jobs
+		SET status = ?, succeeded = ?, errored = ?, canceled = ?, expired = ?, ended_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE batch_id = ?`,
+		job.Status, job.Succeeded, job.Errored, job.Canceled, job.Expired, endedAt, job.BatchID)
+	return err
+}
+
+// GetBatchJob retrieves the job of a batch, nil when the batch wasn't
+// submitted from this database
+func GetBatchJob(batchID string) (*BatchJob, error) {
+	jobs, err := queryBatchJobs(`SELECT `+batchJobColumns+` FROM batch_jobs WHERE batch_id = ?`, batchID)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// ListBatchJobs retrieves every job, most recent first
+func ListBatchJobs() ([]BatchJob, error) {
+	return queryBatchJobs(`SELECT ` + batchJobColumns + ` FROM batch_jobs ORDER BY id DESC`)
+}
+
+// RecordBatchUsage adds the usage of a batch's results, marked as sent
+// through a batch. It returns false, recording nothing, when the batch's
+// usage was already recorded.
+func RecordBatchUsage(batchID string, usage []Usage) (bool, error) {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE batch_jobs SET usage_recorded = 1 WHERE batch_id = ? AND usage_recorded = 0`, batchID)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if updated == 0 {
+		return false, nil
+	}
+	for _, u := range usage {
+		u.Batch = true
+		err = insertUsage(tx, u)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func queryBatchJobs(query string, args ...interface{}) ([]BatchJob, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []BatchJob
+	for rows.Next() {
+		var j BatchJob
+		var endedAt sql.NullTime
+		err := rows.Scan(&j.ID, &j.BatchID, &j.InputFile, &j.Status, &j.Requests, &j.Succeeded, &j.Errored,
+			&j.Canceled, &j.Expired, &j.UsageRecorded, &j.CreatedAt, &j.UpdatedAt, &endedAt)
+		if err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			j.EndedAt = &endedAt.Time
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchJobs(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	_, err = AddBatchJob("msgbatch_1", "first.jsonl", "in_progress", 2)
+	if err != nil {
+		t.Fatalf("AddBatchJob returned an error: %v", err)
+	}
+	_, err = AddBatchJob("msgbatch_2", "second.jsonl", "in_progress", 5)
+	if err != nil {
+		t.Fatalf("AddBatchJob returned an error: %v", err)
+	}
+
+	ended := time.Date(2024, 10, 8, 12, 30, 0, 0, time.UTC)
+	err = UpdateBatchJob(BatchJob{BatchID: "msgbatch_1", Status: "ended", Succeeded: 1, Errored: 1, EndedAt: &ended})
+	if err != nil {
+		t.Fatalf("UpdateBatchJob returned an error: %v", err)
+	}
+	job, err := GetBatchJob("msgbatch_1")
+	if err != nil || job == nil {
+		t.Fatalf("Expected the job, got %+v, %v", job, err)
+	}
+	if job.Status != "ended" || job.Requests != 2 || job.Succeeded != 1 || job.InputFile != "first.jsonl" || job.EndedAt == nil || !job.EndedAt.Equal(ended) {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	jobs, err := ListBatchJobs()
+	if err != nil || len(jobs) != 2 || jobs[0].BatchID != "msgbatch_2" || jobs[0].EndedAt != nil {
+		t.Errorf("Expected both jobs, most recent first, got %+v, %v", jobs, err)
+	}
+	missing, err := GetBatchJob("msgbatch_3")
+	if err != nil || missing != nil {
+		t.Errorf("Expected no job for an unknown batch, got %+v, %v", missing, err)
+	}
+
+	usage := []Usage{{Model: "claude-3-haiku-20240307", InputTokens: 100, OutputTokens: 10}}
+	recorded, err := RecordBatchUsage("msgbatch_1", usage)
+	if err != nil || !recorded {
+		t.Fatalf("Expected the usage to be recorded, got %v, %v", recorded, err)
+	}
+	recorded, err = RecordBatchUsage("msgbatch_1", usage)
+	if err != nil || recorded {
+		t.Errorf("Expected the usage to be recorded once, got %v, %v", recorded, err)
+	}
+	totals, err := GetUsageTotals(UsageByModel, UsageFilter{})
+	if err != nil || len(totals) != 1 || !totals[0].Batch || totals[0].InputTokens != 100 {
+		t.Errorf("Expected the batch usage once, got %+v, %v", totals, err)
+	}
+}
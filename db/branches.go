@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Messages form a tree: every message points at the one it replies to and a
+// conversation remembers the message its active branch ends on. New messages
+// are added below that message, so moving it to an earlier message and
+// continuing from there forks the conversation without losing any reply.
+
+const messageColumns = "m.id, m.conversation_id, m.parent_message_id, m.kind, m.role, m.content, m.thinking, m.created_at"
+
+// GetMessage retrieves a single message by ID
+func GetMessage(messageID int64) (*Message, error) {
+	rows, err := db.Query("SELECT "+messageColumns+" FROM messages m WHERE m.id = ?", messageID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil || len(messages) == 0 {
+		return nil, err // nil, nil when the message is not found
+	}
+	return &messages[0], nil
+}
+
+// GetActiveMessageID returns the message the conversation's active branch
+// ends on, 0 when the conversation has no messages yet or the next message
+// starts a new branch
+func GetActiveMessageID(conversationID int64) (int64, error) {
+	var activeID sql.NullInt64
+	err := db.QueryRow("SELECT active_message_id FROM conversations WHERE id = ?", conversationID).Scan(&activeID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return activeID.Int64, nil
+}
+
+// SetActiveMessage makes the branch ending on messageID the active one, the
+// next message added to the conversation replies to it. A messageID of 0
+// starts a new branch from the beginning of the conversation.
+func SetActiveMessage(conversationID, messageID int64) error {
+	if messageID == 0 {
+		_, err := db.Exec("UPDATE conversations SET active_message_id = NULL WHERE id = ?", conversationID)
+		return err
+	}
+	result, err := db.Exec(`
+		UPDATE conversations SET active_message_id = ?
+		WHERE id = ? AND EXISTS (SELECT 1 FROM messages WHERE id = ? AND conversation_id = ? AND kind = 'message')`,
+		messageID, conversationID, messageID, conversationID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("message %d is not part of conversation %d", messageID, conversationID)
+	}
+	return nil
+}
+
+// GetActiveBranch retrieves the messages of the conversation's active branch,
+// from the first message to the active one
+func GetActiveBranch(conversationID int64) ([]Message, error) {
+	activeID, err := GetActiveMessageID(conversationID)
+	if err != nil || activeID == 0 {
+		return nil, err
+	}
+	return GetBranch(activeID)
+}
+
+// GetBranch retrieves the messages leading to messageID, from the first
+// message of the conversation to messageID itself
+func GetBranch(messageID int64) ([]Message, error) {
+	rows, err := db.Query(`
+		WITH RECURSIVE branch(id, depth) AS (
+			SELECT id, 0 FROM messages WHERE id = ?
+			UNION ALL
+			SELECT m.parent_message_id, b.depth + 1 FROM messages m
+			JOIN branch b ON m.id = b.id
+			WHERE m.parent_message_id IS NOT NULL
+		)
+		SELECT `+messageColumns+` FROM messages m
+		JOIN branch b ON b.id = m.id
+		ORDER BY b.depth DESC`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// GetBranchLeaves retrieves the last message of every branch of a conversation,
+// oldest branch first
+func GetBranchLeaves(conversationID int64) ([]Message, error) {
+	rows, err := db.Query(`
+		SELECT `+messageColumns+` FROM messages m
+		WHERE m.conversation_id = ? AND m.kind = 'message'
+		AND NOT EXISTS (SELECT 1 FROM messages c WHERE c.parent_message_id = m.id AND c.kind = 'message')
+		ORDER BY m.created_at ASC, m.id ASC`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// GetLatestLeaf returns the most recent message among messageID and every
+// reply below it that has no reply of its own
+func GetLatestLeaf(messageID int64) (int64, error) {
+	var leafID int64
+	err := db.QueryRow(`
+		WITH RECURSIVE below(id) AS (
+			SELECT ?
+			UNION ALL
+			SELECT m.id FROM messages m JOIN below b ON m.parent_message_id = b.id WHERE m.kind = 'message'
+		)
+		SELECT b.id FROM below b
+		WHERE NOT EXISTS (SELECT 1 FROM messages c WHERE c.parent_message_id = b.id AND c.kind = 'message')
+		ORDER BY b.id DESC LIMIT 1`, messageID).Scan(&leafID)
+	return leafID, err
+}
+
+// insertMessage adds a message below the conversation's active message and
+// makes it the active one
+func insertMessage(tx *sql.Tx, conversationID int64, role, content string) (int64, error) {
+	var parentID sql.NullInt64
+	err := tx.QueryRow("SELECT active_message_id FROM conversations WHERE id = ?", conversationID).Scan(&parentID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	result, err := tx.Exec("INSERT INTO messages (conversation_id, parent_message_id, role, content) VALUES (?, ?, ?, ?)",
+		conversationID, parentID, role, content)
+	if err != nil {
+		return 0, err
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE conversations SET active_message_id = ? WHERE id = ?", messageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	return messageID, nil
+}
+
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		var parentID sql.NullInt64
+		err := rows.Scan(&m.ID, &m.ConversationID, &parentID, &m.Kind, &m.Role, &m.Content, &m.Thinking, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.ParentID = parentID.Int64
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
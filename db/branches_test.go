@@ -0,0 +1,91 @@
+package db
+
+import "testing"
+
+func TestForkAndSwitchBranches(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	convID, err := CreateConversation("Branches")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, content := range []string{"question", "answer", "follow up", "second answer"} {
+		role := "user"
+		if content == "answer" || content == "second answer" {
+			role = "assistant"
+		}
+		err := AddMessage(convID, role, content)
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+	}
+
+	branch, err := GetActiveBranch(convID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	if len(branch) != 4 || branch[1].ParentID != branch[0].ID {
+		t.Fatalf("Expected a single branch of 4 chained messages, got %+v", branch)
+	}
+	oldLeaf := branch[3].ID
+
+	// Fork after the first answer and ask something else.
+	err = SetActiveMessage(convID, branch[1].ID)
+	if err != nil {
+		t.Fatalf("SetActiveMessage returned an error: %v", err)
+	}
+	err = AddMessage(convID, "user", "different follow up")
+	if err != nil {
+		t.Fatalf("AddMessage returned an error: %v", err)
+	}
+
+	branch, err = GetActiveBranch(convID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	if len(branch) != 3 || branch[2].Content != "different follow up" {
+		t.Errorf("Expected the forked branch to hold 3 messages, got %+v", branch)
+	}
+
+	leaves, err := GetBranchLeaves(convID)
+	if err != nil {
+		t.Fatalf("GetBranchLeaves returned an error: %v", err)
+	}
+	if len(leaves) != 2 {
+		t.Errorf("Expected 2 branches, got %d", len(leaves))
+	}
+
+	leafID, err := GetLatestLeaf(branch[0].ID)
+	if err != nil {
+		t.Fatalf("GetLatestLeaf returned an error: %v", err)
+	}
+	if leafID != branch[2].ID {
+		t.Errorf("Expected the latest leaf to be %d, got %d", branch[2].ID, leafID)
+	}
+
+	err = SetActiveMessage(convID, oldLeaf)
+	if err != nil {
+		t.Fatalf("SetActiveMessage returned an error: %v", err)
+	}
+	err = SetActiveMessage(convID+1, oldLeaf)
+	if err == nil {
+		t.Errorf("Expected an error switching to a message of another conversation")
+	}
+
+	// Deleting a message keeps its replies on the branch.
+	err = DeleteMessage(branch[1].ID)
+	if err != nil {
+		t.Fatalf("DeleteMessage returned an error: %v", err)
+	}
+	branch, err = GetActiveBranch(convID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	if len(branch) != 3 || branch[0].Content != "question" || branch[1].Content != "follow up" {
+		t.Errorf("Expected the deleted message's replies to move up, got %+v", branch)
+	}
+}
@@ -1,7 +1,6 @@
 package db
 
 import (
-	"context"
 	"database/sql"
 
 	"github.com/christianhturner/go-claude/logger"
@@ -11,77 +10,26 @@ import (
 
 var db *sql.DB
 
+// InitDatabase opens the database and brings its schema up to date.
 func InitDatabase(dbPath string) error {
-	var err error
-	db, err = sql.Open("sqlite", dbPath)
-	if err != nil {
-		return err
-	}
-
-	err = createConversationsTable()
-	if err != nil {
-		return err
-	}
-
-	err = createMessagesTable()
-	if err != nil {
-		return err
-	}
-
-	err = createConversationOptionsTable()
+	err := Open(dbPath)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func Close() {
-	err := db.Close()
-	logger.WarnError(err, "Error closing database")
-}
-
-func createConversationsTable() error {
-	_, err := db.ExecContext(
-		context.Background(),
-		`CREATE TABLE IF NOT EXISTS conversations(
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            title TEXT NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
-	)
-	logger.LogError(err, "Failed to create conversations table")
+	_, err = Migrate()
+	logger.LogError(err, "Failed to migrate database")
 	return err
 }
 
-func createMessagesTable() error {
-	_, err := db.ExecContext(
-		context.Background(),
-		`CREATE TABLE IF NOT EXISTS messages (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    conversation_id INTEGER,
-    role TEXT CHECK(role IN ('user', 'assistant')),
-    content TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
-)`,
-	)
-	logger.LogError(err, "Failed to create messages table")
+// Open opens the database without touching its schema.
+func Open(dbPath string) error {
+	var err error
+	db, err = sql.Open("sqlite", dbPath)
 	return err
 }
 
-func createConversationOptionsTable() error {
-	_, err := db.ExecContext(
-		context.Background(),
-		`CREATE TABLE IF NOT EXISTS conversation_options(
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        conversation_id INTEGER,
-        option_name TEXT,
-        option_value TEXT,
-        FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
-        )`,
-	)
-	logger.LogError(err, "Failed to create conversation options table")
-	return err
+func Close() {
+	err := db.Close()
+	logger.WarnError(err, "Error closing database")
 }
@@ -0,0 +1,408 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one ordered step of the database schema. Migrations are only
+// ever appended: once released, a migration must not be edited, add a new one
+// instead.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// MigrationStatus reports whether a migration has been applied to the open
+// database.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create conversations, messages and conversation options",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE IF NOT EXISTS conversations(
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            title TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+				`CREATE TABLE IF NOT EXISTS messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id INTEGER,
+    role TEXT CHECK(role IN ('user', 'assistant')),
+    content TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+)`,
+				`CREATE TABLE IF NOT EXISTS conversation_options(
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        conversation_id INTEGER,
+        option_name TEXT,
+        option_value TEXT,
+        FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+        )`,
+			)
+		},
+	},
+	{
+		// Databases created before this migration have no unique constraint,
+		// which ConfigureConversation's ON CONFLICT clause requires. SQLite
+		// cannot add a constraint in place, so the table is rebuilt keeping
+		// the latest value of every duplicated option.
+		Version: 2,
+		Name:    "make conversation options unique per conversation",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE conversation_options_new(
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        conversation_id INTEGER,
+        option_name TEXT,
+        option_value TEXT,
+        UNIQUE(conversation_id, option_name),
+        FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+        )`,
+				`INSERT INTO conversation_options_new(id, conversation_id, option_name, option_value)
+        SELECT id, conversation_id, option_name, option_value FROM conversation_options
+        WHERE id IN (SELECT MAX(id) FROM conversation_options GROUP BY conversation_id, option_name)`,
+				`DROP TABLE conversation_options`,
+				`ALTER TABLE conversation_options_new RENAME TO conversation_options`,
+			)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create message attachments",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE IF NOT EXISTS message_attachments(
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        message_id INTEGER,
+        path TEXT,
+        kind TEXT CHECK(kind IN ('text', 'image', 'document')),
+        media_type TEXT,
+        data TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+        )`,
+			)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create personas",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE IF NOT EXISTS personas(
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE,
+        system_prompt TEXT NOT NULL DEFAULT '',
+        model TEXT NOT NULL DEFAULT '',
+        max_tokens INTEGER NOT NULL DEFAULT 0,
+        temperature REAL NOT NULL DEFAULT 0,
+        top_p REAL NOT NULL DEFAULT 0,
+        top_k REAL NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+			)
+		},
+	},
+	{
+		// Existing conversations become a single branch: every message's
+		// parent is the one sent before it and the last message is active.
+		Version: 5,
+		Name:    "add message parents and active branch",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`ALTER TABLE messages ADD COLUMN parent_message_id INTEGER REFERENCES messages(id)`,
+				`ALTER TABLE conversations ADD COLUMN active_message_id INTEGER REFERENCES messages(id)`,
+				`UPDATE messages SET parent_message_id = (
+        SELECT p.id FROM messages p
+        WHERE p.conversation_id = messages.conversation_id
+        AND (p.created_at < messages.created_at OR (p.created_at = messages.created_at AND p.id < messages.id))
+        ORDER BY p.created_at DESC, p.id DESC LIMIT 1
+        )`,
+				`UPDATE conversations SET active_message_id = (
+        SELECT m.id FROM messages m
+        WHERE m.conversation_id = conversations.id
+        ORDER BY m.created_at DESC, m.id DESC LIMIT 1
+        )`,
+				`CREATE INDEX IF NOT EXISTS messages_parent_message_id ON messages(parent_message_id)`,
+			)
+		},
+	},
+	{
+		// An external content index: the text lives in messages only and the
+		// triggers keep the index in step with it.
+		Version: 6,
+		Name:    "add full-text search over messages",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE VIRTUAL TABLE messages_fts USING fts5(content, content='messages', content_rowid='id')`,
+				`CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+        INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+        END`,
+				`CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+        INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+        END`,
+				`CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+        INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+        INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+        END`,
+				`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`,
+			)
+		},
+	},
+	{
+		// Summaries of compacted history hang below the last message they
+		// cover, outside of every branch.
+		Version: 7,
+		Name:    "add message kinds for conversation summaries",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`ALTER TABLE messages ADD COLUMN kind TEXT NOT NULL DEFAULT 'message'`,
+			)
+		},
+	},
+	{
+		// Usage rows outlive the messages and conversations they belong to,
+		// what was spent stays spent.
+		Version: 8,
+		Name:    "add message usage",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE message_usage (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        message_id INTEGER,
+        conversation_id INTEGER,
+        model TEXT NOT NULL,
+        input_tokens INTEGER NOT NULL DEFAULT 0,
+        output_tokens INTEGER NOT NULL DEFAULT 0,
+        stop_reason TEXT NOT NULL DEFAULT '',
+        latency_ms INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+				`CREATE INDEX message_usage_message_id ON message_usage(message_id)`,
+				`CREATE INDEX message_usage_created_at ON message_usage(created_at)`,
+			)
+		},
+	},
+	{
+		// Message batches run on the API for up to a day, jobs remember them
+		// between invocations. Their usage is billed at the batch discount.
+		Version: 9,
+		Name:    "add batch jobs",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE batch_jobs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        batch_id TEXT NOT NULL UNIQUE,
+        input_file TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL,
+        requests INTEGER NOT NULL DEFAULT 0,
+        succeeded INTEGER NOT NULL DEFAULT 0,
+        errored INTEGER NOT NULL DEFAULT 0,
+        canceled INTEGER NOT NULL DEFAULT 0,
+        expired INTEGER NOT NULL DEFAULT 0,
+        usage_recorded INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        ended_at TIMESTAMP
+        )`,
+				`ALTER TABLE message_usage ADD COLUMN batch INTEGER NOT NULL DEFAULT 0`,
+			)
+		},
+	},
+	{
+		// The models the API lists, cached so validating a model name does
+		// not need a request.
+		Version: 10,
+		Name:    "add models cache",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`CREATE TABLE models (
+        id TEXT PRIMARY KEY,
+        display_name TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP,
+        fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+			)
+		},
+	},
+	{
+		// Prompt cache writes and reads are billed apart from the other
+		// input tokens.
+		Version: 11,
+		Name:    "add prompt cache usage",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`ALTER TABLE message_usage ADD COLUMN cache_creation_tokens INTEGER NOT NULL DEFAULT 0`,
+				`ALTER TABLE message_usage ADD COLUMN cache_read_tokens INTEGER NOT NULL DEFAULT 0`,
+			)
+		},
+	},
+	{
+		// Extended thinking blocks must be sent back unchanged with the
+		// reply they came with, so they are kept apart from its text.
+		Version: 12,
+		Name:    "add message thinking",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`ALTER TABLE messages ADD COLUMN thinking TEXT NOT NULL DEFAULT ''`,
+			)
+		},
+	},
+	{
+		// Deleting a persona used to leave the conversations using it
+		// pointing at a persona that no longer exists.
+		Version: 13,
+		Name:    "drop options of deleted personas",
+		Up: func(tx *sql.Tx) error {
+			return execStatements(tx,
+				`DELETE FROM conversation_options WHERE option_name = 'persona'
+        AND option_value NOT IN (SELECT name FROM personas)`,
+			)
+		},
+	},
+}
+
+// Migrations returns every known migration in the order they are applied.
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// LatestSchemaVersion is the version the database has once every migration
+// has been applied.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate applies the migrations the database has not seen yet, each in its
+// own transaction, and returns the ones it applied.
+func Migrate() ([]Migration, error) {
+	err := createSchemaVersionTable()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("database schema version %d is newer than the latest version %d known to this build", current, LatestSchemaVersion())
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := applyMigration(m)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database, 0 when none has been.
+func SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(context.Background(), "SELECT MAX(version) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// GetMigrationStatus lists every known migration along with when it was
+// applied, if it has been.
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	err := createSchemaVersionTable()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		err := rows.Scan(&version, &at)
+		if err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := appliedAt[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+func applyMigration(m Migration) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.Up(tx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_version(version, name) VALUES (?, ?)", m.Version, m.Name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func createSchemaVersionTable() error {
+	_, err := db.ExecContext(
+		context.Background(),
+		`CREATE TABLE IF NOT EXISTS schema_version(
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+	)
+	return err
+}
+
+func execStatements(tx *sql.Tx, statements ...string) error {
+	for _, statement := range statements {
+		_, err := tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
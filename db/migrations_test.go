@@ -0,0 +1,139 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "baseline.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(string(fixture))
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	Close()
+
+	err = InitDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	defer Close()
+
+	version, err := SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion returned an error: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", LatestSchemaVersion(), version)
+	}
+
+	messages, err := GetMessages(1)
+	if err != nil {
+		t.Fatalf("GetMessages returned an error: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Errorf("Expected the 2 existing messages to survive, got %d", len(messages))
+	}
+
+	branch, err := GetActiveBranch(1)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+	if len(branch) != 2 || branch[1].ParentID != branch[0].ID {
+		t.Errorf("Expected existing messages to form the active branch, got %+v", branch)
+	}
+
+	options, err := GetConversationOptions(1)
+	if err != nil {
+		t.Fatalf("GetConversationOptions returned an error: %v", err)
+	}
+	values := make(map[string]string)
+	for _, o := range options {
+		values[o.OptionName] = o.OptionValue
+	}
+	if len(options) != 2 || values["model"] != "claude-3-opus-20240229" || values["temperature"] != "0.5" {
+		t.Errorf("Expected duplicate options to keep the latest value, got %+v", options)
+	}
+
+	// The ON CONFLICT clause only works once the unique constraint exists.
+	err = ConfigureConversation(1, "model", "claude-3-5-sonnet-20240620")
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error: %v", err)
+	}
+
+	err = SavePersona(Persona{Name: "reviewer"})
+	if err != nil {
+		t.Errorf("Expected the personas table to exist: %v", err)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	applied, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected no migrations to be applied twice, got %d", len(applied))
+	}
+
+	status, err := GetMigrationStatus()
+	if err != nil {
+		t.Fatalf("GetMigrationStatus returned an error: %v", err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+}
+
+func TestMigrateDropsDeletedPersonaOptions(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	err := InitDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	convID, err := CreateConversation("Persona")
+	if err != nil {
+		t.Fatalf("CreateConversation returned an error: %v", err)
+	}
+	// Left behind by a persona deleted before migration 13.
+	err = ConfigureConversation(convID, OptionPersona, "deleted")
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error: %v", err)
+	}
+	_, err = db.Exec("DELETE FROM schema_version WHERE version = 13")
+	if err != nil {
+		t.Fatalf("Failed to roll back the schema version: %v", err)
+	}
+	Close()
+
+	err = InitDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	defer Close()
+	options, err := GetConversationOptions(convID)
+	if err != nil {
+		t.Fatalf("GetConversationOptions returned an error: %v", err)
+	}
+	if len(options) != 0 {
+		t.Errorf("Expected the option of the deleted persona to be dropped, got %+v", options)
+	}
+}
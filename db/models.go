@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Model is a model the API listed, as cached by ReplaceModels.
+type Model struct {
+	ID          string
+	DisplayName string
+	CreatedAt   time.Time // when the model was released
+	FetchedAt   time.Time
+}
+
+// ReplaceModels replaces the cached models with those the API just listed
+func ReplaceModels(models []Model) error {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM models`)
+	if err != nil {
+		return err
+	}
+	fetchedAt := formatTimestamp(time.Now())
+	for _, m := range models {
+		var createdAt interface{}
+		if !m.CreatedAt.IsZero() {
+			createdAt = formatTimestamp(m.CreatedAt)
+		}
+		_, err = tx.Exec(`INSERT INTO models (id, display_name, created_at, fetched_at) VALUES (?, ?, ?, ?)`,
+			m.ID, m.DisplayName, createdAt, fetchedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetModels retrieves the cached models, most recently released first
+func GetModels() ([]Model, error) {
+	rows, err := db.Query(`SELECT id, display_name, created_at, fetched_at FROM models ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var models []Model
+	for rows.Next() {
+		var m Model
+		var createdAt sql.NullTime
+		err := rows.Scan(&m.ID, &m.DisplayName, &createdAt, &m.FetchedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.CreatedAt = createdAt.Time
+		models = append(models, m)
+	}
+	return models, rows.Err()
+}
+
+// HasModel reports whether a model is in the cache
+func HasModel(id string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM models WHERE id = ?`, id).Scan(&count)
+	return count > 0, err
+}
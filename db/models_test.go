@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestModels(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	err = ReplaceModels([]Model{
+		{ID: "claude-3-haiku-20240307", DisplayName: "Claude 3 Haiku", CreatedAt: time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)},
+		{ID: "claude-3-5-sonnet-20241022", DisplayName: "Claude 3.5 Sonnet (New)", CreatedAt: time.Date(2024, 10, 22, 0, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceModels returned an error: %v", err)
+	}
+	models, err := GetModels()
+	if err != nil {
+		t.Fatalf("GetModels returned an error: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "claude-3-5-sonnet-20241022" || models[0].CreatedAt.Year() != 2024 || models[0].FetchedAt.IsZero() {
+		t.Errorf("Expected the newest model first, got %+v", models)
+	}
+
+	err = ReplaceModels([]Model{{ID: "claude-3-opus-20240229"}})
+	if err != nil {
+		t.Fatalf("ReplaceModels returned an error: %v", err)
+	}
+	for id, want := range map[string]bool{"claude-3-opus-20240229": true, "claude-3-haiku-20240307": false} {
+		has, err := HasModel(id)
+		if err != nil || has != want {
+			t.Errorf("HasModel(%q) = %v, %v, expected %v", id, has, err, want)
+		}
+	}
+}
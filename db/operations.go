@@ -4,9 +4,13 @@ package db
 // CreateConversation: Creates a new conversation.
 // ConfigureConversation: Sets or updates an option for a specific conversation.
 // GetConversationOptions: Retrieves all options for a specific conversation.
+// DeleteConversationOption: Removes a single option from a specific conversation.
 // DeleteConversation: Deletes a conversation and all its associated messages and options.
 // AddMessage: Adds a new message to a conversation.
-// GetMessages: Retrieves all messages for a specific conversation.
+// AddMessageWithAttachments: Adds a new message and the files attached to it.
+// GetMessageAttachments: Retrieves the files attached to a specific message.
+// GetConversationAttachments: Retrieves the files attached to every message of a conversation.
+// GetMessages: Retrieves all messages for a specific conversation, on every branch.
 // DeleteMessage: Deletes a specific message from a conversation.
 // EditMessage: Updates the content of a specific message.
 // SetGlobalOption: Sets or updates a global option (using conversation_id 0).
@@ -37,11 +41,64 @@ type Conversation struct {
 type Message struct {
 	ID             int64
 	ConversationID int64
+	ParentID       int64 // 0 for the first message of a branch
+	Kind           string
 	Role           string
 	Content        string
+	Thinking       string // JSON array of the reply's thinking blocks, empty when there are none
 	CreatedAt      time.Time
 }
 
+// Message kinds. Summaries stand in for the history before them when the
+// conversation is sent to Claude, see AddSummary.
+const (
+	MessageKindMessage = "message"
+	MessageKindSummary = "summary"
+)
+
+// Attachment kinds
+const (
+	AttachmentKindText     = "text"
+	AttachmentKindImage    = "image"
+	AttachmentKindDocument = "document"
+)
+
+// Attachment represents a file attached to a message. Data holds the file's
+// text for text attachments and its base64 encoded contents otherwise, so the
+// message can be replayed even if the file changed or no longer exists.
+type Attachment struct {
+	ID        int64
+	MessageID int64
+	Path      string
+	Kind      string
+	MediaType string
+	Data      string
+	CreatedAt time.Time
+}
+
+// Conversation option names
+const (
+	OptionModel       = "model"
+	OptionMaxTokens   = "max_tokens"
+	OptionTemperature = "temperature"
+	OptionTopP        = "top_p"
+	OptionTopK        = "top_k"
+	OptionStream      = "stream"
+	OptionSystem      = "system"
+	OptionPersona     = "persona"
+
+	OptionContextStrategy   = "context_strategy"
+	OptionContextKeepFirst  = "context_keep_first"
+	OptionContextBudget     = "context_budget"
+	OptionCompactThreshold  = "compact_threshold"
+	OptionCompactKeepRecent = "compact_keep_recent"
+	OptionThinkingBudget    = "thinking_budget"
+
+	// OptionImportSource identifies where an imported conversation came
+	// from, so importing the same file twice doesn't duplicate it.
+	OptionImportSource = "import_source"
+)
+
 // ConversationOption represents an option for a conversation
 type ConversationOption struct {
 	ID             int64
@@ -109,12 +166,22 @@ func GetConversationOptions(conversationID int64) ([]ConversationOption, error)
 	return options, nil
 }
 
+// DeleteConversationOption removes an option from a conversation
+func DeleteConversationOption(conversationID int64, optionName string) error {
+	_, err := db.Exec("DELETE FROM conversation_options WHERE conversation_id = ? AND option_name = ?", conversationID, optionName)
+	return err
+}
+
 // DeleteConversation deletes a conversation and all its messages and options
 func DeleteConversation(conversationID int64) error {
 	sqlResult, err := db.Exec("DELETE FROM conversations WHERE id = ?", conversationID)
 	if err != nil {
 		logger.PanicError(err, fmt.Sprintf("Error attempting to Delete Conversation from table.\n%v\n%v", sqlResult, err))
 	}
+	sqlResult, err = db.Exec("DELETE FROM message_attachments WHERE message_id IN (SELECT id FROM messages WHERE conversation_id = ?)", conversationID)
+	if err != nil {
+		logger.PanicError(err, fmt.Sprintf("Error attempting to Delete Conversation from table.\n%v\n%v", sqlResult, err))
+	}
 	sqlResult, err = db.Exec("DELETE FROM messages WHERE conversation_id = ?", conversationID)
 	if err != nil {
 		logger.PanicError(err, fmt.Sprintf("Error attempting to Delete Conversation from table.\n%v\n%v", sqlResult, err))
@@ -126,37 +193,130 @@ func DeleteConversation(conversationID int64) error {
 	return err
 }
 
-// AddMessage adds a new message to a conversation
+// AddMessage adds a new message to the end of the conversation's active branch
 func AddMessage(conversationID int64, role, content string) error {
-	_, err := db.Exec("INSERT INTO messages (conversation_id, role, content) VALUES (?, ?, ?)",
-		conversationID, role, content)
+	_, err := AddMessageWithAttachments(conversationID, role, content, nil)
 	return err
 }
 
-// GetMessages retrieves all messages for a conversation
-func GetMessages(conversationID int64) ([]Message, error) {
-	rows, err := db.Query("SELECT id, conversation_id, role, content, created_at FROM messages WHERE conversation_id = ? ORDER BY created_at ASC", conversationID)
+// AddMessageWithAttachments adds a new message to the end of the conversation's
+// active branch together with its attachments and returns the new message's ID
+func AddMessageWithAttachments(conversationID int64, role, content string, attachments []Attachment) (int64, error) {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	messageID, err := insertMessage(tx, conversationID, role, content)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range attachments {
+		_, err = tx.Exec("INSERT INTO message_attachments (message_id, path, kind, media_type, data) VALUES (?, ?, ?, ?, ?)",
+			messageID, a.Path, a.Kind, a.MediaType, a.Data)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return messageID, tx.Commit()
+}
+
+// GetMessageAttachments retrieves the attachments of a message
+func GetMessageAttachments(messageID int64) ([]Attachment, error) {
+	rows, err := db.Query("SELECT id, message_id, path, kind, media_type, data, created_at FROM message_attachments WHERE message_id = ? ORDER BY id ASC", messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		err := rows.Scan(&a.ID, &a.MessageID, &a.Path, &a.Kind, &a.MediaType, &a.Data, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// GetConversationAttachments retrieves the attachments of every message in a
+// conversation, keyed by message ID
+func GetConversationAttachments(conversationID int64) (map[int64][]Attachment, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.message_id, a.path, a.kind, a.media_type, a.data, a.created_at
+		FROM message_attachments a
+		JOIN messages m ON m.id = a.message_id
+		WHERE m.conversation_id = ?
+		ORDER BY a.id ASC`, conversationID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []Message
+	attachments := make(map[int64][]Attachment)
 	for rows.Next() {
-		var m Message
-		err := rows.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.CreatedAt)
+		var a Attachment
+		err := rows.Scan(&a.ID, &a.MessageID, &a.Path, &a.Kind, &a.MediaType, &a.Data, &a.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, m)
+		attachments[a.MessageID] = append(attachments[a.MessageID], a)
+	}
+	return attachments, nil
+}
+
+// GetMessages retrieves all messages for a conversation, leaving out summaries
+func GetMessages(conversationID int64) ([]Message, error) {
+	rows, err := db.Query("SELECT "+messageColumns+" FROM messages m WHERE m.conversation_id = ? AND m.kind = 'message' ORDER BY m.created_at ASC, m.id ASC", conversationID)
+	if err != nil {
+		return nil, err
 	}
-	return messages, nil
+	return scanMessages(rows)
 }
 
-// DeleteMessage deletes a specific message from a conversation
+// DeleteMessage deletes a specific message from a conversation. Its replies
+// are kept and attached to the deleted message's parent instead, summaries
+// covering it are deleted with it
 func DeleteMessage(messageID int64) error {
-	_, err := db.Exec("DELETE FROM messages WHERE id = ?", messageID)
-	return err
+	tx, err := BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	err = tx.QueryRow("SELECT parent_message_id FROM messages WHERE id = ?", messageID).Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM messages WHERE parent_message_id = ? AND kind = 'summary'", messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE messages SET parent_message_id = ? WHERE parent_message_id = ?", parentID, messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE conversations SET active_message_id = ? WHERE active_message_id = ?", parentID, messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM message_attachments WHERE message_id = ?", messageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM messages WHERE id = ?", messageID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // EditMessage updates the content of a specific message
@@ -112,3 +112,150 @@ func TestCreateConversation(t *testing.T) {
 		t.Errorf("Expected empty title, got %s", storedTitle)
 	}
 }
+
+func TestAddMessageWithAttachments(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	convID, err := CreateConversation("Attachments")
+	if err != nil {
+		t.Fatalf("CreateConversation returned an error: %v", err)
+	}
+
+	attachments := []Attachment{
+		{Path: "./main.go", Kind: AttachmentKindText, MediaType: "text/plain", Data: "package main"},
+		{Path: "./screen.png", Kind: AttachmentKindImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+	}
+	messageID, err := AddMessageWithAttachments(convID, "user", "What is this?", attachments)
+	if err != nil {
+		t.Fatalf("AddMessageWithAttachments returned an error: %v", err)
+	}
+
+	stored, err := GetMessageAttachments(messageID)
+	if err != nil {
+		t.Fatalf("GetMessageAttachments returned an error: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d", len(stored))
+	}
+	if stored[0].Path != "./main.go" || stored[1].Kind != AttachmentKindImage {
+		t.Errorf("Attachments stored out of order or incomplete: %+v", stored)
+	}
+
+	byMessage, err := GetConversationAttachments(convID)
+	if err != nil {
+		t.Fatalf("GetConversationAttachments returned an error: %v", err)
+	}
+	if len(byMessage[messageID]) != 2 {
+		t.Errorf("Expected 2 attachments for message %d, got %d", messageID, len(byMessage[messageID]))
+	}
+
+	err = DeleteMessage(messageID)
+	if err != nil {
+		t.Fatalf("DeleteMessage returned an error: %v", err)
+	}
+	stored, err = GetMessageAttachments(messageID)
+	if err != nil {
+		t.Fatalf("GetMessageAttachments returned an error: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("Expected attachments to be deleted with their message, got %d", len(stored))
+	}
+}
+
+func TestConfigureConversation(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	convID, err := CreateConversation("Options")
+	if err != nil {
+		t.Fatalf("CreateConversation returned an error: %v", err)
+	}
+
+	err = ConfigureConversation(convID, OptionPersona, "reviewer")
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error: %v", err)
+	}
+	err = ConfigureConversation(convID, OptionPersona, "translator")
+	if err != nil {
+		t.Fatalf("ConfigureConversation returned an error on update: %v", err)
+	}
+
+	options, err := GetConversationOptions(convID)
+	if err != nil {
+		t.Fatalf("GetConversationOptions returned an error: %v", err)
+	}
+	if len(options) != 1 {
+		t.Fatalf("Expected 1 option after update, got %d", len(options))
+	}
+	if options[0].OptionValue != "translator" {
+		t.Errorf("Expected option value translator, got %s", options[0].OptionValue)
+	}
+}
+
+func TestDeletePersonaInUse(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	for _, name := range []string{"reviewer", "translator"} {
+		err := SavePersona(Persona{Name: name, SystemPrompt: "You are a " + name + "."})
+		if err != nil {
+			t.Fatalf("SavePersona returned an error: %v", err)
+		}
+	}
+	var convIDs []int64
+	for _, persona := range []string{"reviewer", "reviewer", "translator"} {
+		convID, err := CreateConversation("Persona")
+		if err != nil {
+			t.Fatalf("CreateConversation returned an error: %v", err)
+		}
+		err = ConfigureConversation(convID, OptionPersona, persona)
+		if err != nil {
+			t.Fatalf("ConfigureConversation returned an error: %v", err)
+		}
+		err = ConfigureConversation(convID, OptionModel, "claude-3-haiku-20240307")
+		if err != nil {
+			t.Fatalf("ConfigureConversation returned an error: %v", err)
+		}
+		convIDs = append(convIDs, convID)
+	}
+
+	err = DeletePersona("reviewer")
+	if err != nil {
+		t.Fatalf("DeletePersona returned an error: %v", err)
+	}
+	persona, err := GetPersona("reviewer")
+	if err != nil || persona != nil {
+		t.Errorf("Expected the persona to be deleted, got %+v, %v", persona, err)
+	}
+
+	for i, convID := range convIDs {
+		options, err := GetConversationOptions(convID)
+		if err != nil {
+			t.Fatalf("GetConversationOptions returned an error: %v", err)
+		}
+		values := map[string]string{}
+		for _, o := range options {
+			values[o.OptionName] = o.OptionValue
+		}
+		wantPersona := ""
+		if i == 2 {
+			wantPersona = "translator"
+		}
+		if values[OptionPersona] != wantPersona {
+			t.Errorf("Conversation %d: expected persona %q, got %q", convID, wantPersona, values[OptionPersona])
+		}
+		if values[OptionModel] != "claude-3-haiku-20240307" {
+			t.Errorf("Conversation %d: expected its other options to be kept, got %v", convID, values)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+// SavePersona: Creates a persona or updates the one with the same name.
+// GetPersona: Retrieves a persona by name.
+// ListPersonas: Retrieves all personas.
+// DeletePersona: Deletes a persona by name.
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Persona is a named system prompt with default model and sampling
+// parameters. Zero values mean the persona leaves that setting alone.
+type Persona struct {
+	ID           int64
+	Name         string
+	SystemPrompt string
+	Model        string
+	MaxTokens    int
+	Temperature  float64
+	TopP         float64
+	TopK         float64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// SavePersona creates a persona, or updates the existing persona with the same name
+func SavePersona(p Persona) error {
+	_, err := db.Exec(`
+		INSERT INTO personas (name, system_prompt, model, max_tokens, temperature, top_p, top_k)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			system_prompt = excluded.system_prompt,
+			model = excluded.model,
+			max_tokens = excluded.max_tokens,
+			temperature = excluded.temperature,
+			top_p = excluded.top_p,
+			top_k = excluded.top_k,
+			updated_at = CURRENT_TIMESTAMP`,
+		p.Name, p.SystemPrompt, p.Model, p.MaxTokens, p.Temperature, p.TopP, p.TopK)
+	return err
+}
+
+// GetPersona retrieves a persona by name
+func GetPersona(name string) (*Persona, error) {
+	var p Persona
+	err := db.QueryRow(`
+		SELECT id, name, system_prompt, model, max_tokens, temperature, top_p, top_k, created_at, updated_at
+		FROM personas WHERE name = ?`, name).
+		Scan(&p.ID, &p.Name, &p.SystemPrompt, &p.Model, &p.MaxTokens, &p.Temperature, &p.TopP, &p.TopK, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Persona not found
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPersonas retrieves all personas ordered by name
+func ListPersonas() ([]Persona, error) {
+	rows, err := db.Query(`
+		SELECT id, name, system_prompt, model, max_tokens, temperature, top_p, top_k, created_at, updated_at
+		FROM personas ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var personas []Persona
+	for rows.Next() {
+		var p Persona
+		err := rows.Scan(&p.ID, &p.Name, &p.SystemPrompt, &p.Model, &p.MaxTokens, &p.Temperature, &p.TopP, &p.TopK, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, p)
+	}
+	return personas, nil
+}
+
+// DeletePersona deletes a persona by name. Conversations using it fall back to
+// their own options and the global configuration.
+func DeletePersona(name string) error {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM conversation_options WHERE option_name = ? AND option_value = ?", OptionPersona, name)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM personas WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Markers placed around the matched terms of a search snippet
+const (
+	SnippetMatchStart = "**"
+	SnippetMatchEnd   = "**"
+)
+
+// SearchOptions narrows a message search. Zero values don't filter.
+type SearchOptions struct {
+	Role           string
+	Since          time.Time
+	ConversationID int64
+	Limit          int
+}
+
+// SearchResult is a message matching a search, best match first
+type SearchResult struct {
+	Message
+	ConversationTitle string
+	Snippet           string  // the matching part of the content, terms between SnippetMatchStart and SnippetMatchEnd
+	Rank              float64 // bm25 score, lower is a better match
+}
+
+// SearchMessages runs a full-text search over the messages of every
+// conversation. query uses the FTS5 query syntax, see QuoteSearchQuery to
+// search for plain words.
+func SearchMessages(query string, opts SearchOptions) ([]SearchResult, error) {
+	var where []string
+	args := []interface{}{SnippetMatchStart, SnippetMatchEnd, query}
+	if opts.Role != "" {
+		where = append(where, "m.role = ?")
+		args = append(args, opts.Role)
+	}
+	if !opts.Since.IsZero() {
+		where = append(where, "m.created_at >= ?")
+		args = append(args, opts.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if opts.ConversationID != 0 {
+		where = append(where, "m.conversation_id = ?")
+		args = append(args, opts.ConversationID)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	args = append(args, limit)
+
+	filter := ""
+	if len(where) > 0 {
+		filter = " AND " + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(`
+		SELECT `+messageColumns+`, c.title,
+			snippet(messages_fts, 0, ?, ?, '...', 16), bm25(messages_fts) AS rank
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE messages_fts MATCH ? AND m.kind = 'message'`+filter+`
+		ORDER BY rank
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search %q: %w", query, err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var parentID sql.NullInt64
+		err := rows.Scan(&r.ID, &r.ConversationID, &parentID, &r.Kind, &r.Role, &r.Content, &r.Thinking, &r.CreatedAt,
+			&r.ConversationTitle, &r.Snippet, &r.Rank)
+		if err != nil {
+			return nil, err
+		}
+		r.ParentID = parentID.Int64
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// QuoteSearchQuery turns plain words into an FTS5 query matching messages
+// that contain all of them, so punctuation such as "go-claude" is searched
+// for rather than parsed as query syntax. A trailing * is kept as a prefix
+// search.
+func QuoteSearchQuery(words string) string {
+	var terms []string
+	for _, word := range strings.Fields(words) {
+		prefix := strings.HasSuffix(word, "*") && len(word) > 1
+		word = strings.TrimSuffix(word, "*")
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
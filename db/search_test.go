@@ -0,0 +1,75 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchMessages(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	goID, _ := CreateConversation("Go questions")
+	sqlID, _ := CreateConversation("SQL questions")
+	AddMessage(goID, "user", "How do I close a channel in Go?")
+	AddMessage(goID, "assistant", "Call close(ch) from the sender once go-claude is done sending.")
+	AddMessage(sqlID, "user", "What does a sqlite trigger do?")
+	AddMessage(sqlID, "assistant", "A trigger runs statements when a row changes, for example to keep a channel index in sync.")
+
+	results, err := SearchMessages(QuoteSearchQuery("channel"), SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchMessages returned an error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results for channel, got %d", len(results))
+	}
+	for _, r := range results {
+		if !strings.Contains(r.Snippet, SnippetMatchStart+"channel"+SnippetMatchEnd) {
+			t.Errorf("Expected the snippet to highlight the match, got %q", r.Snippet)
+		}
+	}
+
+	results, err = SearchMessages(QuoteSearchQuery("channel"), SearchOptions{Role: "user"})
+	if err != nil {
+		t.Fatalf("SearchMessages returned an error: %v", err)
+	}
+	if len(results) != 1 || results[0].ConversationTitle != "Go questions" {
+		t.Errorf("Expected the user question only, got %+v", results)
+	}
+
+	results, err = SearchMessages(QuoteSearchQuery("channel"), SearchOptions{ConversationID: sqlID})
+	if err != nil {
+		t.Fatalf("SearchMessages returned an error: %v", err)
+	}
+	if len(results) != 1 || results[0].ConversationID != sqlID {
+		t.Errorf("Expected a single result from conversation %d, got %+v", sqlID, results)
+	}
+
+	results, err = SearchMessages(QuoteSearchQuery("go-claude"), SearchOptions{Since: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("SearchMessages returned an error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected punctuation to be searched literally, got %d results", len(results))
+	}
+
+	// Edited and deleted messages are kept in step with the index.
+	messages, _ := GetMessages(sqlID)
+	EditMessage(messages[1].ID, "A trigger runs statements when a row changes.")
+	DeleteMessage(messages[0].ID)
+	results, err = SearchMessages(QuoteSearchQuery("trigger"), SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchMessages returned an error: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != messages[1].ID {
+		t.Errorf("Expected only the edited message to match trigger, got %+v", results)
+	}
+	results, _ = SearchMessages(QuoteSearchQuery("channel"), SearchOptions{ConversationID: sqlID})
+	if len(results) != 0 {
+		t.Errorf("Expected the edited text to be removed from the index, got %+v", results)
+	}
+}
@@ -0,0 +1,74 @@
+package db
+
+import "errors"
+
+// A summary condenses the history of a branch up to and including the message
+// it hangs below. It is stored in the messages table with the summary kind so
+// it follows the message around: branches forking after that message share the
+// summary, and the messages it covers stay untouched for history and export.
+
+// AddSummary stores a summary of the branch ending on throughMessageID and
+// returns its ID. The conversation's active message does not move.
+func AddSummary(conversationID, throughMessageID int64, content string) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO messages (conversation_id, parent_message_id, kind, role, content)
+		SELECT ?, id, 'summary', 'assistant', ? FROM messages
+		WHERE id = ? AND conversation_id = ? AND kind = 'message'`,
+		conversationID, content, throughMessageID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if inserted == 0 {
+		return 0, errors.New("the summarized message is not part of the conversation")
+	}
+	return result.LastInsertId()
+}
+
+// GetBranchSummary returns the most recent summary of the branch ending on
+// messageID, the one covering the most of it, or nil when the branch has not
+// been compacted.
+func GetBranchSummary(messageID int64) (*Message, error) {
+	rows, err := db.Query(`
+		WITH RECURSIVE branch(id, depth) AS (
+			SELECT id, 0 FROM messages WHERE id = ?
+			UNION ALL
+			SELECT m.parent_message_id, b.depth + 1 FROM messages m
+			JOIN branch b ON m.id = b.id
+			WHERE m.parent_message_id IS NOT NULL
+		)
+		SELECT `+messageColumns+` FROM messages m
+		JOIN branch b ON b.id = m.parent_message_id
+		WHERE m.kind = 'summary'
+		ORDER BY b.depth ASC, m.id DESC LIMIT 1`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := scanMessages(rows)
+	if err != nil || len(summaries) == 0 {
+		return nil, err
+	}
+	return &summaries[0], nil
+}
+
+// GetSummaries retrieves every summary of a conversation, oldest first
+func GetSummaries(conversationID int64) ([]Message, error) {
+	rows, err := db.Query("SELECT "+messageColumns+" FROM messages m WHERE m.conversation_id = ? AND m.kind = 'summary' ORDER BY m.id ASC", conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// DeleteSummaries deletes every summary of a conversation, so the full
+// history is sent again
+func DeleteSummaries(conversationID int64) (int64, error) {
+	result, err := db.Exec("DELETE FROM messages WHERE conversation_id = ? AND kind = 'summary'", conversationID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,101 @@
+package db
+
+import "testing"
+
+func TestBranchSummaries(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	convID, err := CreateConversation("Summaries")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for i, content := range []string{"question", "answer", "follow up", "second answer"} {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		err := AddMessage(convID, role, content)
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+	}
+	branch, err := GetActiveBranch(convID)
+	if err != nil {
+		t.Fatalf("GetActiveBranch returned an error: %v", err)
+	}
+
+	summary, err := GetBranchSummary(branch[3].ID)
+	if err != nil || summary != nil {
+		t.Fatalf("Expected no summary before compacting, got %+v, %v", summary, err)
+	}
+
+	firstID, err := AddSummary(convID, branch[1].ID, "first pair")
+	if err != nil {
+		t.Fatalf("AddSummary returned an error: %v", err)
+	}
+	_, err = AddSummary(convID, branch[3].ID, "both pairs")
+	if err != nil {
+		t.Fatalf("AddSummary returned an error: %v", err)
+	}
+
+	// The summary covering the most of the branch wins.
+	summary, err = GetBranchSummary(branch[3].ID)
+	if err != nil || summary == nil || summary.Content != "both pairs" || summary.Kind != MessageKindSummary {
+		t.Errorf("Expected the second summary, got %+v, %v", summary, err)
+	}
+
+	// Summaries stay out of the branch, its leaves and the message list.
+	active, err := GetActiveMessageID(convID)
+	if err != nil || active != branch[3].ID {
+		t.Errorf("Expected adding a summary to leave the active message alone, got %d, %v", active, err)
+	}
+	leaves, err := GetBranchLeaves(convID)
+	if err != nil || len(leaves) != 1 || leaves[0].ID != branch[3].ID {
+		t.Errorf("Expected the summaries not to count as branches, got %+v, %v", leaves, err)
+	}
+	messages, err := GetMessages(convID)
+	if err != nil || len(messages) != 4 {
+		t.Errorf("Expected the 4 messages without summaries, got %d, %v", len(messages), err)
+	}
+	err = SetActiveMessage(convID, firstID)
+	if err == nil {
+		t.Errorf("Expected a summary not to become the active message")
+	}
+
+	// A branch forked after the first answer only shares the first summary.
+	err = SetActiveMessage(convID, branch[1].ID)
+	if err != nil {
+		t.Fatalf("SetActiveMessage returned an error: %v", err)
+	}
+	err = AddMessage(convID, "user", "different follow up")
+	if err != nil {
+		t.Fatalf("AddMessage returned an error: %v", err)
+	}
+	forkID, err := GetActiveMessageID(convID)
+	if err != nil {
+		t.Fatalf("GetActiveMessageID returned an error: %v", err)
+	}
+	summary, err = GetBranchSummary(forkID)
+	if err != nil || summary == nil || summary.ID != firstID {
+		t.Errorf("Expected the forked branch to use the first summary, got %+v, %v", summary, err)
+	}
+
+	// Deleting a summarized message deletes the summary hanging below it.
+	err = DeleteMessage(branch[3].ID)
+	if err != nil {
+		t.Fatalf("DeleteMessage returned an error: %v", err)
+	}
+	summaries, err := GetSummaries(convID)
+	if err != nil || len(summaries) != 1 || summaries[0].ID != firstID {
+		t.Errorf("Expected only the first summary to remain, got %+v, %v", summaries, err)
+	}
+
+	deleted, err := DeleteSummaries(convID)
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 summary deleted, got %d, %v", deleted, err)
+	}
+}
@@ -0,0 +1,32 @@
+-- Schema written by go-claude before migrations were introduced, with the
+-- duplicate conversation options its missing UNIQUE constraint allowed.
+CREATE TABLE conversations(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id INTEGER,
+    role TEXT CHECK(role IN ('user', 'assistant')),
+    content TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE conversation_options(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id INTEGER,
+    option_name TEXT,
+    option_value TEXT,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+INSERT INTO conversations(title) VALUES ('Old conversation');
+INSERT INTO messages(conversation_id, role, content) VALUES (1, 'user', 'Hello');
+INSERT INTO messages(conversation_id, role, content) VALUES (1, 'assistant', 'Hi there');
+INSERT INTO conversation_options(conversation_id, option_name, option_value) VALUES (1, 'model', 'claude-3-haiku-20240307');
+INSERT INTO conversation_options(conversation_id, option_name, option_value) VALUES (1, 'temperature', '0.5');
+INSERT INTO conversation_options(conversation_id, option_name, option_value) VALUES (1, 'model', 'claude-3-opus-20240229');
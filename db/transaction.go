@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Tx runs conversation operations that must succeed or fail together, such as
+// importing a batch of conversations. Unlike their package level counterparts,
+// its methods take the timestamps and message parents explicitly so existing
+// history can be written as it was.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// RunInTransaction calls fn with a transaction that is committed when fn
+// returns nil and rolled back otherwise.
+func RunInTransaction(fn func(tx *Tx) error) error {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(&Tx{tx: tx})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateConversation creates a conversation with the given timestamps
+func (t *Tx) CreateConversation(title string, createdAt, updatedAt time.Time) (int64, error) {
+	result, err := t.tx.Exec("INSERT INTO conversations (title, created_at, updated_at) VALUES (?, ?, ?)",
+		title, formatTimestamp(createdAt), formatTimestamp(updatedAt))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ConfigureConversation sets or updates an option for a conversation
+func (t *Tx) ConfigureConversation(conversationID int64, optionName, optionValue string) error {
+	_, err := t.tx.Exec(`
+		INSERT INTO conversation_options (conversation_id, option_name, option_value)
+		VALUES (?, ?, ?)
+		ON CONFLICT(conversation_id, option_name) DO UPDATE SET option_value = ?`,
+		conversationID, optionName, optionValue, optionValue)
+	return err
+}
+
+// AddMessage adds a message replying to parentID, or starting the
+// conversation when parentID is 0, along with its attachments. Unlike the
+// package level AddMessage it leaves the active branch alone.
+func (t *Tx) AddMessage(conversationID, parentID int64, role, content string, createdAt time.Time, attachments []Attachment) (int64, error) {
+	parent := sql.NullInt64{Int64: parentID, Valid: parentID != 0}
+	result, err := t.tx.Exec("INSERT INTO messages (conversation_id, parent_message_id, role, content, created_at) VALUES (?, ?, ?, ?, ?)",
+		conversationID, parent, role, content, formatTimestamp(createdAt))
+	if err != nil {
+		return 0, err
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range attachments {
+		_, err = t.tx.Exec("INSERT INTO message_attachments (message_id, path, kind, media_type, data) VALUES (?, ?, ?, ?, ?)",
+			messageID, a.Path, a.Kind, a.MediaType, a.Data)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return messageID, nil
+}
+
+// SetActiveMessage makes the branch ending on messageID the active one
+func (t *Tx) SetActiveMessage(conversationID, messageID int64) error {
+	_, err := t.tx.Exec("UPDATE conversations SET active_message_id = ? WHERE id = ?", messageID, conversationID)
+	return err
+}
+
+// FindConversationByOption returns the first conversation whose option name
+// is set to value, 0 when there is none
+func (t *Tx) FindConversationByOption(optionName, optionValue string) (int64, error) {
+	var conversationID int64
+	err := t.tx.QueryRow(`
+		SELECT o.conversation_id FROM conversation_options o
+		JOIN conversations c ON c.id = o.conversation_id
+		WHERE o.option_name = ? AND o.option_value = ?
+		ORDER BY o.conversation_id LIMIT 1`, optionName, optionValue).Scan(&conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return conversationID, err
+}
+
+// HasPersona reports whether a persona named name exists
+func (t *Tx) HasPersona(name string) (bool, error) {
+	var exists bool
+	err := t.tx.QueryRow("SELECT EXISTS (SELECT 1 FROM personas WHERE name = ?)", name).Scan(&exists)
+	return exists, err
+}
+
+// formatTimestamp writes t the way CURRENT_TIMESTAMP does, so imported rows
+// sort and compare like the ones created here. The zero time means now.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Usage is what the API reported for the request that produced a message: the
+// tokens it was billed for, why it stopped and how long it took. Usage is kept
+// when the message or its conversation is deleted.
+type Usage struct {
+	ID                  int64
+	MessageID           int64
+	ConversationID      int64
+	Model               string
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64 // written to the prompt cache, not part of InputTokens
+	CacheReadTokens     int64 // read from the prompt cache, not part of InputTokens
+	StopReason          string
+	Latency             time.Duration
+	Batch               bool // sent through a message batch, at the batch discount
+	CreatedAt           time.Time
+}
+
+// UsageGroup is what GetUsageTotals adds usage up by.
+type UsageGroup string
+
+const (
+	UsageByConversation UsageGroup = "conversation"
+	UsageByDay          UsageGroup = "day"
+	UsageByModel        UsageGroup = "model"
+)
+
+// UsageFilter narrows the usage GetUsageTotals adds up. Zero values match
+// everything.
+type UsageFilter struct {
+	Since          time.Time
+	ConversationID int64
+}
+
+// UsageTotal is the usage of a group for a single model, since the cost of
+// tokens depends on the model.
+type UsageTotal struct {
+	Key                 string // conversation ID, day (2006-01-02, UTC) or model
+	Title               string // conversation title, empty for deleted conversations
+	Model               string
+	Requests            int64
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	Latency             time.Duration // summed over the requests
+	Batch               bool          // of requests sent through message batches
+}
+
+// AddReply adds an assistant message to the end of the conversation's active
+// branch together with the usage of the request that produced it, and returns
+// the new message's ID
+func AddReply(conversationID int64, content string, usage Usage) (int64, error) {
+	return AddReplyWithThinking(conversationID, content, "", usage)
+}
+
+// AddReplyWithThinking is AddReply for a reply that came with thinking
+// blocks, given as a JSON array.
+func AddReplyWithThinking(conversationID int64, content, thinking string, usage Usage) (int64, error) {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	messageID, err := insertMessage(tx, conversationID, "assistant", content)
+	if err != nil {
+		return 0, err
+	}
+	if thinking != "" {
+		_, err = tx.Exec("UPDATE messages SET thinking = ? WHERE id = ?", thinking, messageID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	usage.MessageID = messageID
+	usage.ConversationID = conversationID
+	err = insertUsage(tx, usage)
+	if err != nil {
+		return 0, err
+	}
+	return messageID, tx.Commit()
+}
+
+// AddUsage records the usage of a request
+func AddUsage(usage Usage) error {
+	tx, err := BeginTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertUsage(tx, usage)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetMessageUsage retrieves the usage of the request that produced a message,
+// nil when none was recorded
+func GetMessageUsage(messageID int64) (*Usage, error) {
+	var u Usage
+	var messageIDValue, conversationID sql.NullInt64
+	var latency int64
+	err := db.QueryRow(`
+		SELECT id, message_id, conversation_id, model, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens,
+			stop_reason, latency_ms, batch, created_at
+		FROM message_usage WHERE message_id = ? ORDER BY id DESC LIMIT 1`, messageID).
+		Scan(&u.ID, &messageIDValue, &conversationID, &u.Model, &u.InputTokens, &u.OutputTokens, &u.CacheCreationTokens, &u.CacheReadTokens,
+			&u.StopReason, &latency, &u.Batch, &u.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	u.MessageID = messageIDValue.Int64
+	u.ConversationID = conversationID.Int64
+	u.Latency = time.Duration(latency) * time.Millisecond
+	return &u, nil
+}
+
+// GetUsageTotals adds up the recorded usage by group, model and whether it
+// was sent through a batch, largest groups first for conversations and
+// models, most recent first for days
+func GetUsageTotals(group UsageGroup, filter UsageFilter) ([]UsageTotal, error) {
+	var key, order string
+	switch group {
+	case UsageByConversation:
+		key = "CAST(u.conversation_id AS TEXT)"
+		order = "SUM(u.input_tokens + u.output_tokens) DESC"
+	case UsageByDay:
+		key = "date(u.created_at)"
+		order = "group_key DESC"
+	case UsageByModel:
+		key = "u.model"
+		order = "SUM(u.input_tokens + u.output_tokens) DESC"
+	default:
+		return nil, fmt.Errorf("unknown usage group %q", group)
+	}
+
+	var where []string
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		where = append(where, "u.created_at >= ?")
+		args = append(args, formatTimestamp(filter.Since))
+	}
+	if filter.ConversationID != 0 {
+		where = append(where, "u.conversation_id = ?")
+		args = append(args, filter.ConversationID)
+	}
+	filterSQL := ""
+	if len(where) > 0 {
+		filterSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	rows, err := db.Query(`
+		SELECT `+key+` AS group_key, COALESCE(MAX(c.title), ''), u.model, COUNT(*),
+			SUM(u.input_tokens), SUM(u.output_tokens), SUM(u.cache_creation_tokens), SUM(u.cache_read_tokens),
+			SUM(u.latency_ms), u.batch
+		FROM message_usage u
+		LEFT JOIN conversations c ON c.id = u.conversation_id
+		`+filterSQL+`
+		GROUP BY group_key, u.model, u.batch
+		ORDER BY `+order+`, u.model ASC, u.batch ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		var key sql.NullString
+		var latency int64
+		err := rows.Scan(&key, &t.Title, &t.Model, &t.Requests, &t.InputTokens, &t.OutputTokens, &t.CacheCreationTokens, &t.CacheReadTokens,
+			&latency, &t.Batch)
+		if err != nil {
+			return nil, err
+		}
+		t.Key = key.String
+		t.Latency = time.Duration(latency) * time.Millisecond
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+func insertUsage(tx *sql.Tx, u Usage) error {
+	var messageID, conversationID sql.NullInt64
+	if u.MessageID != 0 {
+		messageID = sql.NullInt64{Int64: u.MessageID, Valid: true}
+	}
+	if u.ConversationID != 0 {
+		conversationID = sql.NullInt64{Int64: u.ConversationID, Valid: true}
+	}
+	_, err := tx.Exec(`
+		INSERT INTO message_usage (message_id, conversation_id, model, input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens,
+			stop_reason, latency_ms, batch)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		messageID, conversationID, u.Model, u.InputTokens, u.OutputTokens, u.CacheCreationTokens, u.CacheReadTokens,
+		u.StopReason, u.Latency.Milliseconds(), u.Batch)
+	return err
+}
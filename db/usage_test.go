@@ -0,0 +1,101 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsageTotals(t *testing.T) {
+	err := InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	first, err := CreateConversation("First")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	second, err := CreateConversation("Second")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	replies := []struct {
+		conversationID int64
+		model          string
+		input, output  int64
+		cacheWrite     int64
+		cacheRead      int64
+	}{
+		{first, "claude-3-haiku-20240307", 100, 10, 2048, 0},
+		{first, "claude-3-opus-20240229", 200, 20, 0, 0},
+		{second, "claude-3-haiku-20240307", 300, 30, 0, 4096},
+	}
+	var lastID int64
+	for _, r := range replies {
+		err := AddMessage(r.conversationID, "user", "question")
+		if err != nil {
+			t.Fatalf("AddMessage returned an error: %v", err)
+		}
+		lastID, err = AddReply(r.conversationID, "answer", Usage{
+			Model:               r.model,
+			InputTokens:         r.input,
+			OutputTokens:        r.output,
+			CacheCreationTokens: r.cacheWrite,
+			CacheReadTokens:     r.cacheRead,
+			StopReason:          "end_turn",
+			Latency:             1500 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("AddReply returned an error: %v", err)
+		}
+	}
+
+	usage, err := GetMessageUsage(lastID)
+	if err != nil || usage == nil {
+		t.Fatalf("Expected the reply's usage, got %+v, %v", usage, err)
+	}
+	if usage.ConversationID != second || usage.InputTokens != 300 || usage.CacheReadTokens != 4096 || usage.StopReason != "end_turn" || usage.Latency != 1500*time.Millisecond {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	branch, err := GetActiveBranch(second)
+	if err != nil || len(branch) != 2 || branch[1].ID != lastID || branch[1].Role != "assistant" {
+		t.Errorf("Expected the reply to end the active branch, got %+v, %v", branch, err)
+	}
+
+	byModel, err := GetUsageTotals(UsageByModel, UsageFilter{})
+	if err != nil {
+		t.Fatalf("GetUsageTotals returned an error: %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Key != "claude-3-haiku-20240307" || byModel[0].Requests != 2 || byModel[0].InputTokens != 400 ||
+		byModel[0].CacheCreationTokens != 2048 || byModel[0].CacheReadTokens != 4096 {
+		t.Errorf("Expected haiku first with 2 requests, got %+v", byModel)
+	}
+
+	byConversation, err := GetUsageTotals(UsageByConversation, UsageFilter{ConversationID: first})
+	if err != nil {
+		t.Fatalf("GetUsageTotals returned an error: %v", err)
+	}
+	if len(byConversation) != 2 || byConversation[0].Title != "First" || byConversation[0].Model != "claude-3-opus-20240229" {
+		t.Errorf("Expected a row per model of the first conversation, largest first, got %+v", byConversation)
+	}
+
+	byDay, err := GetUsageTotals(UsageByDay, UsageFilter{Since: time.Now().Add(time.Hour)})
+	if err != nil || len(byDay) != 0 {
+		t.Errorf("Expected no usage in the future, got %+v, %v", byDay, err)
+	}
+
+	// Usage outlives its conversation.
+	err = DeleteConversation(second)
+	if err != nil {
+		t.Fatalf("DeleteConversation returned an error: %v", err)
+	}
+	byConversation, err = GetUsageTotals(UsageByConversation, UsageFilter{})
+	if err != nil {
+		t.Fatalf("GetUsageTotals returned an error: %v", err)
+	}
+	if len(byConversation) != 3 || byConversation[0].Title != "" || byConversation[0].InputTokens != 300 {
+		t.Errorf("Expected the deleted conversation's usage to stay, got %+v", byConversation)
+	}
+}
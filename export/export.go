@@ -0,0 +1,270 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Format is a file format conversations can be exported to.
+type Format string
+
+const (
+	FormatMarkdown Format = "md"
+	FormatJSON     Format = "json"
+	FormatJSONL    Format = "jsonl"
+	FormatHTML     Format = "html"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatMarkdown, FormatJSON, FormatJSONL, FormatHTML}
+
+// Conversation is a conversation as written by an export. The JSON and JSONL
+// formats hold every message of every branch, linked by ParentID; Markdown and
+// HTML show the active branch only.
+type Conversation struct {
+	ID              int64             `json:"id"`
+	Title           string            `json:"title"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Options         map[string]string `json:"options,omitempty"`
+	ActiveMessageID int64             `json:"active_message_id,omitempty"`
+	Messages        []Message         `json:"messages"`
+}
+
+// Message is a message as written by an export.
+type Message struct {
+	ID          int64        `json:"id"`
+	ParentID    int64        `json:"parent_id,omitempty"`
+	Role        string       `json:"role"`
+	Content     string       `json:"content"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is a file attached to an exported message. Data is the text of
+// text files and the base64 encoded contents of images and documents.
+type Attachment struct {
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ParseFormat reads a format name, accepting "markdown" for md.
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	if name == "markdown" {
+		return FormatMarkdown, nil
+	}
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of md, json, jsonl or html", name)
+}
+
+// Load reads a conversation, its options and all its messages.
+func Load(conversationID int64) (*Conversation, error) {
+	conversation, err := db.GetConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return nil, fmt.Errorf("conversation %d not found", conversationID)
+	}
+	options, err := db.GetConversationOptions(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := db.GetMessages(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := db.GetConversationAttachments(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	activeID, err := db.GetActiveMessageID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conversation{
+		ID:              conversation.ID,
+		Title:           conversation.Title,
+		CreatedAt:       conversation.CreatedAt,
+		UpdatedAt:       conversation.UpdatedAt,
+		ActiveMessageID: activeID,
+		Messages:        []Message{},
+	}
+	if len(options) > 0 {
+		c.Options = make(map[string]string, len(options))
+		for _, o := range options {
+			c.Options[o.OptionName] = o.OptionValue
+		}
+	}
+	for _, m := range messages {
+		message := Message{
+			ID:        m.ID,
+			ParentID:  m.ParentID,
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.CreatedAt,
+		}
+		for _, a := range attachments[m.ID] {
+			message.Attachments = append(message.Attachments, Attachment{
+				Path:      a.Path,
+				Kind:      a.Kind,
+				MediaType: a.MediaType,
+				Data:      a.Data,
+			})
+		}
+		c.Messages = append(c.Messages, message)
+	}
+	return c, nil
+}
+
+// ActiveBranch returns the messages leading to the active message, in order.
+func (c *Conversation) ActiveBranch() []Message {
+	byID := make(map[int64]Message, len(c.Messages))
+	for _, m := range c.Messages {
+		byID[m.ID] = m
+	}
+	var branch []Message
+	for id := c.ActiveMessageID; id != 0; {
+		m, ok := byID[id]
+		if !ok {
+			break
+		}
+		branch = append([]Message{m}, branch...)
+		id = m.ParentID
+	}
+	return branch
+}
+
+// Write writes the conversation to w in the given format.
+func Write(w io.Writer, c *Conversation, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return writeMarkdown(w, c)
+	case FormatJSON:
+		return writeJSON(w, c)
+	case FormatJSONL:
+		return writeJSONL(w, c)
+	case FormatHTML:
+		return writeHTML(w, c)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// ExportConversation writes a conversation to the file at path, or to stdout
+// when path is "-".
+func ExportConversation(conversationID int64, format Format, path string) error {
+	c, err := Load(conversationID)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return Write(os.Stdout, c, format)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = Write(f, c, format)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ExportAll writes every conversation to its own file. When out ends in .zip
+// the files are written to a single zip archive, otherwise to the directory
+// out, which is created if needed. It returns the names of the files written.
+func ExportAll(format Format, out string) ([]string, error) {
+	conversations, err := db.ListConversations()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(out), ".zip") {
+		return exportArchive(conversations, format, out)
+	}
+
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, conversation := range conversations {
+		path := filepath.Join(out, FileName(conversation.ID, conversation.Title, format))
+		err := ExportConversation(conversation.ID, format, path)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func exportArchive(conversations []db.Conversation, format Format, out string) ([]string, error) {
+	f, err := os.Create(out)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	var written []string
+	for _, conversation := range conversations {
+		c, err := Load(conversation.ID)
+		if err != nil {
+			return written, err
+		}
+		name := FileName(c.ID, c.Title, format)
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: c.UpdatedAt,
+		})
+		if err != nil {
+			return written, err
+		}
+		err = Write(w, c, format)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, name)
+	}
+	err = archive.Close()
+	if err != nil {
+		return written, err
+	}
+	return written, f.Close()
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileName names the export of a conversation after its ID and title, e.g.
+// "12-go-channels.md".
+func FileName(conversationID int64, title string, format Format) string {
+	slug := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == "" {
+		return fmt.Sprintf("%d.%s", conversationID, format)
+	}
+	return fmt.Sprintf("%d-%s.%s", conversationID, slug, format)
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+func setupConversation(t *testing.T) int64 {
+	t.Helper()
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	convID, err := db.CreateConversation("Go <channels>")
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	db.ConfigureConversation(convID, db.OptionModel, "claude-3-haiku-20240307")
+	db.AddMessage(convID, "user", "How do I close a channel?")
+	db.AddMessage(convID, "assistant", "Call close(ch).")
+	messages, _ := db.GetMessages(convID)
+
+	// An abandoned reply on another branch.
+	db.SetActiveMessage(convID, messages[0].ID)
+	db.AddMessage(convID, "assistant", "Use close on the <sender> side.")
+	return convID
+}
+
+func TestWriteFormats(t *testing.T) {
+	convID := setupConversation(t)
+	c, err := Load(convID)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	var md bytes.Buffer
+	err = Write(&md, c, FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Write md returned an error: %v", err)
+	}
+	if !strings.Contains(md.String(), "# Go <channels>") || !strings.Contains(md.String(), "- model: claude-3-haiku-20240307") {
+		t.Errorf("Expected the title and options in the Markdown export, got:\n%s", md.String())
+	}
+	if strings.Contains(md.String(), "Call close(ch).") || !strings.Contains(md.String(), "1 messages on other branches") {
+		t.Errorf("Expected the Markdown export to show the active branch only, got:\n%s", md.String())
+	}
+
+	var js bytes.Buffer
+	err = Write(&js, c, FormatJSON)
+	if err != nil {
+		t.Fatalf("Write json returned an error: %v", err)
+	}
+	var decoded Conversation
+	err = json.Unmarshal(js.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("JSON export does not decode: %v", err)
+	}
+	if decoded.Title != c.Title || len(decoded.Messages) != 3 || decoded.ActiveMessageID != c.ActiveMessageID {
+		t.Errorf("Expected the JSON export to hold every message, got %+v", decoded)
+	}
+
+	var jsonl bytes.Buffer
+	err = Write(&jsonl, c, FormatJSONL)
+	if err != nil {
+		t.Fatalf("Write jsonl returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"conversation_title":"Go <channels>"`) {
+		t.Errorf("Expected one line per message, got:\n%s", jsonl.String())
+	}
+
+	var html bytes.Buffer
+	err = Write(&html, c, FormatHTML)
+	if err != nil {
+		t.Fatalf("Write html returned an error: %v", err)
+	}
+	if !strings.Contains(html.String(), "&lt;sender&gt;") || strings.Contains(html.String(), "<sender>") {
+		t.Errorf("Expected message content to be escaped in the HTML export")
+	}
+}
+
+func TestExportAllArchive(t *testing.T) {
+	setupConversation(t)
+	db.CreateConversation("Second")
+
+	out := filepath.Join(t.TempDir(), "backup.zip")
+	written, err := ExportAll(FormatJSON, out)
+	if err != nil {
+		t.Fatalf("ExportAll returned an error: %v", err)
+	}
+	if len(written) != 2 {
+		t.Errorf("Expected 2 files, got %v", written)
+	}
+
+	archive, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer archive.Close()
+	var names []string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "1-go-channels.json,2-second.json" {
+		t.Errorf("Unexpected archive contents: %v", names)
+	}
+}
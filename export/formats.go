@@ -0,0 +1,164 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+const timeFormat = "2006-01-02 15:04:05"
+
+func writeMarkdown(w io.Writer, c *Conversation) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", displayTitle(c))
+	fmt.Fprintf(&sb, "- Conversation: %d\n", c.ID)
+	fmt.Fprintf(&sb, "- Created: %s\n", c.CreatedAt.Format(timeFormat))
+	fmt.Fprintf(&sb, "- Updated: %s\n", c.UpdatedAt.Format(timeFormat))
+	for _, name := range sortedKeys(c.Options) {
+		fmt.Fprintf(&sb, "- %s: %s\n", name, c.Options[name])
+	}
+
+	branch := c.ActiveBranch()
+	for _, m := range branch {
+		fmt.Fprintf(&sb, "\n## %s (%s)\n\n", m.Role, m.CreatedAt.Format(timeFormat))
+		for _, a := range m.Attachments {
+			fmt.Fprintf(&sb, "> Attached %s: `%s`\n\n", a.Kind, a.Path)
+		}
+		fmt.Fprintf(&sb, "%s\n", m.Content)
+	}
+	if other := len(c.Messages) - len(branch); other > 0 {
+		fmt.Fprintf(&sb, "\n---\n\n_%d messages on other branches are left out, export to json to keep them._\n", other)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeJSON(w io.Writer, c *Conversation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// jsonlMessage is one line of a JSONL export: a message along with the
+// conversation it belongs to.
+type jsonlMessage struct {
+	ConversationID    int64  `json:"conversation_id"`
+	ConversationTitle string `json:"conversation_title"`
+	Message
+}
+
+func writeJSONL(w io.Writer, c *Conversation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, m := range c.Messages {
+		err := encoder.Encode(jsonlMessage{
+			ConversationID:    c.ID,
+			ConversationTitle: c.Title,
+			Message:           m,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var htmlTemplate = template.Must(template.New("conversation").Funcs(template.FuncMap{
+	"dataURL": func(a Attachment) template.URL {
+		return template.URL("data:" + a.MediaType + ";base64," + a.Data)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #222; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 0.2em 1em; color: #555; }
+dt { font-weight: bold; }
+dd { margin: 0; }
+.message { border-radius: 6px; margin: 1em 0; padding: 0.8em 1em; }
+.user { background: #eef3fb; }
+.assistant { background: #f6f6f6; }
+.meta { font-size: 0.85em; color: #666; margin-bottom: 0.5em; }
+.content { white-space: pre-wrap; }
+.attachment { font-size: 0.85em; color: #555; }
+.attachment img { max-width: 100%; display: block; margin-top: 0.3em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<dl>
+<dt>Conversation</dt><dd>{{.ID}}</dd>
+<dt>Created</dt><dd>{{.Created}}</dd>
+<dt>Updated</dt><dd>{{.Updated}}</dd>
+{{- range .Options}}
+<dt>{{.Name}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+{{- range .Messages}}
+<div class="message {{.Role}}">
+<div class="meta">{{.Role}} &middot; {{.CreatedAt.Format "2006-01-02 15:04:05"}}</div>
+{{- range .Attachments}}
+<div class="attachment">Attached {{.Kind}}: {{.Path}}{{if eq .Kind "image"}}<img src="{{dataURL .}}" alt="{{.Path}}">{{end}}</div>
+{{- end}}
+<div class="content">{{.Content}}</div>
+</div>
+{{- end}}
+{{- if .Omitted}}
+<p class="meta">{{.Omitted}} messages on other branches are left out, export to json to keep them.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlOption struct {
+	Name  string
+	Value string
+}
+
+func writeHTML(w io.Writer, c *Conversation) error {
+	branch := c.ActiveBranch()
+	var options []htmlOption
+	for _, name := range sortedKeys(c.Options) {
+		options = append(options, htmlOption{Name: name, Value: c.Options[name]})
+	}
+	return htmlTemplate.Execute(w, struct {
+		ID       int64
+		Title    string
+		Created  string
+		Updated  string
+		Options  []htmlOption
+		Messages []Message
+		Omitted  int
+	}{
+		ID:       c.ID,
+		Title:    displayTitle(c),
+		Created:  c.CreatedAt.Format(timeFormat),
+		Updated:  c.UpdatedAt.Format(timeFormat),
+		Options:  options,
+		Messages: branch,
+		Omitted:  len(c.Messages) - len(branch),
+	})
+}
+
+func displayTitle(c *Conversation) string {
+	if c.Title == "" {
+		return "Untitled conversation"
+	}
+	return c.Title
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
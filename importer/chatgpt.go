@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chatGPTConversation is a conversation of the conversations.json file in a
+// ChatGPT data export. Its messages are a tree keyed by node ID.
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	UpdateTime     float64                `json:"update_time"`
+	CurrentNode    string                 `json:"current_node"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID      string `json:"id"`
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		CreateTime float64 `json:"create_time"`
+		Content    struct {
+			ContentType string            `json:"content_type"`
+			Parts       []json.RawMessage `json:"parts"`
+			Text        string            `json:"text"`
+			Language    string            `json:"language"`
+		} `json:"content"`
+		Metadata struct {
+			IsVisuallyHiddenFromConversation bool `json:"is_visually_hidden_from_conversation"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+func parseChatGPT(data []byte) ([]Conversation, error) {
+	var exported []chatGPTConversation
+	err := json.Unmarshal(data, &exported)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []Conversation
+	for _, c := range exported {
+		id := c.ConversationID
+		if id == "" {
+			id = c.ID
+		}
+		conversation := Conversation{
+			SourceID:  id,
+			Title:     c.Title,
+			CreatedAt: unixSeconds(c.CreateTime),
+			UpdatedAt: unixSeconds(c.UpdateTime),
+			ActiveID:  c.CurrentNode,
+		}
+
+		nodeIDs := make([]string, 0, len(c.Mapping))
+		for nodeID := range c.Mapping {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+		sort.Strings(nodeIDs)
+		for _, nodeID := range nodeIDs {
+			node := c.Mapping[nodeID]
+			message := Message{SourceID: nodeID, ParentID: node.Parent}
+			if node.Message != nil && !node.Message.Metadata.IsVisuallyHiddenFromConversation {
+				m := node.Message
+				message.Role = m.Author.Role
+				message.CreatedAt = unixSeconds(m.CreateTime)
+
+				text, skipped := chatGPTText(m.Content.ContentType, m.Content.Parts, m.Content.Text, m.Content.Language)
+				message.Content = text
+				for _, s := range skipped {
+					conversation.Skipped = append(conversation.Skipped, fmt.Sprintf("%s in message %s", s, nodeID))
+				}
+			}
+			conversation.Messages = append(conversation.Messages, message)
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// chatGPTText extracts the text of a message. Parts that are not text, such
+// as images, are reported as skipped.
+func chatGPTText(contentType string, parts []json.RawMessage, text, language string) (string, []string) {
+	switch contentType {
+	case "text", "multimodal_text":
+		var texts, skipped []string
+		for _, part := range parts {
+			var s string
+			if json.Unmarshal(part, &s) == nil {
+				if s != "" {
+					texts = append(texts, s)
+				}
+				continue
+			}
+			var object struct {
+				ContentType string `json:"content_type"`
+			}
+			json.Unmarshal(part, &object)
+			skipped = append(skipped, fmt.Sprintf("%s part", object.ContentType))
+		}
+		return strings.Join(texts, "\n\n"), skipped
+	case "code":
+		return "```" + language + "\n" + text + "\n```", nil
+	case "":
+		return "", nil
+	}
+	return "", []string{contentType + " content"}
+}
+
+func unixSeconds(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*1e9))
+}
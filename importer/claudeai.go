@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+// claudeAIConversation is a conversation of the conversations.json file in a
+// claude.ai data export.
+type claudeAIConversation struct {
+	UUID         string            `json:"uuid"`
+	Name         string            `json:"name"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	ChatMessages []claudeAIMessage `json:"chat_messages"`
+}
+
+type claudeAIMessage struct {
+	UUID       string `json:"uuid"`
+	ParentUUID string `json:"parent_message_uuid"`
+	Sender     string `json:"sender"`
+	Text       string `json:"text"`
+	Content    []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+	Attachments []struct {
+		FileName         string `json:"file_name"`
+		ExtractedContent string `json:"extracted_content"`
+	} `json:"attachments"`
+	Files []struct {
+		FileName string `json:"file_name"`
+	} `json:"files"`
+}
+
+func parseClaudeAI(data []byte) ([]Conversation, error) {
+	var exported []claudeAIConversation
+	err := json.Unmarshal(data, &exported)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []Conversation
+	for _, c := range exported {
+		conversation := Conversation{
+			SourceID:  c.UUID,
+			Title:     c.Name,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		}
+
+		// Older exports have no parent pointers, their messages are a
+		// single branch in order.
+		previous := ""
+		for i, m := range c.ChatMessages {
+			id := m.UUID
+			if id == "" {
+				id = strconv.Itoa(i)
+			}
+			parentID := m.ParentUUID
+			if parentID == rootMessageUUID {
+				parentID = ""
+			} else if parentID == "" {
+				parentID = previous
+			}
+
+			message := Message{
+				SourceID:  id,
+				ParentID:  parentID,
+				Role:      claudeAIRole(m.Sender),
+				Content:   claudeAIText(m),
+				CreatedAt: m.CreatedAt,
+			}
+			for _, a := range m.Attachments {
+				if a.ExtractedContent == "" {
+					conversation.Skipped = append(conversation.Skipped, fmt.Sprintf("attachment %s without its contents", a.FileName))
+					continue
+				}
+				message.Attachments = append(message.Attachments, db.Attachment{
+					Path:      a.FileName,
+					Kind:      db.AttachmentKindText,
+					MediaType: "text/plain",
+					Data:      a.ExtractedContent,
+				})
+			}
+			for _, f := range m.Files {
+				conversation.Skipped = append(conversation.Skipped, fmt.Sprintf("file %s, the export does not include its contents", f.FileName))
+			}
+			conversation.Messages = append(conversation.Messages, message)
+			previous = id
+		}
+		conversation.ActiveID = previous
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// rootMessageUUID is the parent claude.ai gives the first message.
+const rootMessageUUID = "00000000-0000-4000-8000-000000000000"
+
+func claudeAIRole(sender string) string {
+	if sender == "human" {
+		return "user"
+	}
+	return sender
+}
+
+func claudeAIText(m claudeAIMessage) string {
+	var parts []string
+	for _, c := range m.Content {
+		if c.Type == "text" && c.Text != "" {
+			parts = append(parts, c.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return m.Text
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/export"
+)
+
+// parseGoClaude reads a JSON export written by go-claude: a single
+// conversation or a list of them.
+func parseGoClaude(data []byte) ([]Conversation, error) {
+	var exported []export.Conversation
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var c export.Conversation
+		err := json.Unmarshal(data, &c)
+		if err != nil {
+			return nil, err
+		}
+		exported = append(exported, c)
+	} else {
+		err := json.Unmarshal(data, &exported)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var conversations []Conversation
+	for _, c := range exported {
+		conversations = append(conversations, fromExport(c))
+	}
+	return conversations, nil
+}
+
+// parseGoClaudeJSONL reads a JSONL export written by go-claude, where every
+// line is a message along with the conversation it belongs to.
+func parseGoClaudeJSONL(data []byte) ([]Conversation, error) {
+	var line struct {
+		ConversationID    int64  `json:"conversation_id"`
+		ConversationTitle string `json:"conversation_title"`
+		export.Message
+	}
+
+	var order []int64
+	byID := make(map[int64]*export.Conversation)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		line.Message = export.Message{}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		c, ok := byID[line.ConversationID]
+		if !ok {
+			c = &export.Conversation{ID: line.ConversationID, Title: line.ConversationTitle, CreatedAt: line.CreatedAt}
+			byID[line.ConversationID] = c
+			order = append(order, line.ConversationID)
+		}
+		c.Messages = append(c.Messages, line.Message)
+		c.UpdatedAt = line.CreatedAt
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var conversations []Conversation
+	for _, id := range order {
+		conversations = append(conversations, fromExport(*byID[id]))
+	}
+	return conversations, nil
+}
+
+func fromExport(c export.Conversation) Conversation {
+	conversation := Conversation{
+		// The ID alone is only unique within the database it was exported
+		// from, the creation time tells apart exports of different ones.
+		SourceID:  fmt.Sprintf("%d@%d", c.ID, c.CreatedAt.Unix()),
+		Title:     c.Title,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Options:   make(map[string]string),
+	}
+	for name, value := range c.Options {
+		if name == db.OptionImportSource {
+			continue
+		}
+		conversation.Options[name] = value
+	}
+	if c.ActiveMessageID != 0 {
+		conversation.ActiveID = strconv.FormatInt(c.ActiveMessageID, 10)
+	}
+
+	for _, m := range c.Messages {
+		message := Message{
+			SourceID:  strconv.FormatInt(m.ID, 10),
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.CreatedAt,
+		}
+		if m.ParentID != 0 {
+			message.ParentID = strconv.FormatInt(m.ParentID, 10)
+		}
+		for _, a := range m.Attachments {
+			switch a.Kind {
+			case db.AttachmentKindText, db.AttachmentKindImage, db.AttachmentKindDocument:
+				message.Attachments = append(message.Attachments, db.Attachment{
+					Path:      a.Path,
+					Kind:      a.Kind,
+					MediaType: a.MediaType,
+					Data:      a.Data,
+				})
+			default:
+				conversation.Skipped = append(conversation.Skipped, fmt.Sprintf("attachment %s of message %d with unknown kind %q", a.Path, m.ID, a.Kind))
+			}
+		}
+		conversation.Messages = append(conversation.Messages, message)
+	}
+	return conversation
+}
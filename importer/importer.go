@@ -0,0 +1,390 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/db"
+)
+
+// Format is a conversation export format that can be imported.
+type Format string
+
+const (
+	FormatGoClaude      Format = "go-claude"       // export conversations --format json
+	FormatGoClaudeJSONL Format = "go-claude-jsonl" // export conversations --format jsonl
+	FormatClaudeAI      Format = "claude.ai"       // conversations.json of a claude.ai data export
+	FormatChatGPT       Format = "chatgpt"         // conversations.json of a ChatGPT data export
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatGoClaude, FormatGoClaudeJSONL, FormatClaudeAI, FormatChatGPT}
+
+// Conversation is a conversation read from an export, before it is stored.
+// Messages reference their parent by the ID they had in the export, so
+// branched histories survive the import.
+type Conversation struct {
+	SourceID  string // unique within the format, used to skip re-imports
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Options   map[string]string
+	Messages  []Message
+	ActiveID  string // the message the active branch ends on, the last message when empty
+	Skipped   []string
+}
+
+// Message is a message read from an export.
+type Message struct {
+	SourceID    string
+	ParentID    string // empty for the first message
+	Role        string
+	Content     string
+	CreatedAt   time.Time
+	Attachments []db.Attachment
+}
+
+// Report lists what an import stored and what it left out.
+type Report struct {
+	Imported []Imported
+	Skipped  []Skipped
+}
+
+// Imported is a conversation stored by an import.
+type Imported struct {
+	Title          string
+	ConversationID int64
+	Messages       int
+	Skipped        []string // messages left out, with the reason
+}
+
+// Skipped is a conversation an import left out.
+type Skipped struct {
+	Title  string
+	Reason string
+}
+
+// ParseFormat reads a format name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(string(f), name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown import format %q, expected one of go-claude, go-claude-jsonl, claude.ai or chatgpt", name)
+}
+
+// DetectFormat guesses the format of an export from its file name and the
+// shape of its contents.
+func DetectFormat(path string, data []byte) (Format, error) {
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		return FormatGoClaudeJSONL, nil
+	}
+
+	data = bytes.TrimSpace(data)
+	var probe map[string]json.RawMessage
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		err := json.Unmarshal(data, &probe)
+		if err != nil {
+			return "", err
+		}
+	case bytes.HasPrefix(data, []byte("[")):
+		var list []map[string]json.RawMessage
+		err := json.Unmarshal(data, &list)
+		if err != nil {
+			return "", err
+		}
+		if len(list) == 0 {
+			return FormatGoClaude, nil
+		}
+		probe = list[0]
+	default:
+		return "", fmt.Errorf("%s is not a JSON export", path)
+	}
+
+	switch {
+	case probe["mapping"] != nil:
+		return FormatChatGPT, nil
+	case probe["chat_messages"] != nil:
+		return FormatClaudeAI, nil
+	case probe["messages"] != nil:
+		return FormatGoClaude, nil
+	}
+	return "", fmt.Errorf("could not tell the format of %s, pass --format", path)
+}
+
+// Parse reads the conversations of an export.
+func Parse(format Format, data []byte) ([]Conversation, error) {
+	switch format {
+	case FormatGoClaude:
+		return parseGoClaude(data)
+	case FormatGoClaudeJSONL:
+		return parseGoClaudeJSONL(data)
+	case FormatClaudeAI:
+		return parseClaudeAI(data)
+	case FormatChatGPT:
+		return parseChatGPT(data)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// ImportFile imports every conversation of an export file. An empty format
+// is detected from the file.
+func ImportFile(path string, format Format) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format, err = DetectFormat(path, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conversations, err := Parse(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return Import(format, conversations)
+}
+
+// Import stores conversations in a single transaction: either all of them are
+// imported or none. Conversations imported before from the same source are
+// skipped, as are messages the conversation history cannot hold.
+func Import(format Format, conversations []Conversation) (*Report, error) {
+	report := &Report{}
+	err := db.RunInTransaction(func(tx *db.Tx) error {
+		for _, c := range conversations {
+			title := c.Title
+			if title == "" {
+				title = "Imported conversation"
+			}
+
+			source := string(format) + ":" + c.SourceID
+			existing, err := tx.FindConversationByOption(db.OptionImportSource, source)
+			if err != nil {
+				return err
+			}
+			if existing != 0 {
+				report.Skipped = append(report.Skipped, Skipped{Title: title, Reason: fmt.Sprintf("already imported as conversation %d", existing)})
+				continue
+			}
+
+			messages, skipped := normalize(c.Messages)
+			skipped = append(c.Skipped, skipped...)
+			if len(messages) == 0 {
+				report.Skipped = append(report.Skipped, Skipped{Title: title, Reason: "no messages to import"})
+				continue
+			}
+
+			// A persona is not part of the export, the conversation
+			// falls back to its own options without it.
+			if persona := c.Options[db.OptionPersona]; persona != "" {
+				exists, err := tx.HasPersona(persona)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					c.Options = maps.Clone(c.Options)
+					delete(c.Options, db.OptionPersona)
+					skipped = append(skipped, fmt.Sprintf("persona %s, which does not exist in this database", persona))
+				}
+			}
+
+			conversationID, err := store(tx, c, title, source, messages)
+			if err != nil {
+				return fmt.Errorf("%s: %w", title, err)
+			}
+			report.Imported = append(report.Imported, Imported{
+				Title:          title,
+				ConversationID: conversationID,
+				Messages:       len(messages),
+				Skipped:        skipped,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func store(tx *db.Tx, c Conversation, title, source string, messages []Message) (int64, error) {
+	conversationID, err := tx.CreateConversation(title, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range c.Options {
+		err := tx.ConfigureConversation(conversationID, name, value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = tx.ConfigureConversation(conversationID, db.OptionImportSource, source)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make(map[string]int64, len(messages))
+	var lastID int64
+	for _, m := range messages {
+		id, err := tx.AddMessage(conversationID, ids[m.ParentID], m.Role, m.Content, m.CreatedAt, m.Attachments)
+		if err != nil {
+			return 0, err
+		}
+		ids[m.SourceID] = id
+		lastID = id
+	}
+
+	// The active message may have been dropped or merged, its closest stored
+	// ancestor takes its place.
+	activeID := lastID
+	parents := make(map[string]string, len(c.Messages))
+	for _, m := range c.Messages {
+		parents[m.SourceID] = m.ParentID
+	}
+	for id, n := c.ActiveID, 0; id != "" && n <= len(parents); id, n = parents[id], n+1 {
+		if stored, ok := ids[id]; ok {
+			activeID = stored
+			break
+		}
+	}
+	return conversationID, tx.SetActiveMessage(conversationID, activeID)
+}
+
+// normalize orders messages so every parent comes before its replies and
+// drops the ones Claude cannot be sent: other roles than user and assistant,
+// and empty messages. Replies to a dropped message move up to its parent. A
+// message following one of the same role as its only reply is merged into it,
+// since the API requires the roles to alternate.
+func normalize(messages []Message) ([]Message, []string) {
+	messages = append([]Message(nil), messages...)
+	var skipped []string
+	byID := make(map[string]*Message, len(messages))
+	for i := range messages {
+		byID[messages[i].SourceID] = &messages[i]
+	}
+
+	// Resolve the parent of every kept message to its closest kept ancestor.
+	keep := func(m *Message) bool {
+		return (m.Role == "user" || m.Role == "assistant") && (strings.TrimSpace(m.Content) != "" || len(m.Attachments) > 0)
+	}
+	var kept []*Message
+	for i := range messages {
+		m := &messages[i]
+		if !keep(m) {
+			if m.Role == "user" || m.Role == "assistant" {
+				skipped = append(skipped, fmt.Sprintf("empty %s message %s", m.Role, m.SourceID))
+			} else if m.Role != "" {
+				skipped = append(skipped, fmt.Sprintf("%s message %s", m.Role, m.SourceID))
+			}
+			continue
+		}
+		kept = append(kept, m)
+	}
+	parentOf := func(m *Message) string {
+		seen := map[string]bool{}
+		id := m.ParentID
+		for id != "" && !seen[id] {
+			seen[id] = true
+			p, ok := byID[id]
+			if !ok {
+				return ""
+			}
+			if keep(p) {
+				return id
+			}
+			id = p.ParentID
+		}
+		return ""
+	}
+	for _, m := range kept {
+		m.ParentID = parentOf(m)
+	}
+
+	// Merge runs of the same role.
+	children := make(map[string][]*Message)
+	for _, m := range kept {
+		children[m.ParentID] = append(children[m.ParentID], m)
+	}
+	merged := make(map[string]string) // merged message -> message it was merged into
+	for _, m := range kept {
+		if _, gone := merged[m.SourceID]; gone || m.ParentID == "" {
+			continue
+		}
+		parentID := m.ParentID
+		for {
+			if target, ok := merged[parentID]; ok {
+				parentID = target
+				continue
+			}
+			break
+		}
+		p := byID[parentID]
+		if p.Role != m.Role || len(children[m.ParentID]) != 1 {
+			continue
+		}
+		p.Content = strings.TrimSpace(p.Content + "\n\n" + m.Content)
+		p.Attachments = append(p.Attachments, m.Attachments...)
+		merged[m.SourceID] = parentID
+	}
+
+	var result []Message
+	for _, m := range kept {
+		if _, gone := merged[m.SourceID]; gone {
+			continue
+		}
+		for {
+			target, ok := merged[m.ParentID]
+			if !ok {
+				break
+			}
+			m.ParentID = target
+		}
+		result = append(result, *m)
+	}
+	return sortParentsFirst(result), skipped
+}
+
+// sortParentsFirst orders messages by depth in the tree, then by time, so
+// every parent is stored before its replies.
+func sortParentsFirst(messages []Message) []Message {
+	byID := make(map[string]Message, len(messages))
+	for _, m := range messages {
+		byID[m.SourceID] = m
+	}
+	depth := make(map[string]int, len(messages))
+	var depthOf func(id string, seen int) int
+	depthOf = func(id string, seen int) int {
+		m, ok := byID[id]
+		if !ok || seen > len(messages) {
+			return -1
+		}
+		if d, ok := depth[id]; ok {
+			return d
+		}
+		d := depthOf(m.ParentID, seen+1) + 1
+		depth[id] = d
+		return d
+	}
+	for _, m := range messages {
+		depthOf(m.SourceID, 0)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		di, dj := depth[messages[i].SourceID], depth[messages[j].SourceID]
+		if di != dj {
+			return di < dj
+		}
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/export"
+)
+
+func initDatabase(t *testing.T) {
+	t.Helper()
+	err := db.InitDatabase(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(db.Close)
+}
+
+func TestImportClaudeAI(t *testing.T) {
+	initDatabase(t)
+
+	report, err := ImportFile(filepath.Join("testdata", "claudeai.json"), "")
+	if err != nil {
+		t.Fatalf("ImportFile returned an error: %v", err)
+	}
+	if len(report.Imported) != 1 || len(report.Skipped) != 1 {
+		t.Fatalf("Expected 1 imported and 1 skipped conversation, got %+v", report)
+	}
+	imported := report.Imported[0]
+	if imported.Messages != 2 || len(imported.Skipped) != 1 || !strings.Contains(imported.Skipped[0], "diagram.png") {
+		t.Errorf("Expected 2 messages and the missing file reported, got %+v", imported)
+	}
+
+	conversation, _ := db.GetConversation(imported.ConversationID)
+	if conversation.Title != "Sorting in Go" || conversation.CreatedAt.Format("2006-01-02 15:04") != "2024-05-01 10:00" {
+		t.Errorf("Expected the title and creation time to be kept, got %+v", conversation)
+	}
+	branch, _ := db.GetActiveBranch(imported.ConversationID)
+	if len(branch) != 2 || branch[0].Role != "user" || branch[1].Content != "Use sort.Slice." {
+		t.Errorf("Unexpected messages %+v", branch)
+	}
+	attachments, _ := db.GetMessageAttachments(branch[0].ID)
+	if len(attachments) != 1 || attachments[0].Path != "main.go" {
+		t.Errorf("Expected the extracted attachment to be kept, got %+v", attachments)
+	}
+
+	// Importing the same file again doesn't duplicate anything.
+	report, err = ImportFile(filepath.Join("testdata", "claudeai.json"), "")
+	if err != nil {
+		t.Fatalf("ImportFile returned an error: %v", err)
+	}
+	if len(report.Imported) != 0 || !strings.Contains(report.Skipped[0].Reason, "already imported") {
+		t.Errorf("Expected the re-import to be skipped, got %+v", report)
+	}
+}
+
+func TestImportChatGPT(t *testing.T) {
+	initDatabase(t)
+
+	report, err := ImportFile(filepath.Join("testdata", "chatgpt.json"), FormatChatGPT)
+	if err != nil {
+		t.Fatalf("ImportFile returned an error: %v", err)
+	}
+	if len(report.Imported) != 1 {
+		t.Fatalf("Expected 1 imported conversation, got %+v", report)
+	}
+	imported := report.Imported[0]
+
+	// The two user messages are merged and the tool message dropped, leaving
+	// both replies as branches of the merged question.
+	if imported.Messages != 3 {
+		t.Errorf("Expected 3 messages, got %d", imported.Messages)
+	}
+	leaves, _ := db.GetBranchLeaves(imported.ConversationID)
+	if len(leaves) != 2 {
+		t.Errorf("Expected 2 branches, got %d", len(leaves))
+	}
+	branch, _ := db.GetActiveBranch(imported.ConversationID)
+	if len(branch) != 2 || branch[0].Content != "Hi\n\nWhat is in this picture?" || branch[1].Content != "It is a cat." {
+		t.Errorf("Expected the current node to be the active branch, got %+v", branch)
+	}
+	reasons := strings.Join(imported.Skipped, ", ")
+	if !strings.Contains(reasons, "image_asset_pointer") || !strings.Contains(reasons, "tool message t1") {
+		t.Errorf("Expected the image and tool message to be reported, got %s", reasons)
+	}
+}
+
+func TestImportGoClaudeExport(t *testing.T) {
+	initDatabase(t)
+
+	convID, _ := db.CreateConversation("Round trip")
+	db.ConfigureConversation(convID, db.OptionModel, "claude-3-haiku-20240307")
+	db.AddMessage(convID, "user", "Question")
+	db.AddMessage(convID, "assistant", "Answer")
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	err := export.ExportConversation(convID, export.FormatJSON, path)
+	if err != nil {
+		t.Fatalf("ExportConversation returned an error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	format, err := DetectFormat(path, data)
+	if err != nil || format != FormatGoClaude {
+		t.Fatalf("Expected the go-claude format to be detected, got %q, %v", format, err)
+	}
+
+	report, err := ImportFile(path, "")
+	if err != nil {
+		t.Fatalf("ImportFile returned an error: %v", err)
+	}
+	if len(report.Imported) != 1 || report.Imported[0].Messages != 2 {
+		t.Fatalf("Expected the conversation to be imported, got %+v", report)
+	}
+	options, _ := db.GetConversationOptions(report.Imported[0].ConversationID)
+	found := false
+	for _, o := range options {
+		if o.OptionName == db.OptionModel && o.OptionValue == "claude-3-haiku-20240307" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the conversation options to be imported, got %+v", options)
+	}
+}
+
+func TestImportMissingPersona(t *testing.T) {
+	initDatabase(t)
+	err := db.SavePersona(db.Persona{Name: "reviewer", SystemPrompt: "You review code."})
+	if err != nil {
+		t.Fatalf("SavePersona returned an error: %v", err)
+	}
+
+	messages := []Message{
+		{SourceID: "1", Role: "user", Content: "Question"},
+		{SourceID: "2", ParentID: "1", Role: "assistant", Content: "Answer"},
+	}
+	conversations := []Conversation{
+		{SourceID: "1", Title: "Known", Options: map[string]string{db.OptionPersona: "reviewer"}, Messages: messages},
+		{SourceID: "2", Title: "Missing", Options: map[string]string{db.OptionPersona: "translator", db.OptionSystem: "Answer in French."}, Messages: messages},
+	}
+	report, err := Import(FormatGoClaude, conversations)
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if len(report.Imported) != 2 {
+		t.Fatalf("Expected both conversations to be imported, got %+v", report)
+	}
+
+	want := []map[string]string{
+		{db.OptionPersona: "reviewer"},
+		{db.OptionSystem: "Answer in French."},
+	}
+	for i, imported := range report.Imported {
+		options, err := db.GetConversationOptions(imported.ConversationID)
+		if err != nil {
+			t.Fatalf("GetConversationOptions returned an error: %v", err)
+		}
+		got := map[string]string{}
+		for _, o := range options {
+			if o.OptionName != db.OptionImportSource {
+				got[o.OptionName] = o.OptionValue
+			}
+		}
+		if len(got) != len(want[i]) {
+			t.Errorf("%s: expected options %v, got %v", imported.Title, want[i], got)
+		}
+		for name, value := range want[i] {
+			if got[name] != value {
+				t.Errorf("%s: expected options %v, got %v", imported.Title, want[i], got)
+			}
+		}
+	}
+	if skipped := report.Imported[1].Skipped; len(skipped) != 1 || !strings.Contains(skipped[0], "persona translator") {
+		t.Errorf("Expected the missing persona to be reported, got %q", skipped)
+	}
+	if conversations[1].Options[db.OptionPersona] != "translator" {
+		t.Errorf("Expected the parsed conversation to be left alone")
+	}
+}
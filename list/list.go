@@ -70,3 +70,33 @@ func ShowConvList() error {
 
 	return nil
 }
+
+func ShowPersonaList() error {
+	personas, err := db.ListPersonas()
+	if err != nil {
+		logger.FatalError(err, "Error listing personas.")
+	}
+
+	term := terminal.New()
+
+	table := term.NewTable(30)
+
+	table.AddColumn("Name", "Name", 10, nil, false, 0)
+	table.AddColumn("Model", "Model", 10, nil, false, 0)
+	table.AddColumn("System Prompt", "System", 40, nil, true, 0)
+	timeMaxWidth := 19
+	table.AddColumn("Updated", "Updated", 19, &timeMaxWidth, false, terminal.AlignCenter)
+
+	for _, p := range personas {
+		table.AddRow(map[string]interface{}{
+			"Name":    p.Name,
+			"Model":   p.Model,
+			"System":  p.SystemPrompt,
+			"Updated": p.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	table.Render()
+
+	return nil
+}
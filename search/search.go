@@ -0,0 +1,77 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/terminal"
+)
+
+// ShowSearchResults runs a search and renders the hits in a table, best
+// match first.
+func ShowSearchResults(query string, opts db.SearchOptions) error {
+	results, err := db.SearchMessages(query, opts)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("No messages found.")
+		return nil
+	}
+
+	term := terminal.New()
+
+	table := term.NewTable(30)
+
+	idMaxWidth := 7
+	table.AddColumn("Conv", "Conversation", 5, &idMaxWidth, false, 0)
+	table.AddColumn("Title", "Title", 15, nil, true, 0)
+	table.AddColumn("Msg", "Message", 5, &idMaxWidth, false, 0)
+	table.AddColumn("Role", "Role", 5, nil, false, 0)
+	table.AddColumn("Match", "Snippet", 40, nil, true, 0)
+	timeMaxWidth := 19
+	table.AddColumn("Created", "Created", 19, &timeMaxWidth, false, terminal.AlignCenter)
+
+	for _, r := range results {
+		table.AddRow(map[string]interface{}{
+			"Conversation": r.ConversationID,
+			"Title":        r.ConversationTitle,
+			"Message":      r.ID,
+			"Role":         r.Role,
+			"Snippet":      strings.Join(strings.Fields(r.Snippet), " "),
+			"Created":      r.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	table.Render()
+
+	return nil
+}
+
+// ParseSince reads a point in time given either as a date (2024-06-01), a
+// timestamp (RFC 3339) or an age relative to now such as 36h, 7d or 2w.
+func ParseSince(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(value) > 1 {
+		if multiple, ok := unit[value[len(value)-1]]; ok {
+			n, err := strconv.Atoi(value[:len(value)-1])
+			if err == nil && n >= 0 {
+				return time.Now().Add(-time.Duration(n) * multiple), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a date such as 2024-06-01 or an age such as 36h, 7d or 2w", value)
+}
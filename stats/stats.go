@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/christianhturner/go-claude/terminal"
+)
+
+// Groups lists the ways usage can be broken down, in the order ShowStats
+// renders them.
+var Groups = []db.UsageGroup{db.UsageByModel, db.UsageByDay, db.UsageByConversation}
+
+// Row is the usage of a group across every model it used.
+type Row struct {
+	Key                 string
+	Title               string
+	Requests            int64
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	Latency             time.Duration // average per request
+	Cost                float64       // in US dollars, of the models with a known price
+	Unpriced            []string      // models without a price
+}
+
+// Summarize adds up the per model totals of every group, keeping the order
+// of the groups.
+func Summarize(totals []db.UsageTotal) []Row {
+	var rows []Row
+	index := make(map[string]int)
+	latency := make(map[string]time.Duration)
+	for _, t := range totals {
+		i, ok := index[t.Key]
+		if !ok {
+			i = len(rows)
+			index[t.Key] = i
+			rows = append(rows, Row{Key: t.Key, Title: t.Title})
+		}
+		r := &rows[i]
+		r.Requests += t.Requests
+		r.InputTokens += t.InputTokens
+		r.OutputTokens += t.OutputTokens
+		r.CacheCreationTokens += t.CacheCreationTokens
+		r.CacheReadTokens += t.CacheReadTokens
+		latency[t.Key] += t.Latency
+		cost, ok := config.UsageCost(t.Model, t.InputTokens, t.OutputTokens, t.CacheCreationTokens, t.CacheReadTokens, t.Batch)
+		if ok {
+			r.Cost += cost
+		} else if !slices.Contains(r.Unpriced, t.Model) {
+			r.Unpriced = append(r.Unpriced, t.Model)
+		}
+	}
+	for i := range rows {
+		if rows[i].Requests > 0 {
+			rows[i].Latency = latency[rows[i].Key] / time.Duration(rows[i].Requests)
+		}
+	}
+	return rows
+}
+
+// ShowStats renders the usage matching filter broken down by each of groups.
+func ShowStats(groups []db.UsageGroup, filter db.UsageFilter) error {
+	var unpriced []string
+	for i, group := range groups {
+		totals, err := db.GetUsageTotals(group, filter)
+		if err != nil {
+			return err
+		}
+		rows := Summarize(totals)
+		if i == 0 && len(rows) == 0 {
+			fmt.Println("No usage recorded yet.")
+			return nil
+		}
+		for _, r := range rows {
+			for _, model := range r.Unpriced {
+				if !slices.Contains(unpriced, model) {
+					unpriced = append(unpriced, model)
+				}
+			}
+		}
+
+		fmt.Printf("\nBy %s\n", group)
+		showRows(group, rows)
+	}
+	if len(unpriced) > 0 {
+		sort.Strings(unpriced)
+		fmt.Printf("\nNo price is known for %s, their tokens are not in the cost. Add them to %q in the config file.\n",
+			strings.Join(unpriced, ", "), config.PricingKey)
+	}
+	return nil
+}
+
+func showRows(group db.UsageGroup, rows []Row) {
+	term := terminal.New()
+
+	table := term.NewTable(30)
+
+	switch group {
+	case db.UsageByConversation:
+		idMaxWidth := 7
+		table.AddColumn("Conv", "Key", 5, &idMaxWidth, false, 0)
+		table.AddColumn("Title", "Title", 15, nil, true, 0)
+	case db.UsageByDay:
+		dayMaxWidth := 10
+		table.AddColumn("Day", "Key", 10, &dayMaxWidth, false, terminal.AlignCenter)
+	default:
+		table.AddColumn("Model", "Key", 26, nil, true, 0)
+	}
+	numberMaxWidth := 12
+	table.AddColumn("Requests", "Requests", 8, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Input", "Input", 8, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Output", "Output", 8, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Cache W", "CacheWrite", 7, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Cache R", "CacheRead", 7, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Latency", "Latency", 7, &numberMaxWidth, false, terminal.AlignRight)
+	table.AddColumn("Cost", "Cost", 8, &numberMaxWidth, false, terminal.AlignRight)
+
+	var total Row
+	for _, r := range rows {
+		title := r.Title
+		if group == db.UsageByConversation && title == "" {
+			title = "(deleted)"
+		}
+		table.AddRow(map[string]interface{}{
+			"Key":        r.Key,
+			"Title":      title,
+			"Requests":   r.Requests,
+			"Input":      r.InputTokens,
+			"Output":     r.OutputTokens,
+			"CacheWrite": r.CacheCreationTokens,
+			"CacheRead":  r.CacheReadTokens,
+			"Latency":    r.Latency.Round(100 * time.Millisecond).String(),
+			"Cost":       formatCost(r),
+		})
+		total.Requests += r.Requests
+		total.InputTokens += r.InputTokens
+		total.OutputTokens += r.OutputTokens
+		total.CacheCreationTokens += r.CacheCreationTokens
+		total.CacheReadTokens += r.CacheReadTokens
+		total.Cost += r.Cost
+		total.Unpriced = append(total.Unpriced, r.Unpriced...)
+	}
+	if len(rows) > 1 {
+		table.AddRow(map[string]interface{}{
+			"Key":        "Total",
+			"Title":      "",
+			"Requests":   total.Requests,
+			"Input":      total.InputTokens,
+			"Output":     total.OutputTokens,
+			"CacheWrite": total.CacheCreationTokens,
+			"CacheRead":  total.CacheReadTokens,
+			"Latency":    "",
+			"Cost":       formatCost(total),
+		})
+	}
+
+	table.Render()
+}
+
+// formatCost shows a cost in dollars, marked with a * when part of it is
+// unknown.
+func formatCost(r Row) string {
+	cost := fmt.Sprintf("$%.4f", r.Cost)
+	if len(r.Unpriced) > 0 {
+		cost += "*"
+	}
+	return cost
+}
@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/christianhturner/go-claude/config"
+	"github.com/christianhturner/go-claude/db"
+	"github.com/spf13/viper"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set(config.PricingKey, map[string]any{
+		"claude-test": map[string]any{"input": 1.0, "output": 2.0},
+	})
+
+	totals := []db.UsageTotal{
+		{Key: "2024-06-02", Model: "claude-test", Requests: 2, InputTokens: 1000000, OutputTokens: 500000, Latency: 3 * time.Second},
+		{Key: "2024-06-01", Model: "claude-test", Requests: 1, InputTokens: 1000000, Latency: time.Second, Batch: true},
+		{Key: "2024-06-02", Model: "local-model", Requests: 1, InputTokens: 4000, OutputTokens: 100, Latency: 5 * time.Second},
+		{Key: "2024-06-02", Model: "local-model", Requests: 1, InputTokens: 1000, Latency: 4 * time.Second},
+	}
+	want := []struct {
+		key      string
+		requests int64
+		input    int64
+		output   int64
+		latency  time.Duration
+		cost     string
+		unpriced int
+	}{
+		// The unpriced model adds to every total but the cost, which is marked.
+		{"2024-06-02", 4, 1005000, 500100, 3 * time.Second, "$2.0000*", 1},
+		{"2024-06-01", 1, 1000000, 0, time.Second, "$0.5000", 0},
+	}
+
+	rows := Summarize(totals)
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %d: %+v", len(want), len(rows), rows)
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.Key != w.key {
+			t.Errorf("Row %d: expected the groups in their first order, got %s for %s", i, r.Key, w.key)
+			continue
+		}
+		if r.Requests != w.requests || r.InputTokens != w.input || r.OutputTokens != w.output {
+			t.Errorf("%s: expected %d requests of %d/%d tokens, got %d of %d/%d", w.key, w.requests, w.input, w.output, r.Requests, r.InputTokens, r.OutputTokens)
+		}
+		if r.Latency != w.latency {
+			t.Errorf("%s: expected an average latency of %s, got %s", w.key, w.latency, r.Latency)
+		}
+		if got := formatCost(r); got != w.cost {
+			t.Errorf("%s: expected the cost %s, got %s", w.key, w.cost, got)
+		}
+		if len(r.Unpriced) != w.unpriced {
+			t.Errorf("%s: expected %d unpriced models, got %q", w.key, w.unpriced, r.Unpriced)
+		}
+	}
+
+	if rows := Summarize(nil); len(rows) != 0 {
+		t.Errorf("Expected no rows without usage, got %+v", rows)
+	}
+}